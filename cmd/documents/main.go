@@ -0,0 +1,57 @@
+package main
+
+import (
+	"context"
+	"flag"
+	dbsetup "github.com/Kobietka/product-service/internal/database/setup"
+	productdb "github.com/Kobietka/product-service/internal/products/database"
+	"github.com/charmbracelet/log"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"os"
+	"os/signal"
+)
+
+// documents rebuilds product_document from the normalised tables. With -check
+// it only reports how many documents drifted and fails when any did.
+func main() {
+	check := flag.Bool("check", false, "count drifted documents without rebuilding them")
+	databaseUrl := flag.String("database", os.Getenv("DATABASE_URL"), "database url, defaults to DATABASE_URL")
+	flag.Parse()
+
+	if *databaseUrl == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	pool, err := pgxpool.New(ctx, *databaseUrl)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer pool.Close()
+
+	if err := dbsetup.NewSeeder(pool).CreateSchema(ctx); err != nil {
+		log.Fatal(err)
+	}
+
+	store := productdb.NewPostgresStore(pool)
+	drifted, err := store.CountDriftedDocuments(ctx)
+	if err != nil {
+		log.Fatal(err)
+	}
+	log.Info("documents checked", "drifted", drifted)
+	if *check {
+		if drifted > 0 {
+			os.Exit(1)
+		}
+		return
+	}
+
+	rebuilt, err := store.RebuildDocuments(ctx)
+	if err != nil {
+		log.Fatal(err)
+	}
+	log.Info("documents rebuilt", "documents", rebuilt)
+}
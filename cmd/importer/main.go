@@ -0,0 +1,154 @@
+package main
+
+import (
+	"compress/gzip"
+	"context"
+	"flag"
+	dbsetup "github.com/Kobietka/product-service/internal/database/setup"
+	"github.com/Kobietka/product-service/internal/openfoodfacts"
+	"github.com/Kobietka/product-service/internal/products"
+	productdb "github.com/Kobietka/product-service/internal/products/database"
+	"github.com/Kobietka/product-service/internal/types"
+	typesdb "github.com/Kobietka/product-service/internal/types/database"
+	"github.com/charmbracelet/log"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+)
+
+func main() {
+	file := flag.String("file", "", "Open Food Facts JSONL or CSV dump, optionally gzipped")
+	format := flag.String("format", "", "dump format: jsonl or csv, detected from the file name when empty")
+	rejectsPath := flag.String("rejects", "rejects.csv", "file rejected records are appended to")
+	progressPath := flag.String("progress", "", "file keeping the last imported line, defaults to <file>.progress")
+	rulesPath := flag.String("rules", "", "JSON file configuring the validation rules")
+	energyTolerance := flag.Float64("energy-tolerance", products.DefaultEnergyTolerance, "accepted relative deviation of kcal from macronutrients, 0 disables the check")
+	energyWarn := flag.Bool("energy-warn", false, "import products failing the energy check instead of rejecting them")
+	databaseUrl := flag.String("database", os.Getenv("DATABASE_URL"), "database url, defaults to DATABASE_URL")
+	flag.Parse()
+
+	if *file == "" || *databaseUrl == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+	if *progressPath == "" {
+		*progressPath = *file + ".progress"
+	}
+
+	dumpFormat := openfoodfacts.Format(*format)
+	if dumpFormat == "" {
+		detected, err := openfoodfacts.FormatFromPath(*file)
+		if err != nil {
+			log.Fatal("cannot detect dump format", "file", *file)
+		}
+		dumpFormat = detected
+	}
+
+	rules, err := loadRules(*rulesPath, *energyTolerance, *energyWarn)
+	if err != nil {
+		log.Fatal("invalid validation rules", "err", err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	dump, err := os.Open(*file)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer dump.Close()
+
+	var input io.Reader = dump
+	if strings.HasSuffix(*file, ".gz") {
+		gzipReader, err := gzip.NewReader(dump)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer gzipReader.Close()
+		input = gzipReader
+	}
+
+	reader, err := openfoodfacts.NewReader(input, dumpFormat)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	rejects, err := os.OpenFile(*rejectsPath, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer rejects.Close()
+
+	pool, err := pgxpool.New(ctx, *databaseUrl)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer pool.Close()
+
+	seeder := dbsetup.NewSeeder(pool)
+	if err := seeder.CreateSchema(ctx); err != nil {
+		log.Fatal(err)
+	}
+	if err := seeder.Seed(ctx); err != nil {
+		log.Fatal(err)
+	}
+
+	dictionaries, err := types.LoadDictionaries(ctx, typesdb.NewPostgresStore(pool))
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	importer := openfoodfacts.NewImporter(productdb.NewPostgresStore(pool), rejects, *progressPath).
+		WithRules(rules).
+		WithDictionaries(dictionaries)
+	summary, err := importer.Import(ctx, reader)
+	log.Info(
+		"import finished",
+		"imported", summary.Imported,
+		"rejected", summary.Rejected,
+		"skipped", summary.Skipped,
+		"warned", summary.Warned,
+		"line", summary.LastLine,
+	)
+	if err != nil {
+		log.Error("import stopped", "err", err)
+		os.Exit(1)
+	}
+}
+
+// loadRules applies the rules file and then the energy flags that were set
+// explicitly, so that their defaults do not override the file.
+func loadRules(path string, energyTolerance float64, energyWarn bool) (products.Rules, error) {
+	rules := products.DefaultRules
+	if path != "" {
+		file, err := os.Open(path)
+		if err != nil {
+			return rules, err
+		}
+		defer file.Close()
+
+		configs, err := products.ReadRuleConfigs(file)
+		if err != nil {
+			return rules, err
+		}
+		if rules, err = rules.Configure(configs); err != nil {
+			return rules, err
+		}
+	}
+
+	var energy products.RuleConfig
+	flag.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "energy-tolerance":
+			energy.Params = products.Params{"tolerance": energyTolerance}
+		case "energy-warn":
+			energy.Severity = products.SeverityError
+			if energyWarn {
+				energy.Severity = products.SeverityWarning
+			}
+		}
+	})
+	return rules.Configure(map[string]products.RuleConfig{products.RuleEnergy: energy})
+}
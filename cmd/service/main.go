@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"expvar"
 	"fmt"
 	"github.com/Kobietka/product-service/internal/config"
 	dbsetup "github.com/Kobietka/product-service/internal/database/setup"
@@ -10,9 +11,12 @@ import (
 	"github.com/Kobietka/product-service/internal/types"
 	typesdb "github.com/Kobietka/product-service/internal/types/database"
 	"github.com/Kobietka/product-service/pkg/logger"
+	"github.com/Kobietka/product-service/pkg/postgres"
+	"github.com/Kobietka/product-service/pkg/sqlite"
 	"github.com/charmbracelet/log"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/labstack/echo/v4"
+	"os"
 )
 
 func main() {
@@ -23,36 +27,137 @@ func main() {
 		panic(err)
 	}
 
-	poolConfig, err := pgxpool.ParseConfig(c.DatabaseUrl)
-	if err != nil {
-		panic(err)
+	var s stores
+	switch {
+	case c.Store == config.StoreMemory:
+		s, err = newMemoryStores(context.Background(), c.Fixture)
+	case c.Driver == config.DriverSqlite:
+		s, err = newSqliteStores(context.Background(), c.DatabaseUrl)
+	default:
+		s, err = newDatabaseStores(context.Background(), c.DatabaseUrl)
 	}
-
-	pool, err := pgxpool.NewWithConfig(context.Background(), poolConfig)
 	if err != nil {
 		panic(err)
 	}
 
-	seeder := dbsetup.NewSeeder(pool)
-	err = seeder.CreateSchema(context.Background())
-	if err != nil {
-		panic(err)
+	// Concurrent reads of a product share one read of the store, the cache
+	// sits in front and only lets its misses through.
+	coalescing := products.NewCoalescingStore(s.products)
+	expvar.Publish("productCoalescing", expvar.Func(func() any {
+		return coalescing.Stats()
+	}))
+
+	var productStore products.Store = coalescing
+	if c.ProductCache.Size > 0 {
+		cache := products.NewCachedStore(coalescing, c.ProductCache.Size, c.ProductCache.Ttl).
+			WithNotFoundTtl(c.ProductCache.NotFoundTtl)
+		expvar.Publish("productCache", expvar.Func(func() any {
+			return cache.Stats()
+		}))
+		if s.changes != nil {
+			go s.changes.Listen(context.Background(), cache.Purge, cache.Invalidate)
+		}
+		productStore = cache
 	}
-	err = seeder.Seed(context.Background())
+
+	rules, err := products.DefaultRules.Configure(c.ValidationRules)
 	if err != nil {
 		panic(err)
 	}
 
-	productStore := productdb.NewPostgresStore(pool)
-	unitStore := typesdb.NewPostgresStore(pool)
-	productServer := products.NewServer(productStore)
+	unitStore := types.NewCachedStore(s.types, types.DefaultCacheTtl)
+	productServer := products.NewServer(productStore).
+		WithMeasureTemplates(c.MeasureTemplates).
+		WithRules(rules).
+		WithTypes(unitStore)
 	typeServer := types.NewServer(unitStore)
 
 	e := echo.New()
 	e.Use(logger.NewBasicRequestLogger())
+	e.GET("/debug/vars", echo.WrapHandler(expvar.Handler()))
 
 	productServer.Routes(e)
 	typeServer.Routes(e)
 
 	log.Fatal(e.Start(fmt.Sprintf(":%s", c.Port)))
 }
+
+type stores struct {
+	products products.Store
+	types    types.Store
+	// changes notifies of the products written by other instances, it is nil
+	// when the products cannot be written elsewhere.
+	changes *postgres.Listener
+}
+
+func newDatabaseStores(ctx context.Context, databaseUrl string) (stores, error) {
+	poolConfig, err := pgxpool.ParseConfig(databaseUrl)
+	if err != nil {
+		return stores{}, err
+	}
+
+	pool, err := pgxpool.NewWithConfig(ctx, poolConfig)
+	if err != nil {
+		return stores{}, err
+	}
+
+	seeder := dbsetup.NewSeeder(pool)
+	err = seeder.CreateSchema(ctx)
+	if err != nil {
+		return stores{}, err
+	}
+	err = seeder.Seed(ctx)
+	if err != nil {
+		return stores{}, err
+	}
+
+	changes := postgres.NewListener(pool, "product_changed")
+	return stores{
+		products: productdb.NewPostgresStore(pool),
+		types:    typesdb.NewPostgresStore(pool),
+		changes:  &changes,
+	}, nil
+}
+
+func newSqliteStores(ctx context.Context, databaseUrl string) (stores, error) {
+	db, err := sqlite.Open(databaseUrl)
+	if err != nil {
+		return stores{}, err
+	}
+
+	seeder := dbsetup.NewSqliteSeeder(db)
+	err = seeder.CreateSchema(ctx)
+	if err != nil {
+		return stores{}, err
+	}
+	err = seeder.Seed(ctx)
+	if err != nil {
+		return stores{}, err
+	}
+
+	return stores{products: productdb.NewSqliteStore(db), types: typesdb.NewSqliteStore(db)}, nil
+}
+
+// newMemoryStores starts from the seeded dictionaries when no fixture file is
+// given.
+func newMemoryStores(ctx context.Context, fixturePath string) (stores, error) {
+	fixture := dbsetup.DefaultFixture()
+	if fixturePath != "" {
+		file, err := os.Open(fixturePath)
+		if err != nil {
+			return stores{}, err
+		}
+		fixture, err = dbsetup.ReadFixture(file)
+		file.Close()
+		if err != nil {
+			return stores{}, err
+		}
+	}
+
+	typeStore := typesdb.NewMemoryStore(fixture)
+	productStore := productdb.NewMemoryStore(typeStore)
+	if err := productStore.Load(ctx, fixture.Products); err != nil {
+		return stores{}, err
+	}
+	return stores{products: productStore, types: typeStore}, nil
+}
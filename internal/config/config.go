@@ -2,12 +2,41 @@ package config
 
 import (
 	"errors"
+	"github.com/Kobietka/product-service/internal/ean"
+	"github.com/Kobietka/product-service/internal/products"
+	"github.com/Kobietka/product-service/pkg/sqlite"
 	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	StoreDatabase = "database"
+	StoreMemory   = "memory"
+)
+
+const (
+	DriverPostgres = "postgres"
+	DriverSqlite   = "sqlite"
 )
 
 type Config struct {
-	DatabaseUrl string
-	Port        string
+	Store            string
+	Fixture          string
+	Driver           string
+	DatabaseUrl      string
+	Port             string
+	MeasureTemplates []ean.Template
+	ValidationRules  map[string]products.RuleConfig
+	ProductCache     ProductCacheConfig
+}
+
+// ProductCacheConfig sizes the cache of products, a size of zero turns it off.
+type ProductCacheConfig struct {
+	Size        int
+	Ttl         time.Duration
+	NotFoundTtl time.Duration
 }
 
 func NewConfigStore() Store {
@@ -17,17 +46,121 @@ func NewConfigStore() Store {
 type Store struct{}
 
 func (s Store) GetConfig() (Config, error) {
+	store := StoreDatabase
+	if value, ok := os.LookupEnv("STORE"); ok {
+		if value != StoreDatabase && value != StoreMemory {
+			return Config{}, errors.New("STORE environment variable must be database or memory")
+		}
+		store = value
+	}
+	fixture := os.Getenv("STORE_FIXTURE")
+	if fixture != "" && store != StoreMemory {
+		return Config{}, errors.New("STORE_FIXTURE environment variable requires STORE=memory")
+	}
+
 	databaseUrl, ok := os.LookupEnv("DATABASE_URL")
-	if !ok {
+	if !ok && store == StoreDatabase {
 		return Config{}, errors.New("DATABASE_URL environment variable not set")
 	}
+	driver := ""
+	if store == StoreDatabase {
+		switch {
+		case strings.HasPrefix(databaseUrl, "postgres://"), strings.HasPrefix(databaseUrl, "postgresql://"):
+			driver = DriverPostgres
+		case strings.HasPrefix(databaseUrl, sqlite.Scheme):
+			driver = DriverSqlite
+		default:
+			return Config{}, errors.New("DATABASE_URL environment variable must start with postgres:// or sqlite://")
+		}
+	}
 	port, ok := os.LookupEnv("PORT")
 	if !ok {
 		return Config{}, errors.New("PORT environment variable not set")
 	}
 
+	measureTemplates := ean.DefaultTemplates
+	if value, ok := os.LookupEnv("VARIABLE_MEASURE_TEMPLATES"); ok {
+		templates, err := ean.ParseTemplates(value)
+		if err != nil {
+			return Config{}, errors.New("VARIABLE_MEASURE_TEMPLATES environment variable invalid")
+		}
+		measureTemplates = templates
+	}
+
+	validationRules := map[string]products.RuleConfig{}
+	if path, ok := os.LookupEnv("VALIDATION_RULES"); ok {
+		file, err := os.Open(path)
+		if err != nil {
+			return Config{}, err
+		}
+		validationRules, err = products.ReadRuleConfigs(file)
+		file.Close()
+		if err != nil {
+			return Config{}, errors.New("VALIDATION_RULES file invalid")
+		}
+	}
+
+	// ENERGY_TOLERANCE and ENERGY_CHECK predate the rules file and take
+	// precedence over its energy rule.
+	energy := validationRules[products.RuleEnergy]
+	if value, ok := os.LookupEnv("ENERGY_TOLERANCE"); ok {
+		tolerance, err := strconv.ParseFloat(value, 64)
+		if err != nil || tolerance < 0 {
+			return Config{}, errors.New("ENERGY_TOLERANCE environment variable invalid")
+		}
+		if energy.Params == nil {
+			energy.Params = products.Params{}
+		}
+		energy.Params["tolerance"] = tolerance
+		validationRules[products.RuleEnergy] = energy
+	}
+	if value, ok := os.LookupEnv("ENERGY_CHECK"); ok {
+		switch value {
+		case "reject":
+			energy.Severity = products.SeverityError
+		case "warn":
+			energy.Severity = products.SeverityWarning
+		default:
+			return Config{}, errors.New("ENERGY_CHECK environment variable must be reject or warn")
+		}
+		validationRules[products.RuleEnergy] = energy
+	}
+
+	productCache := ProductCacheConfig{
+		Size:        products.DefaultCacheSize,
+		Ttl:         products.DefaultCacheTtl,
+		NotFoundTtl: products.DefaultNotFoundCacheTtl,
+	}
+	if value, ok := os.LookupEnv("PRODUCT_CACHE_SIZE"); ok {
+		size, err := strconv.Atoi(value)
+		if err != nil || size < 0 {
+			return Config{}, errors.New("PRODUCT_CACHE_SIZE environment variable invalid")
+		}
+		productCache.Size = size
+	}
+	if value, ok := os.LookupEnv("PRODUCT_CACHE_TTL"); ok {
+		ttl, err := time.ParseDuration(value)
+		if err != nil || ttl <= 0 {
+			return Config{}, errors.New("PRODUCT_CACHE_TTL environment variable invalid")
+		}
+		productCache.Ttl = ttl
+	}
+	if value, ok := os.LookupEnv("PRODUCT_CACHE_NOT_FOUND_TTL"); ok {
+		ttl, err := time.ParseDuration(value)
+		if err != nil || ttl < 0 {
+			return Config{}, errors.New("PRODUCT_CACHE_NOT_FOUND_TTL environment variable invalid")
+		}
+		productCache.NotFoundTtl = ttl
+	}
+
 	return Config{
-		DatabaseUrl: databaseUrl,
-		Port:        port,
+		Store:            store,
+		Fixture:          fixture,
+		Driver:           driver,
+		DatabaseUrl:      databaseUrl,
+		Port:             port,
+		MeasureTemplates: measureTemplates,
+		ValidationRules:  validationRules,
+		ProductCache:     productCache,
 	}, nil
 }
@@ -0,0 +1,50 @@
+package setup
+
+import (
+	_ "embed"
+	"encoding/json"
+	"github.com/Kobietka/product-service/internal/types"
+	v1 "github.com/Kobietka/product-service/pkg/api/v1"
+	"io"
+)
+
+// fixture.json holds the dictionaries of seed.sql, the in-memory stores start
+// from it like a seeded database.
+//
+//go:embed fixture.json
+var defaultFixture []byte
+
+type FixtureUnit struct {
+	Value     string          `json:"value"`
+	Dimension types.Dimension `json:"dimension"`
+}
+
+// Fixture is the content of the in-memory stores. Products are created in
+// order, so components have to come before the products made of them.
+type Fixture struct {
+	Units         []FixtureUnit `json:"units"`
+	NutrientTypes []string      `json:"nutrientTypes"`
+	VitaminTypes  []string      `json:"vitaminTypes"`
+	MineralTypes  []string      `json:"mineralTypes"`
+	Products      []v1.Product  `json:"products"`
+}
+
+func DefaultFixture() Fixture {
+	var fixture Fixture
+	if err := json.Unmarshal(defaultFixture, &fixture); err != nil {
+		panic(err)
+	}
+	return fixture
+}
+
+// ReadFixture decodes a JSON fixture. Dictionaries missing from it are the
+// seeded ones.
+func ReadFixture(reader io.Reader) (Fixture, error) {
+	fixture := DefaultFixture()
+	decoder := json.NewDecoder(reader)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&fixture); err != nil {
+		return Fixture{}, err
+	}
+	return fixture, nil
+}
@@ -0,0 +1,51 @@
+package setup
+
+import (
+	"github.com/Kobietka/product-service/internal/types"
+	"github.com/stretchr/testify/assert"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestDefaultFixtureMatchesSeed(t *testing.T) {
+	for name, seed := range map[string]string{"postgres": seed, "sqlite": sqliteSeed} {
+		t.Run(name, func(t *testing.T) {
+			assertFixtureMatchesSeed(t, DefaultFixture(), seed)
+		})
+	}
+}
+
+func assertFixtureMatchesSeed(t *testing.T, fixture Fixture, seed string) {
+	var units []FixtureUnit
+	for _, match := range regexp.MustCompile(`INSERT INTO unit \(id, value, dimension\) VALUES \(\d+, '([^']+)', '([^']+)'\)`).FindAllStringSubmatch(seed, -1) {
+		units = append(units, FixtureUnit{Value: match[1], Dimension: types.Dimension(match[2])})
+	}
+	seededTypes := func(table string) []string {
+		var values []string
+		for _, match := range regexp.MustCompile(`INSERT INTO `+table+` \(id, type\) VALUES \(\d+, '([^']+)'\)`).FindAllStringSubmatch(seed, -1) {
+			values = append(values, match[1])
+		}
+		return values
+	}
+
+	assert.Equal(t, units, fixture.Units)
+	assert.Equal(t, seededTypes("nutrient_type"), fixture.NutrientTypes)
+	assert.Equal(t, seededTypes("vitamin_type"), fixture.VitaminTypes)
+	assert.Equal(t, seededTypes("mineral_type"), fixture.MineralTypes)
+	assert.Empty(t, fixture.Products)
+}
+
+func TestReadFixture(t *testing.T) {
+	fixture, err := ReadFixture(strings.NewReader(`{
+		"units": [{"value": "oz", "dimension": "MASS"}],
+		"products": [{"ean": "12345678", "name": "Milk", "packaging": {"value": 1, "unit": "oz"}}]
+	}`))
+	assert.NoError(t, err)
+	assert.Equal(t, []FixtureUnit{{Value: "oz", Dimension: types.DimensionMass}}, fixture.Units)
+	assert.Equal(t, DefaultFixture().NutrientTypes, fixture.NutrientTypes)
+	assert.Len(t, fixture.Products, 1)
+
+	_, err = ReadFixture(strings.NewReader(`{"brands": []}`))
+	assert.Error(t, err)
+}
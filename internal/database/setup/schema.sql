@@ -16,6 +16,10 @@ CREATE TABLE IF NOT EXISTS unit
     value TEXT NOT NULL UNIQUE
 );
 
+ALTER TABLE unit
+    ADD COLUMN IF NOT EXISTS dimension TEXT NOT NULL DEFAULT 'MASS'
+        CHECK (dimension IN ('MASS', 'VOLUME', 'ENERGY', 'COUNT'));
+
 CREATE TABLE IF NOT EXISTS packaging
 (
     ean     TEXT REFERENCES product (ean) ON DELETE CASCADE ON UPDATE CASCADE,
@@ -30,6 +34,26 @@ CREATE TABLE IF NOT EXISTS nutrition_quantity
     unit_id INTEGER NOT NULL REFERENCES unit (id) ON DELETE CASCADE ON UPDATE CASCADE
 );
 
+-- A product has at most one packaging and nutrition, upserts conflict on ean.
+-- Databases created before the indexes may hold several rows of a product,
+-- one row per ean is kept before the index is created.
+DO
+$$
+    DECLARE
+        deduplicated TEXT;
+    BEGIN
+        FOREACH deduplicated IN ARRAY ARRAY ['packaging', 'nutrition', 'nutrition_quantity']
+            LOOP
+                IF NOT EXISTS (SELECT FROM pg_indexes
+                               WHERE schemaname = current_schema() AND indexname = deduplicated || '_ean') THEN
+                    EXECUTE format('DELETE FROM %1$I duplicate USING %1$I kept ' ||
+                                   'WHERE duplicate.ean = kept.ean AND duplicate.ctid < kept.ctid', deduplicated);
+                    EXECUTE format('CREATE UNIQUE INDEX %I ON %I (ean)', deduplicated || '_ean', deduplicated);
+                END IF;
+            END LOOP;
+    END
+$$;
+
 CREATE TABLE IF NOT EXISTS nutrient_type
 (
     id   INTEGER PRIMARY KEY,
@@ -75,3 +99,188 @@ CREATE TABLE IF NOT EXISTS mineral
     unit_id INTEGER NOT NULL REFERENCES unit (id) ON DELETE CASCADE ON UPDATE CASCADE,
     PRIMARY KEY (ean, type_id)
 );
+
+CREATE TABLE IF NOT EXISTS product_component
+(
+    ean           TEXT REFERENCES product (ean) ON DELETE CASCADE ON UPDATE CASCADE,
+    component_ean TEXT REFERENCES product (ean) ON DELETE RESTRICT ON UPDATE CASCADE,
+    count         INTEGER NOT NULL CHECK (count > 0),
+    PRIMARY KEY (ean, component_ean)
+);
+
+CREATE TABLE IF NOT EXISTS product_serving
+(
+    ean     TEXT REFERENCES product (ean) ON DELETE CASCADE ON UPDATE CASCADE,
+    name    TEXT    NOT NULL,
+    value   REAL    NOT NULL CHECK (value > 0),
+    unit_id INTEGER NOT NULL REFERENCES unit (id) ON DELETE CASCADE ON UPDATE CASCADE,
+    PRIMARY KEY (ean, name)
+);
+
+-- Instances caching products listen on product_changed, every write of a
+-- product touches its row and notifies them when it commits.
+CREATE OR REPLACE FUNCTION notify_product_changed() RETURNS trigger AS
+$$
+BEGIN
+    IF TG_OP = 'DELETE' THEN
+        PERFORM pg_notify('product_changed', OLD.ean);
+    ELSE
+        PERFORM pg_notify('product_changed', NEW.ean);
+    END IF;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS product_changed ON product;
+CREATE TRIGGER product_changed
+    AFTER INSERT OR UPDATE OR DELETE
+    ON product
+    FOR EACH ROW
+EXECUTE FUNCTION notify_product_changed();
+
+-- product_document holds every product as PostgresStore reads it. It is kept
+-- in sync by the triggers below when the transaction writing the product
+-- commits, a product without packaging has no document. Renaming units or types does
+-- not update the documents, they are rebuilt with cmd/documents.
+CREATE TABLE IF NOT EXISTS product_document
+(
+    ean      TEXT PRIMARY KEY REFERENCES product (ean) ON DELETE CASCADE ON UPDATE CASCADE,
+    document JSONB NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS product_document_document ON product_document USING GIN (document jsonb_path_ops);
+CREATE INDEX IF NOT EXISTS product_document_nutrients ON product_document USING GIN ((document -> 'nutrition' -> 'nutrients'));
+
+CREATE OR REPLACE FUNCTION build_product_document(document_ean TEXT) RETURNS JSONB AS
+$$
+SELECT jsonb_build_object(
+               'ean', product.ean,
+               'name', product.name,
+               'packaging', jsonb_build_object('value', packaging.value, 'unit', packaging_unit.value),
+               'nutrition', jsonb_build_object(
+                       'kcal', nutrition.kcal,
+                       'per', CASE
+                                  WHEN nutrition_quantity.ean IS NOT NULL THEN
+                                      jsonb_build_object('value', nutrition_quantity.value, 'unit', nutrition_unit.value)
+                           END,
+                       'nutrients', (SELECT COALESCE(jsonb_agg(jsonb_build_object('type', nutrient_type.type, 'value', nutrient.value, 'unit', unit.value) ORDER BY nutrient_type.type), '[]')
+                                     FROM nutrient
+                                              JOIN unit ON unit.id = nutrient.unit_id
+                                              JOIN nutrient_type ON nutrient_type.id = nutrient.type_id
+                                     WHERE nutrient.ean = product.ean),
+                       'vitamins', (SELECT COALESCE(jsonb_agg(jsonb_build_object('type', vitamin_type.type, 'value', vitamin.value, 'unit', unit.value) ORDER BY vitamin_type.type), '[]')
+                                    FROM vitamin
+                                             JOIN unit ON unit.id = vitamin.unit_id
+                                             JOIN vitamin_type ON vitamin_type.id = vitamin.type_id
+                                    WHERE vitamin.ean = product.ean),
+                       'minerals', (SELECT COALESCE(jsonb_agg(jsonb_build_object('type', mineral_type.type, 'value', mineral.value, 'unit', unit.value) ORDER BY mineral_type.type), '[]')
+                                    FROM mineral
+                                             JOIN unit ON unit.id = mineral.unit_id
+                                             JOIN mineral_type ON mineral_type.id = mineral.type_id
+                                    WHERE mineral.ean = product.ean)
+                            ),
+               'components', (SELECT COALESCE(jsonb_agg(jsonb_build_object('ean', component_ean, 'count', count) ORDER BY component_ean), '[]')
+                              FROM product_component
+                              WHERE product_component.ean = product.ean),
+               'servings', (SELECT COALESCE(jsonb_agg(jsonb_build_object('name', product_serving.name, 'value', product_serving.value, 'unit', unit.value)
+                                                      ORDER BY product_serving.value, product_serving.name), '[]')
+                            FROM product_serving
+                                     JOIN unit ON unit.id = product_serving.unit_id
+                            WHERE product_serving.ean = product.ean)
+       )
+FROM product
+         JOIN packaging ON packaging.ean = product.ean
+         JOIN unit packaging_unit ON packaging_unit.id = packaging.unit_id
+         LEFT JOIN nutrition ON nutrition.ean = product.ean
+         LEFT JOIN nutrition_quantity ON nutrition_quantity.ean = product.ean
+         LEFT JOIN unit nutrition_unit ON nutrition_unit.id = nutrition_quantity.unit_id
+WHERE product.ean = document_ean;
+$$ LANGUAGE sql STABLE;
+
+CREATE OR REPLACE FUNCTION refresh_product_document(document_ean TEXT) RETURNS void AS
+$$
+DECLARE
+    built JSONB := build_product_document(document_ean);
+BEGIN
+    IF built IS NULL THEN
+        DELETE FROM product_document WHERE ean = document_ean;
+    ELSE
+        INSERT INTO product_document (ean, document)
+        VALUES (document_ean, built)
+        ON CONFLICT (ean) DO UPDATE SET document = EXCLUDED.document;
+    END IF;
+END;
+$$ LANGUAGE plpgsql;
+
+-- product_document_pending queues the EANs a transaction changed. Only the
+-- first change of an EAN inserts a row, the deferred trigger on the queue then
+-- rebuilds the document once when the transaction commits, however many rows
+-- of the product were written.
+CREATE UNLOGGED TABLE IF NOT EXISTS product_document_pending
+(
+    ean TEXT PRIMARY KEY
+);
+
+CREATE OR REPLACE FUNCTION queue_product_document(document_ean TEXT) RETURNS void AS
+$$
+INSERT INTO product_document_pending (ean)
+VALUES (document_ean)
+ON CONFLICT (ean) DO NOTHING;
+$$ LANGUAGE sql;
+
+CREATE OR REPLACE FUNCTION refresh_changed_product_document() RETURNS trigger AS
+$$
+BEGIN
+    IF TG_OP = 'INSERT' THEN
+        PERFORM queue_product_document(NEW.ean);
+    ELSIF TG_OP = 'DELETE' THEN
+        PERFORM queue_product_document(OLD.ean);
+    ELSE
+        PERFORM queue_product_document(OLD.ean);
+        IF NEW.ean IS DISTINCT FROM OLD.ean THEN
+            PERFORM queue_product_document(NEW.ean);
+        END IF;
+    END IF;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE OR REPLACE FUNCTION refresh_pending_product_document() RETURNS trigger AS
+$$
+BEGIN
+    DELETE FROM product_document_pending WHERE ean = NEW.ean;
+    PERFORM refresh_product_document(NEW.ean);
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS product_document_pending_refresh ON product_document_pending;
+CREATE CONSTRAINT TRIGGER product_document_pending_refresh
+    AFTER INSERT
+    ON product_document_pending
+    DEFERRABLE INITIALLY DEFERRED
+    FOR EACH ROW
+EXECUTE FUNCTION refresh_pending_product_document();
+
+DO
+$$
+    DECLARE
+        changed TEXT;
+    BEGIN
+        FOREACH changed IN ARRAY ARRAY ['product', 'packaging', 'nutrition', 'nutrition_quantity', 'nutrient', 'vitamin',
+            'mineral', 'product_component', 'product_serving']
+            LOOP
+                EXECUTE format('DROP TRIGGER IF EXISTS product_document_refresh ON %I', changed);
+                EXECUTE format('CREATE TRIGGER product_document_refresh AFTER INSERT OR UPDATE OR DELETE ON %I ' ||
+                               'FOR EACH ROW EXECUTE FUNCTION refresh_changed_product_document()', changed);
+            END LOOP;
+    END
+$$;
+
+-- Products written before product_document existed get their documents.
+INSERT INTO product_document (ean, document)
+SELECT ean, document
+FROM (SELECT product.ean, build_product_document(product.ean) AS document
+      FROM product
+      WHERE NOT EXISTS (SELECT FROM product_document WHERE product_document.ean = product.ean)) built
+WHERE document IS NOT NULL;
@@ -19,6 +19,8 @@ INSERT INTO nutrient_type (id, type) VALUES (7, 'SUGAR') ON CONFLICT DO NOTHING;
 INSERT INTO nutrient_type (id, type) VALUES (8, 'FIBER') ON CONFLICT DO NOTHING;
 INSERT INTO nutrient_type (id, type) VALUES (9, 'PROTEIN') ON CONFLICT DO NOTHING;
 INSERT INTO nutrient_type (id, type) VALUES (10, 'SALT') ON CONFLICT DO NOTHING;
+INSERT INTO nutrient_type (id, type) VALUES (11, 'ALCOHOL') ON CONFLICT DO NOTHING;
+INSERT INTO nutrient_type (id, type) VALUES (12, 'POLYOLS') ON CONFLICT DO NOTHING;
 
 INSERT INTO vitamin_type (id, type) VALUES (1, 'VITAMIN_A') ON CONFLICT DO NOTHING;
 INSERT INTO vitamin_type (id, type) VALUES (2, 'VITAMIN_B1') ON CONFLICT DO NOTHING;
@@ -34,9 +36,12 @@ INSERT INTO vitamin_type (id, type) VALUES (11, 'VITAMIN_D') ON CONFLICT DO NOTH
 INSERT INTO vitamin_type (id, type) VALUES (12, 'VITAMIN_E') ON CONFLICT DO NOTHING;
 INSERT INTO vitamin_type (id, type) VALUES (13, 'VITAMIN_F') ON CONFLICT DO NOTHING;
 
-INSERT INTO unit (id, value) VALUES (1, 'l') ON CONFLICT DO NOTHING;
-INSERT INTO unit (id, value) VALUES (2, 'ml') ON CONFLICT DO NOTHING;
-INSERT INTO unit (id, value) VALUES (3, 'kg') ON CONFLICT DO NOTHING;
-INSERT INTO unit (id, value) VALUES (4, 'g') ON CONFLICT DO NOTHING;
-INSERT INTO unit (id, value) VALUES (5, 'mg') ON CONFLICT DO NOTHING;
-INSERT INTO unit (id, value) VALUES (6, 'µg') ON CONFLICT DO NOTHING;
\ No newline at end of file
+INSERT INTO unit (id, value, dimension) VALUES (1, 'l', 'VOLUME') ON CONFLICT (id) DO UPDATE SET dimension = EXCLUDED.dimension;
+INSERT INTO unit (id, value, dimension) VALUES (2, 'ml', 'VOLUME') ON CONFLICT (id) DO UPDATE SET dimension = EXCLUDED.dimension;
+INSERT INTO unit (id, value, dimension) VALUES (3, 'kg', 'MASS') ON CONFLICT (id) DO UPDATE SET dimension = EXCLUDED.dimension;
+INSERT INTO unit (id, value, dimension) VALUES (4, 'g', 'MASS') ON CONFLICT (id) DO UPDATE SET dimension = EXCLUDED.dimension;
+INSERT INTO unit (id, value, dimension) VALUES (5, 'mg', 'MASS') ON CONFLICT (id) DO UPDATE SET dimension = EXCLUDED.dimension;
+INSERT INTO unit (id, value, dimension) VALUES (6, 'µg', 'MASS') ON CONFLICT (id) DO UPDATE SET dimension = EXCLUDED.dimension;
+INSERT INTO unit (id, value, dimension) VALUES (7, 'kcal', 'ENERGY') ON CONFLICT (id) DO UPDATE SET dimension = EXCLUDED.dimension;
+INSERT INTO unit (id, value, dimension) VALUES (8, 'kJ', 'ENERGY') ON CONFLICT (id) DO UPDATE SET dimension = EXCLUDED.dimension;
+INSERT INTO unit (id, value, dimension) VALUES (9, 'pcs', 'COUNT') ON CONFLICT (id) DO UPDATE SET dimension = EXCLUDED.dimension;
\ No newline at end of file
@@ -1,8 +1,10 @@
 package setup
 
 import (
+	"database/sql"
 	_ "embed"
 	"github.com/Kobietka/product-service/pkg/postgres"
+	"github.com/Kobietka/product-service/pkg/sqlite"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
@@ -12,6 +14,16 @@ var schema string
 //go:embed seed.sql
 var seed string
 
+//go:embed sqlite_schema.sql
+var sqliteSchema string
+
+//go:embed sqlite_seed.sql
+var sqliteSeed string
+
 func NewSeeder(pool *pgxpool.Pool) postgres.Seeder {
 	return postgres.NewSeeder(pool, schema, seed)
 }
+
+func NewSqliteSeeder(db *sql.DB) sqlite.Seeder {
+	return sqlite.NewSeeder(db, sqliteSchema, sqliteSeed)
+}
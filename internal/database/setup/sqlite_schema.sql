@@ -0,0 +1,96 @@
+CREATE TABLE IF NOT EXISTS product
+(
+    ean  TEXT NOT NULL PRIMARY KEY,
+    name TEXT NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS unit
+(
+    id        INTEGER PRIMARY KEY,
+    value     TEXT NOT NULL UNIQUE,
+    dimension TEXT NOT NULL DEFAULT 'MASS' CHECK (dimension IN ('MASS', 'VOLUME', 'ENERGY', 'COUNT'))
+);
+
+CREATE TABLE IF NOT EXISTS nutrition
+(
+    ean  TEXT    NOT NULL UNIQUE REFERENCES product (ean) ON DELETE CASCADE ON UPDATE CASCADE,
+    kcal INTEGER NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS packaging
+(
+    ean     TEXT    NOT NULL UNIQUE REFERENCES product (ean) ON DELETE CASCADE ON UPDATE CASCADE,
+    value   REAL    NOT NULL,
+    unit_id INTEGER NOT NULL REFERENCES unit (id) ON DELETE CASCADE ON UPDATE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS nutrition_quantity
+(
+    ean     TEXT    NOT NULL UNIQUE REFERENCES product (ean) ON DELETE CASCADE ON UPDATE CASCADE,
+    value   INTEGER NOT NULL,
+    unit_id INTEGER NOT NULL REFERENCES unit (id) ON DELETE CASCADE ON UPDATE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS nutrient_type
+(
+    id   INTEGER PRIMARY KEY,
+    type TEXT NOT NULL UNIQUE
+);
+
+CREATE TABLE IF NOT EXISTS nutrient
+(
+    ean     TEXT    NOT NULL REFERENCES product (ean) ON DELETE CASCADE ON UPDATE CASCADE,
+    type_id INTEGER NOT NULL REFERENCES nutrient_type (id) ON DELETE CASCADE ON UPDATE CASCADE,
+    value   REAL    NOT NULL,
+    unit_id INTEGER NOT NULL REFERENCES unit (id) ON DELETE CASCADE ON UPDATE CASCADE,
+    PRIMARY KEY (ean, type_id)
+);
+
+CREATE TABLE IF NOT EXISTS vitamin_type
+(
+    id   INTEGER PRIMARY KEY,
+    type TEXT NOT NULL UNIQUE
+);
+
+CREATE TABLE IF NOT EXISTS vitamin
+(
+    ean     TEXT    NOT NULL REFERENCES product (ean) ON DELETE CASCADE ON UPDATE CASCADE,
+    type_id INTEGER NOT NULL REFERENCES vitamin_type (id) ON DELETE CASCADE ON UPDATE CASCADE,
+    value   REAL    NOT NULL,
+    unit_id INTEGER NOT NULL REFERENCES unit (id) ON DELETE CASCADE ON UPDATE CASCADE,
+    PRIMARY KEY (ean, type_id)
+);
+
+CREATE TABLE IF NOT EXISTS mineral_type
+(
+    id   INTEGER PRIMARY KEY,
+    type TEXT NOT NULL UNIQUE
+);
+
+CREATE TABLE IF NOT EXISTS mineral
+(
+    ean     TEXT    NOT NULL REFERENCES product (ean) ON DELETE CASCADE ON UPDATE CASCADE,
+    type_id INTEGER NOT NULL REFERENCES mineral_type (id) ON DELETE CASCADE ON UPDATE CASCADE,
+    value   REAL    NOT NULL,
+    unit_id INTEGER NOT NULL REFERENCES unit (id) ON DELETE CASCADE ON UPDATE CASCADE,
+    PRIMARY KEY (ean, type_id)
+);
+
+CREATE TABLE IF NOT EXISTS product_component
+(
+    ean           TEXT    NOT NULL REFERENCES product (ean) ON DELETE CASCADE ON UPDATE CASCADE,
+    component_ean TEXT    NOT NULL REFERENCES product (ean) ON DELETE RESTRICT ON UPDATE CASCADE,
+    count         INTEGER NOT NULL CHECK (count > 0),
+    PRIMARY KEY (ean, component_ean)
+);
+
+CREATE TABLE IF NOT EXISTS product_serving
+(
+    ean     TEXT    NOT NULL REFERENCES product (ean) ON DELETE CASCADE ON UPDATE CASCADE,
+    name    TEXT    NOT NULL,
+    value   REAL    NOT NULL CHECK (value > 0),
+    unit_id INTEGER NOT NULL REFERENCES unit (id) ON DELETE CASCADE ON UPDATE CASCADE,
+    PRIMARY KEY (ean, name)
+);
+
+CREATE INDEX IF NOT EXISTS product_component_component_ean ON product_component (component_ean);
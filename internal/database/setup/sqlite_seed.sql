@@ -0,0 +1,47 @@
+INSERT INTO mineral_type (id, type) VALUES (1, 'MAGNESIUM') ON CONFLICT DO NOTHING;
+INSERT INTO mineral_type (id, type) VALUES (2, 'SODIUM') ON CONFLICT DO NOTHING;
+INSERT INTO mineral_type (id, type) VALUES (3, 'POTASSIUM') ON CONFLICT DO NOTHING;
+INSERT INTO mineral_type (id, type) VALUES (4, 'CALCIUM') ON CONFLICT DO NOTHING;
+INSERT INTO mineral_type (id, type) VALUES (5, 'PHOSPHORUS') ON CONFLICT DO NOTHING;
+INSERT INTO mineral_type (id, type) VALUES (6, 'ZINC') ON CONFLICT DO NOTHING;
+INSERT INTO mineral_type (id, type) VALUES (7, 'IRON') ON CONFLICT DO NOTHING;
+INSERT INTO mineral_type (id, type) VALUES (8, 'COPPER') ON CONFLICT DO NOTHING;
+INSERT INTO mineral_type (id, type) VALUES (9, 'MANGANESE') ON CONFLICT DO NOTHING;
+INSERT INTO mineral_type (id, type) VALUES (10, 'IODINE') ON CONFLICT DO NOTHING;
+
+INSERT INTO nutrient_type (id, type) VALUES (1, 'FAT') ON CONFLICT DO NOTHING;
+INSERT INTO nutrient_type (id, type) VALUES (2, 'SATURATED_FAT') ON CONFLICT DO NOTHING;
+INSERT INTO nutrient_type (id, type) VALUES (3, 'MONO_UNSATURATED_FAT') ON CONFLICT DO NOTHING;
+INSERT INTO nutrient_type (id, type) VALUES (4, 'POLY_UNSATURATED_FAT') ON CONFLICT DO NOTHING;
+INSERT INTO nutrient_type (id, type) VALUES (5, 'TRANS_FAT') ON CONFLICT DO NOTHING;
+INSERT INTO nutrient_type (id, type) VALUES (6, 'CARBOHYDRATES') ON CONFLICT DO NOTHING;
+INSERT INTO nutrient_type (id, type) VALUES (7, 'SUGAR') ON CONFLICT DO NOTHING;
+INSERT INTO nutrient_type (id, type) VALUES (8, 'FIBER') ON CONFLICT DO NOTHING;
+INSERT INTO nutrient_type (id, type) VALUES (9, 'PROTEIN') ON CONFLICT DO NOTHING;
+INSERT INTO nutrient_type (id, type) VALUES (10, 'SALT') ON CONFLICT DO NOTHING;
+INSERT INTO nutrient_type (id, type) VALUES (11, 'ALCOHOL') ON CONFLICT DO NOTHING;
+INSERT INTO nutrient_type (id, type) VALUES (12, 'POLYOLS') ON CONFLICT DO NOTHING;
+
+INSERT INTO vitamin_type (id, type) VALUES (1, 'VITAMIN_A') ON CONFLICT DO NOTHING;
+INSERT INTO vitamin_type (id, type) VALUES (2, 'VITAMIN_B1') ON CONFLICT DO NOTHING;
+INSERT INTO vitamin_type (id, type) VALUES (3, 'VITAMIN_B2') ON CONFLICT DO NOTHING;
+INSERT INTO vitamin_type (id, type) VALUES (4, 'VITAMIN_B3') ON CONFLICT DO NOTHING;
+INSERT INTO vitamin_type (id, type) VALUES (5, 'VITAMIN_B5') ON CONFLICT DO NOTHING;
+INSERT INTO vitamin_type (id, type) VALUES (6, 'VITAMIN_B6') ON CONFLICT DO NOTHING;
+INSERT INTO vitamin_type (id, type) VALUES (7, 'VITAMIN_B7') ON CONFLICT DO NOTHING;
+INSERT INTO vitamin_type (id, type) VALUES (8, 'VITAMIN_B9') ON CONFLICT DO NOTHING;
+INSERT INTO vitamin_type (id, type) VALUES (9, 'VITAMIN_B12') ON CONFLICT DO NOTHING;
+INSERT INTO vitamin_type (id, type) VALUES (10, 'VITAMIN_C') ON CONFLICT DO NOTHING;
+INSERT INTO vitamin_type (id, type) VALUES (11, 'VITAMIN_D') ON CONFLICT DO NOTHING;
+INSERT INTO vitamin_type (id, type) VALUES (12, 'VITAMIN_E') ON CONFLICT DO NOTHING;
+INSERT INTO vitamin_type (id, type) VALUES (13, 'VITAMIN_F') ON CONFLICT DO NOTHING;
+
+INSERT INTO unit (id, value, dimension) VALUES (1, 'l', 'VOLUME') ON CONFLICT (id) DO UPDATE SET dimension = excluded.dimension;
+INSERT INTO unit (id, value, dimension) VALUES (2, 'ml', 'VOLUME') ON CONFLICT (id) DO UPDATE SET dimension = excluded.dimension;
+INSERT INTO unit (id, value, dimension) VALUES (3, 'kg', 'MASS') ON CONFLICT (id) DO UPDATE SET dimension = excluded.dimension;
+INSERT INTO unit (id, value, dimension) VALUES (4, 'g', 'MASS') ON CONFLICT (id) DO UPDATE SET dimension = excluded.dimension;
+INSERT INTO unit (id, value, dimension) VALUES (5, 'mg', 'MASS') ON CONFLICT (id) DO UPDATE SET dimension = excluded.dimension;
+INSERT INTO unit (id, value, dimension) VALUES (6, 'µg', 'MASS') ON CONFLICT (id) DO UPDATE SET dimension = excluded.dimension;
+INSERT INTO unit (id, value, dimension) VALUES (7, 'kcal', 'ENERGY') ON CONFLICT (id) DO UPDATE SET dimension = excluded.dimension;
+INSERT INTO unit (id, value, dimension) VALUES (8, 'kJ', 'ENERGY') ON CONFLICT (id) DO UPDATE SET dimension = excluded.dimension;
+INSERT INTO unit (id, value, dimension) VALUES (9, 'pcs', 'COUNT') ON CONFLICT (id) DO UPDATE SET dimension = excluded.dimension;
\ No newline at end of file
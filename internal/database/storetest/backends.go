@@ -0,0 +1,90 @@
+package storetest
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"github.com/Kobietka/product-service/internal/database/setup"
+	"github.com/Kobietka/product-service/pkg/sqlite"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"os"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+var localHosts = []string{"localhost", "127.0.0.1", "::1"}
+
+// SqliteDb returns a seeded in-memory SQLite database that is closed when the
+// test ends.
+func SqliteDb(t testing.TB) *sql.DB {
+	t.Helper()
+
+	db, err := sqlite.Open(sqlite.Scheme + ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		db.Close()
+	})
+
+	seeder := setup.NewSqliteSeeder(db)
+	if err := seeder.CreateSchema(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if err := seeder.Seed(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+// PostgresPool connects to the Postgres instance of DATABASE_URL and skips the
+// test unless it is a local one. Every pool works in a schema of its own that
+// is created, seeded and dropped again, data outside of it is not touched.
+func PostgresPool(t testing.TB) *pgxpool.Pool {
+	t.Helper()
+
+	databaseUrl := os.Getenv("DATABASE_URL")
+	if !strings.HasPrefix(databaseUrl, "postgres://") && !strings.HasPrefix(databaseUrl, "postgresql://") {
+		t.Skip("DATABASE_URL does not point at Postgres")
+	}
+	poolConfig, err := pgxpool.ParseConfig(databaseUrl)
+	if err != nil {
+		t.Fatal(err)
+	}
+	host := poolConfig.ConnConfig.Host
+	if !slices.Contains(localHosts, host) && !strings.HasPrefix(host, "/") {
+		t.Skipf("DATABASE_URL points at %s, the conformance suite only runs against a local instance", host)
+	}
+
+	ctx := context.Background()
+	schema := fmt.Sprintf("conformance_%d", time.Now().UnixNano())
+	admin, err := pgxpool.NewWithConfig(ctx, poolConfig.Copy())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(admin.Close)
+	if _, err := admin.Exec(ctx, `CREATE SCHEMA `+schema); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		admin.Exec(context.Background(), `DROP SCHEMA `+schema+` CASCADE`)
+	})
+
+	poolConfig.ConnConfig.RuntimeParams["search_path"] = schema
+	pool, err := pgxpool.NewWithConfig(ctx, poolConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(pool.Close)
+
+	seeder := setup.NewSeeder(pool)
+	if err := seeder.CreateSchema(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := seeder.Seed(ctx); err != nil {
+		t.Fatal(err)
+	}
+	return pool
+}
@@ -0,0 +1,433 @@
+package storetest
+
+import (
+	"context"
+	"github.com/Kobietka/product-service/internal/products"
+	v1 "github.com/Kobietka/product-service/pkg/api/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+// ProductStoreFactory returns an empty store whose dictionaries are the
+// seeded ones. It is called once per subtest.
+type ProductStoreFactory func(t *testing.T) products.Store
+
+var oatBar = v1.Product{
+	Ean:       "4006381333931",
+	Name:      "Oat Bar",
+	Packaging: v1.Quantity{Value: 40, Unit: "g"},
+	Nutrition: v1.Nutrition{
+		Per:  v1.Quantity{Value: 100, Unit: "g"},
+		Kcal: 420,
+		Nutrients: []v1.Nutrient{
+			{T: "CARBOHYDRATES", Quantity: v1.Quantity{Value: 60, Unit: "g"}},
+			{T: "FAT", Quantity: v1.Quantity{Value: 15.5, Unit: "g"}},
+			{T: "PROTEIN", Quantity: v1.Quantity{Value: 9, Unit: "g"}},
+		},
+		Vitamins: []v1.Vitamin{
+			{T: "VITAMIN_E", Quantity: v1.Quantity{Value: 1.2, Unit: "mg"}},
+		},
+		Minerals: []v1.Mineral{
+			{T: "IRON", Quantity: v1.Quantity{Value: 3.5, Unit: "mg"}},
+		},
+	},
+	Components: []v1.Component{},
+	Servings: []v1.Serving{
+		{Name: "half", Quantity: v1.Quantity{Value: 20, Unit: "g"}},
+	},
+}
+
+var sticker = v1.Product{
+	Ean:       "12345670",
+	Name:      "Sticker",
+	Packaging: v1.Quantity{Value: 1, Unit: "pcs"},
+}
+
+var multipack = v1.Product{
+	Ean:       "4006381333948",
+	Name:      "Oat Bar Multipack",
+	Packaging: v1.Quantity{Value: 240, Unit: "g"},
+	Components: []v1.Component{
+		{Ean: oatBar.Ean, Count: 6},
+		{Ean: sticker.Ean, Count: 1},
+	},
+	Servings: []v1.Serving{
+		{Name: "two bars", Quantity: v1.Quantity{Value: 80, Unit: "g"}},
+		{Name: "one bar", Quantity: v1.Quantity{Value: 40, Unit: "g"}},
+	},
+}
+
+// RunProductStore checks that a products.Store behaves like PostgresStore:
+// what it reads back, the order of lists, search and the errors of every
+// method.
+func RunProductStore(t *testing.T, newStore ProductStoreFactory) {
+	t.Run("create and get", func(t *testing.T) {
+		testCreateAndGet(t, newStore(t))
+	})
+	t.Run("create errors", func(t *testing.T) {
+		testCreateErrors(t, newStore)
+	})
+	t.Run("update", func(t *testing.T) {
+		testUpdate(t, newStore(t))
+	})
+	t.Run("upsert", func(t *testing.T) {
+		testUpsert(t, newStore(t))
+	})
+	t.Run("upsert products", func(t *testing.T) {
+		testUpsertProducts(t, newStore(t))
+	})
+	t.Run("delete", func(t *testing.T) {
+		testDelete(t, newStore(t))
+	})
+	t.Run("get products", func(t *testing.T) {
+		testGetProducts(t, newStore(t))
+	})
+	t.Run("search", func(t *testing.T) {
+		testSearch(t, newStore(t))
+	})
+}
+
+func create(t *testing.T, store products.Store, products ...v1.Product) {
+	t.Helper()
+	for _, product := range products {
+		require.NoError(t, store.CreateProduct(context.Background(), product))
+	}
+}
+
+func testCreateAndGet(t *testing.T, store products.Store) {
+	ctx := context.Background()
+	create(t, store, oatBar, sticker, multipack)
+
+	product, err := store.GetProduct(ctx, oatBar.Ean)
+	assert.NoError(t, err)
+	assert.Equal(t, oatBar, product)
+
+	product, err = store.GetProduct(ctx, sticker.Ean)
+	assert.NoError(t, err)
+	assert.Equal(t, v1.Product{
+		Ean:        sticker.Ean,
+		Name:       sticker.Name,
+		Packaging:  sticker.Packaging,
+		Nutrition:  v1.Nutrition{Nutrients: []v1.Nutrient{}, Vitamins: []v1.Vitamin{}, Minerals: []v1.Mineral{}},
+		Components: []v1.Component{},
+		Servings:   []v1.Serving{},
+	}, product, "lists are empty, never nil")
+
+	product, err = store.GetProduct(ctx, multipack.Ean)
+	assert.NoError(t, err)
+	assert.Equal(t, []v1.Component{multipack.Components[1], multipack.Components[0]}, product.Components, "components are ordered by EAN")
+	assert.Equal(t, []v1.Serving{multipack.Servings[1], multipack.Servings[0]}, product.Servings, "servings are ordered by quantity")
+
+	rounded := sticker
+	rounded.Ean = "12345687"
+	rounded.Nutrition = v1.Nutrition{Per: v1.Quantity{Value: 2.5, Unit: "g"}, Kcal: 1}
+	create(t, store, rounded)
+	product, err = store.GetProduct(ctx, rounded.Ean)
+	assert.NoError(t, err)
+	assert.Equal(t, float32(2), product.Nutrition.Per.Value, "the nutrition quantity is rounded to even")
+
+	unordered := oatBar
+	unordered.Ean = "12345694"
+	unordered.Nutrition.Nutrients = []v1.Nutrient{oatBar.Nutrition.Nutrients[2], oatBar.Nutrition.Nutrients[1], oatBar.Nutrition.Nutrients[0]}
+	create(t, store, unordered)
+	product, err = store.GetProduct(ctx, unordered.Ean)
+	assert.NoError(t, err)
+	assert.Equal(t, oatBar.Nutrition.Nutrients, product.Nutrition.Nutrients, "nutrients are ordered by type")
+
+	_, err = store.GetProduct(ctx, "87654321")
+	assert.ErrorIs(t, err, v1.ErrorDataNotFound)
+}
+
+func testCreateErrors(t *testing.T, newStore ProductStoreFactory) {
+	invalid := func(change func(product *v1.Product)) v1.Product {
+		product := v1.Product{
+			Ean:       "12345678",
+			Name:      "Invalid",
+			Packaging: v1.Quantity{Value: 1, Unit: "g"},
+			Nutrition: v1.Nutrition{
+				Per:       v1.Quantity{Value: 100, Unit: "g"},
+				Nutrients: []v1.Nutrient{{T: "FAT", Quantity: v1.Quantity{Value: 1, Unit: "g"}}},
+			},
+		}
+		change(&product)
+		return product
+	}
+
+	tests := []struct {
+		Name        string
+		Product     v1.Product
+		ExpectedErr error
+	}{
+		{
+			Name:        "product already exists",
+			Product:     sticker,
+			ExpectedErr: v1.ErrorProductAlreadyExists,
+		},
+		{
+			Name:        "unknown packaging unit",
+			Product:     invalid(func(product *v1.Product) { product.Packaging.Unit = "oz" }),
+			ExpectedErr: v1.ErrorInvalidData,
+		},
+		{
+			Name:        "unknown nutrition unit",
+			Product:     invalid(func(product *v1.Product) { product.Nutrition.Per.Unit = "oz" }),
+			ExpectedErr: v1.ErrorInvalidData,
+		},
+		{
+			Name:        "unknown nutrient type",
+			Product:     invalid(func(product *v1.Product) { product.Nutrition.Nutrients[0].T = "CAFFEINE" }),
+			ExpectedErr: v1.ErrorInvalidData,
+		},
+		{
+			Name: "duplicate nutrient",
+			Product: invalid(func(product *v1.Product) {
+				product.Nutrition.Nutrients = append(product.Nutrition.Nutrients, product.Nutrition.Nutrients[0])
+			}),
+			ExpectedErr: v1.ErrorInvalidData,
+		},
+		{
+			Name: "unknown vitamin type",
+			Product: invalid(func(product *v1.Product) {
+				product.Nutrition.Vitamins = []v1.Vitamin{{T: "VITAMIN_Z", Quantity: v1.Quantity{Value: 1, Unit: "mg"}}}
+			}),
+			ExpectedErr: v1.ErrorInvalidData,
+		},
+		{
+			Name: "unknown mineral type",
+			Product: invalid(func(product *v1.Product) {
+				product.Nutrition.Minerals = []v1.Mineral{{T: "GOLD", Quantity: v1.Quantity{Value: 1, Unit: "mg"}}}
+			}),
+			ExpectedErr: v1.ErrorInvalidData,
+		},
+		{
+			Name: "missing component",
+			Product: invalid(func(product *v1.Product) {
+				product.Components = []v1.Component{{Ean: "87654321", Count: 1}}
+			}),
+			ExpectedErr: v1.ErrorInvalidData,
+		},
+		{
+			Name: "component count not positive",
+			Product: invalid(func(product *v1.Product) {
+				product.Components = []v1.Component{{Ean: sticker.Ean, Count: 0}}
+			}),
+			ExpectedErr: v1.ErrorInvalidData,
+		},
+		{
+			Name: "serving quantity not positive",
+			Product: invalid(func(product *v1.Product) {
+				product.Servings = []v1.Serving{{Name: "none", Quantity: v1.Quantity{Value: 0, Unit: "g"}}}
+			}),
+			ExpectedErr: v1.ErrorInvalidData,
+		},
+		{
+			Name: "unknown serving unit",
+			Product: invalid(func(product *v1.Product) {
+				product.Servings = []v1.Serving{{Name: "cup", Quantity: v1.Quantity{Value: 1, Unit: "cup"}}}
+			}),
+			ExpectedErr: v1.ErrorInvalidData,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			store := newStore(t)
+			create(t, store, sticker)
+
+			err := store.CreateProduct(context.Background(), test.Product)
+			assert.ErrorIs(t, err, test.ExpectedErr)
+
+			if test.Product.Ean != sticker.Ean {
+				_, err = store.GetProduct(context.Background(), test.Product.Ean)
+				assert.ErrorIs(t, err, v1.ErrorDataNotFound, "a failed create leaves nothing behind")
+			}
+		})
+	}
+}
+
+func testUpdate(t *testing.T, store products.Store) {
+	ctx := context.Background()
+
+	assert.ErrorIs(t, store.UpdateProduct(ctx, oatBar), v1.ErrorProductDoesNotExist)
+
+	create(t, store, oatBar, sticker)
+
+	updated := v1.Product{
+		Ean:        oatBar.Ean,
+		Name:       "Oat Bar Classic",
+		Packaging:  v1.Quantity{Value: 45, Unit: "g"},
+		Components: []v1.Component{{Ean: sticker.Ean, Count: 2}},
+	}
+	assert.NoError(t, store.UpdateProduct(ctx, updated))
+	product, err := store.GetProduct(ctx, oatBar.Ean)
+	assert.NoError(t, err)
+	assert.Equal(t, v1.Product{
+		Ean:        updated.Ean,
+		Name:       updated.Name,
+		Packaging:  updated.Packaging,
+		Nutrition:  v1.Nutrition{Nutrients: []v1.Nutrient{}, Vitamins: []v1.Vitamin{}, Minerals: []v1.Mineral{}},
+		Components: updated.Components,
+		Servings:   []v1.Serving{},
+	}, product, "an update replaces every row of the product")
+
+	invalid := oatBar
+	invalid.Nutrition.Nutrients = []v1.Nutrient{{T: "CAFFEINE", Quantity: v1.Quantity{Value: 1, Unit: "g"}}}
+	assert.ErrorIs(t, store.UpdateProduct(ctx, invalid), v1.ErrorInvalidData)
+	product, err = store.GetProduct(ctx, oatBar.Ean)
+	assert.NoError(t, err)
+	assert.Equal(t, updated.Name, product.Name, "a failed update changes nothing")
+	assert.Equal(t, updated.Components, product.Components, "a failed update changes nothing")
+}
+
+func testUpsert(t *testing.T, store products.Store) {
+	ctx := context.Background()
+	create(t, store, sticker)
+
+	created, err := store.UpsertProduct(ctx, oatBar)
+	assert.NoError(t, err)
+	assert.True(t, created)
+	product, err := store.GetProduct(ctx, oatBar.Ean)
+	assert.NoError(t, err)
+	assert.Equal(t, oatBar, product)
+
+	replaced := oatBar
+	replaced.Name = "Oat Bar Classic"
+	replaced.Nutrition.Nutrients = []v1.Nutrient{{T: "FAT", Quantity: v1.Quantity{Value: 14, Unit: "g"}}}
+	replaced.Nutrition.Vitamins = nil
+	replaced.Components = []v1.Component{{Ean: sticker.Ean, Count: 1}}
+	replaced.Servings = []v1.Serving{{Name: "bar", Quantity: v1.Quantity{Value: 40, Unit: "g"}}}
+	created, err = store.UpsertProduct(ctx, replaced)
+	assert.NoError(t, err)
+	assert.False(t, created)
+
+	product, err = store.GetProduct(ctx, oatBar.Ean)
+	assert.NoError(t, err)
+	replaced.Nutrition.Vitamins = []v1.Vitamin{}
+	assert.Equal(t, replaced, product, "rows missing from the product are removed")
+
+	invalid := replaced
+	invalid.Packaging.Unit = "oz"
+	_, err = store.UpsertProduct(ctx, invalid)
+	assert.ErrorIs(t, err, v1.ErrorInvalidData)
+	product, err = store.GetProduct(ctx, oatBar.Ean)
+	assert.NoError(t, err)
+	assert.Equal(t, replaced, product, "a failed upsert changes nothing")
+
+	invalid.Ean = "12345678"
+	_, err = store.UpsertProduct(ctx, invalid)
+	assert.ErrorIs(t, err, v1.ErrorInvalidData)
+	_, err = store.GetProduct(ctx, invalid.Ean)
+	assert.ErrorIs(t, err, v1.ErrorDataNotFound, "a failed upsert leaves nothing behind")
+}
+
+func testUpsertProducts(t *testing.T, store products.Store) {
+	ctx := context.Background()
+	create(t, store, sticker)
+
+	renamed := sticker
+	renamed.Name = "Sticker Sheet"
+	assert.NoError(t, store.UpsertProducts(ctx, []v1.Product{oatBar, renamed, multipack}))
+	products, err := store.GetProducts(ctx, []string{oatBar.Ean, sticker.Ean, multipack.Ean})
+	assert.NoError(t, err)
+	assert.Equal(t, oatBar, products[oatBar.Ean])
+	assert.Equal(t, renamed.Name, products[sticker.Ean].Name)
+	assert.ElementsMatch(t, multipack.Components, products[multipack.Ean].Components, "components may come earlier in the chunk")
+
+	invalid := sticker
+	invalid.Ean = "12345678"
+	invalid.Packaging.Unit = "oz"
+	replaced := oatBar
+	replaced.Name = "Oat Bar Classic"
+	assert.ErrorIs(t, store.UpsertProducts(ctx, []v1.Product{replaced, invalid}), v1.ErrorInvalidData)
+	product, err := store.GetProduct(ctx, oatBar.Ean)
+	assert.NoError(t, err)
+	assert.Equal(t, oatBar, product, "a failed chunk changes nothing")
+	_, err = store.GetProduct(ctx, invalid.Ean)
+	assert.ErrorIs(t, err, v1.ErrorDataNotFound, "a failed chunk leaves nothing behind")
+}
+
+func testDelete(t *testing.T, store products.Store) {
+	ctx := context.Background()
+	create(t, store, oatBar, sticker, multipack)
+
+	assert.ErrorIs(t, store.DeleteProduct(ctx, "87654321"), v1.ErrorProductDoesNotExist)
+	assert.ErrorIs(t, store.DeleteProduct(ctx, oatBar.Ean), v1.ErrorProductInUse)
+
+	assert.NoError(t, store.DeleteProduct(ctx, multipack.Ean))
+	_, err := store.GetProduct(ctx, multipack.Ean)
+	assert.ErrorIs(t, err, v1.ErrorDataNotFound)
+
+	assert.NoError(t, store.DeleteProduct(ctx, oatBar.Ean), "components are free once the product using them is gone")
+	assert.ErrorIs(t, store.DeleteProduct(ctx, oatBar.Ean), v1.ErrorProductDoesNotExist)
+
+	create(t, store, oatBar)
+	product, err := store.GetProduct(ctx, oatBar.Ean)
+	assert.NoError(t, err)
+	assert.Equal(t, oatBar, product, "a deleted product leaves no rows behind")
+}
+
+func testGetProducts(t *testing.T, store products.Store) {
+	ctx := context.Background()
+	create(t, store, oatBar, sticker, multipack)
+
+	oatBarProduct, err := store.GetProduct(ctx, oatBar.Ean)
+	require.NoError(t, err)
+	multipackProduct, err := store.GetProduct(ctx, multipack.Ean)
+	require.NoError(t, err)
+
+	found, err := store.GetProducts(ctx, []string{multipack.Ean, "87654321", oatBar.Ean, oatBar.Ean})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]v1.Product{
+		oatBar.Ean:    oatBarProduct,
+		multipack.Ean: multipackProduct,
+	}, found, "products read like GetProduct reads them, missing EANs are left out")
+
+	found, err = store.GetProducts(ctx, []string{})
+	assert.NoError(t, err)
+	assert.Empty(t, found)
+}
+
+func testSearch(t *testing.T, store products.Store) {
+	ctx := context.Background()
+	// The products are created out of EAN order, search returns them by EAN.
+	create(t, store,
+		v1.Product{Ean: "44444444", Name: "Barley", Packaging: v1.Quantity{Value: 1, Unit: "kg"}},
+		v1.Product{Ean: "22222222", Name: "Chocolate BAR", Packaging: v1.Quantity{Value: 100, Unit: "g"}},
+		v1.Product{Ean: "33333333", Name: "Milk 100%", Packaging: v1.Quantity{Value: 1, Unit: "l"}},
+		v1.Product{Ean: "11111111", Name: "Oat Bar", Packaging: v1.Quantity{Value: 40, Unit: "g"}},
+	)
+
+	tests := []struct {
+		Name         string
+		Query        string
+		Limit        int8
+		ExpectedEans []string
+	}{
+		{Name: "case-insensitive substring by ean", Query: "bar", Limit: 15, ExpectedEans: []string{"11111111", "22222222", "44444444"}},
+		{Name: "limited to the first eans", Query: "bar", Limit: 2, ExpectedEans: []string{"11111111", "22222222"}},
+		{Name: "single character wildcard", Query: "t_bar", Limit: 15, ExpectedEans: []string{"11111111"}},
+		{Name: "escaped wildcard", Query: `0\%`, Limit: 15, ExpectedEans: []string{"33333333"}},
+		{Name: "no match", Query: "bread", Limit: 15, ExpectedEans: []string{}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			found, err := store.SearchProducts(ctx, test.Query, test.Limit)
+			assert.NoError(t, err)
+			assert.NotNil(t, found)
+			eans := make([]string, 0)
+			for _, product := range found {
+				eans = append(eans, product.Ean)
+			}
+			assert.Equal(t, test.ExpectedEans, eans)
+		})
+	}
+
+	t.Run("full products", func(t *testing.T) {
+		create(t, store, oatBar)
+		found, err := store.SearchProducts(ctx, "oat bar", 15)
+		assert.NoError(t, err)
+		assert.Contains(t, found, oatBar)
+	})
+}
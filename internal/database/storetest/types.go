@@ -0,0 +1,64 @@
+package storetest
+
+import (
+	"context"
+	"github.com/Kobietka/product-service/internal/database/setup"
+	"github.com/Kobietka/product-service/internal/types"
+	"github.com/Kobietka/product-service/pkg/array"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+// TypeStoreFactory returns a store holding the seeded dictionaries.
+type TypeStoreFactory func(t *testing.T) types.Store
+
+// RunTypeStore checks that a types.Store serves the dictionaries of seed.sql.
+// Lists are compared without their order, PostgresStore does not sort them.
+func RunTypeStore(t *testing.T, newStore TypeStoreFactory) {
+	fixture := setup.DefaultFixture()
+	store := newStore(t)
+	ctx := context.Background()
+
+	t.Run("units", func(t *testing.T) {
+		units, err := store.GetUnits(ctx)
+		assert.NoError(t, err)
+		assert.ElementsMatch(t, array.MapArray(fixture.Units, func(unit setup.FixtureUnit) string {
+			return unit.Value
+		}), units)
+	})
+
+	t.Run("unit dimensions", func(t *testing.T) {
+		dimensions, err := store.GetUnitDimensions(ctx)
+		assert.NoError(t, err)
+		expected := make(map[string]types.Dimension, len(fixture.Units))
+		for _, unit := range fixture.Units {
+			expected[unit.Value] = unit.Dimension
+		}
+		assert.Equal(t, expected, dimensions)
+	})
+
+	t.Run("nutrient types", func(t *testing.T) {
+		nutrientTypes, err := store.GetNutrientTypes(ctx)
+		assert.NoError(t, err)
+		assert.ElementsMatch(t, fixture.NutrientTypes, nutrientTypes)
+	})
+
+	t.Run("vitamin types", func(t *testing.T) {
+		vitaminTypes, err := store.GetVitaminTypes(ctx)
+		assert.NoError(t, err)
+		assert.ElementsMatch(t, fixture.VitaminTypes, vitaminTypes)
+	})
+
+	t.Run("mineral types", func(t *testing.T) {
+		mineralTypes, err := store.GetMineralTypes(ctx)
+		assert.NoError(t, err)
+		assert.ElementsMatch(t, fixture.MineralTypes, mineralTypes)
+	})
+
+	t.Run("dictionaries", func(t *testing.T) {
+		dictionaries, err := types.LoadDictionaries(ctx, store)
+		assert.NoError(t, err)
+		assert.True(t, dictionaries.NutrientTypes["FAT"])
+		assert.Equal(t, types.DimensionEnergy, dictionaries.Units["kcal"])
+	})
+}
@@ -0,0 +1,278 @@
+package ean
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"io"
+	"math"
+	"slices"
+)
+
+var (
+	ErrorBarcodeNotFound = errors.New("BARCODE_NOT_FOUND")
+	ErrorImageTooLarge   = errors.New("IMAGE_TOO_LARGE")
+)
+
+const (
+	MaxImagePixels     = 40_000_000
+	scanLines          = 48
+	maxDigitVariance   = 0.38
+	maxGuardVariance   = 0.5
+	minQuietZoneFactor = 3
+)
+
+// digitRuns holds the widths of the four alternating runs of every left-hand
+// odd parity digit. Right-hand codes share the widths with the colours
+// inverted and even parity codes are the right-hand codes mirrored.
+var digitRuns = [10][4]float64{
+	{3, 2, 1, 1}, {2, 2, 2, 1}, {2, 1, 2, 2}, {1, 4, 1, 1}, {1, 1, 3, 2},
+	{1, 2, 3, 1}, {1, 1, 1, 4}, {1, 3, 1, 2}, {1, 2, 1, 3}, {3, 1, 1, 2},
+}
+
+// ReadImage decodes an image of a registered format, images declaring more
+// than MaxImagePixels are rejected before their pixels are decoded.
+func ReadImage(r io.Reader) (image.Image, error) {
+	var header bytes.Buffer
+	config, _, err := image.DecodeConfig(io.TeeReader(r, &header))
+	if err != nil {
+		return nil, err
+	}
+	if config.Width*config.Height > MaxImagePixels {
+		return nil, ErrorImageTooLarge
+	}
+
+	img, _, err := image.Decode(io.MultiReader(&header, r))
+	return img, err
+}
+
+// DecodeImage looks for a single EAN-13, EAN-8 or UPC-A symbol in a photo by
+// sampling horizontal and vertical scan lines in both directions. Only codes
+// with a correct check digit are returned.
+func DecodeImage(img image.Image) (string, error) {
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	if width*height > MaxImagePixels {
+		return "", ErrorImageTooLarge
+	}
+	luminance := make([]uint8, width*height)
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			switch typed := img.(type) {
+			case *image.YCbCr:
+				luminance[y*width+x] = typed.Y[typed.YOffset(bounds.Min.X+x, bounds.Min.Y+y)]
+			case *image.Gray:
+				luminance[y*width+x] = typed.GrayAt(bounds.Min.X+x, bounds.Min.Y+y).Y
+			default:
+				gray := color.GrayModel.Convert(img.At(bounds.Min.X+x, bounds.Min.Y+y)).(color.Gray)
+				luminance[y*width+x] = gray.Y
+			}
+		}
+	}
+
+	horizontal := func(index, position int) uint8 { return luminance[index*width+position] }
+	vertical := func(index, position int) uint8 { return luminance[position*width+index] }
+
+	if code, ok := scan(height, width, horizontal); ok {
+		return code, nil
+	}
+	if code, ok := scan(width, height, vertical); ok {
+		return code, nil
+	}
+	return "", ErrorBarcodeNotFound
+}
+
+func scan(lines, length int, pixel func(index, position int) uint8) (string, bool) {
+	if lines == 0 || length == 0 {
+		return "", false
+	}
+
+	step := max(1, lines/scanLines)
+	middle := lines / 2
+	line := make([]uint8, length)
+	for offset := 0; offset <= middle; offset += step {
+		for _, index := range []int{middle - offset, middle + offset} {
+			if index < 0 || index >= lines {
+				continue
+			}
+			for position := range line {
+				line[position] = pixel(index, position)
+			}
+
+			runs := toRuns(line)
+			if code, ok := decodeRuns(runs); ok {
+				return code, true
+			}
+			slices.Reverse(runs)
+			if code, ok := decodeRuns(runs); ok {
+				return code, true
+			}
+		}
+	}
+	return "", false
+}
+
+// toRuns binarises a scan line and returns run lengths starting with a light
+// run, so that even indices are spaces and odd indices are bars.
+func toRuns(line []uint8) []int {
+	sorted := slices.Clone(line)
+	slices.Sort(sorted)
+	low := int(sorted[len(sorted)/20])
+	high := int(sorted[len(sorted)-1-len(sorted)/20])
+	if high-low < 32 {
+		return nil
+	}
+	threshold := uint8((low + high) / 2)
+
+	runs := []int{0}
+	dark := false
+	for _, value := range line {
+		isDark := value < threshold
+		if isDark != dark {
+			runs = append(runs, 0)
+			dark = isDark
+		}
+		runs[len(runs)-1]++
+	}
+	if dark {
+		runs = append(runs, 0)
+	}
+	return runs
+}
+
+func decodeRuns(runs []int) (string, bool) {
+	for start := 1; start < len(runs); start += 2 {
+		if code, ok := decodeAt(runs, start, 6); ok {
+			return code, true
+		}
+		if code, ok := decodeAt(runs, start, 4); ok {
+			return code, true
+		}
+	}
+	return "", false
+}
+
+// decodeAt tries to read a symbol with the given number of digits per half
+// whose start guard begins at the bar run with the given index.
+func decodeAt(runs []int, start, half int) (string, bool) {
+	count := 3 + half*4 + 5 + half*4 + 3
+	modules := 3 + half*7 + 5 + half*7 + 3
+	if start+count+1 > len(runs) {
+		return "", false
+	}
+
+	total := 0
+	for _, run := range runs[start : start+count] {
+		total += run
+	}
+	module := float64(total) / float64(modules)
+
+	if float64(runs[start-1]) < minQuietZoneFactor*module || float64(runs[start+count]) < minQuietZoneFactor*module {
+		return "", false
+	}
+
+	position := start
+	if !matchGuard(runs[position:position+3], module) {
+		return "", false
+	}
+	position += 3
+
+	digits := make([]byte, 0, half*2+1)
+	parity := make([]byte, 0, half)
+	for i := 0; i < half; i++ {
+		digit, even, ok := matchDigit(runs[position:position+4], true)
+		if !ok || (half == 4 && even) {
+			return "", false
+		}
+		digits = append(digits, digit)
+		if even {
+			parity = append(parity, 'E')
+		} else {
+			parity = append(parity, 'O')
+		}
+		position += 4
+	}
+
+	if !matchGuard(runs[position:position+5], module) {
+		return "", false
+	}
+	position += 5
+
+	for i := 0; i < half; i++ {
+		digit, _, ok := matchDigit(runs[position:position+4], false)
+		if !ok {
+			return "", false
+		}
+		digits = append(digits, digit)
+		position += 4
+	}
+
+	if !matchGuard(runs[position:position+3], module) {
+		return "", false
+	}
+
+	code := string(digits)
+	if half == 6 {
+		first := slices.Index(ean13Parities, string(parity))
+		if first < 0 {
+			return "", false
+		}
+		code = string(rune('0'+first)) + code
+		if first == 0 {
+			code = code[1:]
+		}
+	}
+
+	if CheckDigit(code[:len(code)-1]) != code[len(code)-1] {
+		return "", false
+	}
+	return code, true
+}
+
+func matchGuard(runs []int, module float64) bool {
+	for _, run := range runs {
+		if math.Abs(float64(run)/module-1) > maxGuardVariance {
+			return false
+		}
+	}
+	return true
+}
+
+func matchDigit(runs []int, left bool) (byte, bool, bool) {
+	total := 0
+	for _, run := range runs {
+		total += run
+	}
+	if total == 0 {
+		return 0, false, false
+	}
+
+	normalised := [4]float64{}
+	for i, run := range runs {
+		normalised[i] = float64(run) * 7 / float64(total)
+	}
+
+	bestDigit, bestEven, bestVariance := byte(0), false, math.MaxFloat64
+	for digit, pattern := range digitRuns {
+		for _, even := range []bool{false, true} {
+			if even && !left {
+				continue
+			}
+			if even {
+				pattern = [4]float64{pattern[3], pattern[2], pattern[1], pattern[0]}
+			}
+
+			variance := 0.0
+			for i := range normalised {
+				variance += math.Abs(normalised[i] - pattern[i])
+			}
+			variance /= 4
+			if variance < bestVariance {
+				bestDigit, bestEven, bestVariance = byte('0'+digit), even, variance
+			}
+		}
+	}
+
+	return bestDigit, bestEven, bestVariance <= maxDigitVariance
+}
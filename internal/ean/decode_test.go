@@ -0,0 +1,129 @@
+package ean
+
+import (
+	"bytes"
+	"encoding/binary"
+	"github.com/stretchr/testify/assert"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/jpeg"
+	"image/png"
+	"math/rand"
+	"testing"
+)
+
+func renderedSymbol(t *testing.T, code string, scale int) image.Image {
+	symbol, err := Encode(code)
+	assert.NoError(t, err)
+	data, err := symbol.Png(scale)
+	assert.NoError(t, err)
+	img, err := png.Decode(bytes.NewReader(data))
+	assert.NoError(t, err)
+	return img
+}
+
+func photo(symbol image.Image, rotate bool) image.Image {
+	bounds := symbol.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	if rotate {
+		width, height = height, width
+	}
+
+	random := rand.New(rand.NewSource(1))
+	canvas := image.NewGray(image.Rect(0, 0, width+120, height+80))
+	for y := 0; y < canvas.Bounds().Dy(); y++ {
+		for x := 0; x < canvas.Bounds().Dx(); x++ {
+			canvas.SetGray(x, y, color.Gray{Y: uint8(200 + random.Intn(40))})
+		}
+	}
+
+	if !rotate {
+		draw.Draw(canvas, image.Rect(60, 40, 60+width, 40+height), symbol, bounds.Min, draw.Over)
+	} else {
+		for y := 0; y < bounds.Dy(); y++ {
+			for x := 0; x < bounds.Dx(); x++ {
+				canvas.Set(60+bounds.Dy()-1-y, 40+x, symbol.At(x, y))
+			}
+		}
+	}
+
+	for i := range canvas.Pix {
+		noise := random.Intn(50) - 25
+		canvas.Pix[i] = uint8(min(255, max(0, int(canvas.Pix[i])+noise)))
+	}
+	return canvas
+}
+
+func TestDecodeImage(t *testing.T) {
+	tests := []struct {
+		Name   string
+		Code   string
+		Scale  int
+		Rotate bool
+		Jpeg   bool
+	}{
+		{Name: "ean-13", Code: "5901234123457", Scale: 2},
+		{Name: "ean-8", Code: "96385074", Scale: 3},
+		{Name: "upc-a", Code: "036000291452", Scale: 2},
+		{Name: "rotated ean-13", Code: "4006381333931", Scale: 3, Rotate: true},
+		{Name: "jpeg ean-13", Code: "5901234123457", Scale: 3, Jpeg: true},
+	}
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			img := photo(renderedSymbol(t, test.Code, test.Scale), test.Rotate)
+			if test.Jpeg {
+				var buffer bytes.Buffer
+				assert.NoError(t, jpeg.Encode(&buffer, img, &jpeg.Options{Quality: 60}))
+				decoded, err := jpeg.Decode(&buffer)
+				assert.NoError(t, err)
+				img = decoded
+			}
+
+			code, err := DecodeImage(img)
+			assert.NoError(t, err)
+			assert.Equal(t, test.Code, code)
+		})
+	}
+}
+
+func TestDecodeImageNotFound(t *testing.T) {
+	img := image.NewGray(image.Rect(0, 0, 200, 100))
+	for i := range img.Pix {
+		img.Pix[i] = uint8(i % 7 * 30)
+	}
+
+	_, err := DecodeImage(img)
+	assert.Equal(t, ErrorBarcodeNotFound, err)
+}
+
+// oversizedPng encodes a one pixel PNG whose header declares width and height.
+func oversizedPng(t *testing.T, width, height uint32) []byte {
+	var buffer bytes.Buffer
+	assert.NoError(t, png.Encode(&buffer, image.NewGray(image.Rect(0, 0, 1, 1))))
+	data := buffer.Bytes()
+	binary.BigEndian.PutUint32(data[16:], width)
+	binary.BigEndian.PutUint32(data[20:], height)
+	binary.BigEndian.PutUint32(data[29:], crc32.ChecksumIEEE(data[12:29]))
+	return data
+}
+
+func TestReadImage(t *testing.T) {
+	symbol, err := Encode("5901234123457")
+	assert.NoError(t, err)
+	data, err := symbol.Png(2)
+	assert.NoError(t, err)
+
+	img, err := ReadImage(bytes.NewReader(data))
+	assert.NoError(t, err)
+	code, err := DecodeImage(img)
+	assert.NoError(t, err)
+	assert.Equal(t, "5901234123457", code)
+
+	_, err = ReadImage(bytes.NewReader(oversizedPng(t, 60000, 60000)))
+	assert.Equal(t, ErrorImageTooLarge, err)
+
+	_, err = ReadImage(bytes.NewReader([]byte("text")))
+	assert.Error(t, err)
+}
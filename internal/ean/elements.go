@@ -0,0 +1,282 @@
+package ean
+
+import (
+	"errors"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const groupSeparator = '\x1d'
+
+const (
+	AiSscc        = "00"
+	AiGtin        = "01"
+	AiContent     = "02"
+	AiBatch       = "10"
+	AiProduction  = "11"
+	AiDue         = "12"
+	AiPackaging   = "13"
+	AiBestBefore  = "15"
+	AiSellBy      = "16"
+	AiExpiry      = "17"
+	AiVariant     = "20"
+	AiSerial      = "21"
+	AiVarCount    = "30"
+	AiNetWeightKg = "310"
+	AiCount       = "37"
+	AiPrice       = "392"
+)
+
+var (
+	ErrorElementStringEmpty   = errors.New("GS1_DATA_EMPTY")
+	ErrorElementUnknown       = errors.New("GS1_AI_UNKNOWN")
+	ErrorElementInvalid       = errors.New("GS1_AI_VALUE_INVALID")
+	ErrorElementStringInvalid = errors.New("GS1_DATA_INVALID")
+)
+
+type elementDefinition struct {
+	Ai        string
+	Title     string
+	Length    int
+	MaxLength int
+	Decimals  bool
+	Date      bool
+	Numeric   bool
+}
+
+var elementDefinitions = []elementDefinition{
+	{Ai: AiSscc, Title: "SSCC", Length: 18, Numeric: true},
+	{Ai: AiGtin, Title: "GTIN", Length: 14, Numeric: true},
+	{Ai: AiContent, Title: "CONTENT", Length: 14, Numeric: true},
+	{Ai: AiBatch, Title: "BATCH/LOT", MaxLength: 20},
+	{Ai: AiProduction, Title: "PROD DATE", Length: 6, Date: true},
+	{Ai: AiDue, Title: "DUE DATE", Length: 6, Date: true},
+	{Ai: AiPackaging, Title: "PACK DATE", Length: 6, Date: true},
+	{Ai: AiBestBefore, Title: "BEST BEFORE", Length: 6, Date: true},
+	{Ai: AiSellBy, Title: "SELL BY", Length: 6, Date: true},
+	{Ai: AiExpiry, Title: "USE BY", Length: 6, Date: true},
+	{Ai: AiVariant, Title: "VARIANT", Length: 2, Numeric: true},
+	{Ai: AiSerial, Title: "SERIAL", MaxLength: 20},
+	{Ai: AiVarCount, Title: "VAR. COUNT", MaxLength: 8, Numeric: true},
+	{Ai: AiNetWeightKg, Title: "NET WEIGHT (kg)", Length: 6, Decimals: true, Numeric: true},
+	{Ai: AiCount, Title: "COUNT", MaxLength: 8, Numeric: true},
+	{Ai: AiPrice, Title: "PRICE", MaxLength: 15, Decimals: true, Numeric: true},
+	{Ai: "400", Title: "ORDER NUMBER", MaxLength: 30},
+	{Ai: "410", Title: "SHIP TO LOC", Length: 13, Numeric: true},
+	{Ai: "414", Title: "LOC No.", Length: 13, Numeric: true},
+	{Ai: "422", Title: "ORIGIN", Length: 3, Numeric: true},
+}
+
+type Element struct {
+	Ai    string
+	Title string
+	Value string
+	Date  *time.Time
+	// Number is set for AIs carrying an implied decimal point such as 310n.
+	Number *float64
+}
+
+// ParseElementString reads GS1 element strings either in the human readable
+// parenthesised form "(01)05901234123457(10)ABC" or in the scanned form where
+// variable length fields are terminated by FNC1, transmitted as the ASCII
+// group separator. A leading symbology identifier such as "]d2" is skipped.
+func ParseElementString(data string) ([]Element, error) {
+	data = strings.TrimSpace(data)
+	if strings.HasPrefix(data, "]") && len(data) >= 3 {
+		data = data[3:]
+	}
+	data = strings.TrimPrefix(data, string(groupSeparator))
+	if data == "" {
+		return nil, ErrorElementStringEmpty
+	}
+
+	if strings.HasPrefix(data, "(") {
+		return parseParenthesised(data)
+	}
+	return parseScanned(data)
+}
+
+func parseParenthesised(data string) ([]Element, error) {
+	elements := make([]Element, 0)
+	for data != "" {
+		if data[0] != '(' {
+			return nil, ErrorElementStringInvalid
+		}
+		end := strings.IndexByte(data, ')')
+		if end < 0 {
+			return nil, ErrorElementStringInvalid
+		}
+		ai := data[1:end]
+		data = data[end+1:]
+
+		next := strings.IndexByte(data, '(')
+		if next < 0 {
+			next = len(data)
+		}
+		value := data[:next]
+		data = data[next:]
+
+		definition, ok := findDefinition(ai)
+		if !ok || !isDigits(ai) || (!definition.Decimals && ai != definition.Ai) {
+			return nil, ErrorElementUnknown
+		}
+		element, err := newElement(definition, ai, value)
+		if err != nil {
+			return nil, err
+		}
+		elements = append(elements, element)
+	}
+	return elements, nil
+}
+
+func parseScanned(data string) ([]Element, error) {
+	elements := make([]Element, 0)
+	for data != "" {
+		definition, ok := findDefinition(data)
+		if !ok {
+			return nil, ErrorElementUnknown
+		}
+
+		aiLength := len(definition.Ai)
+		if definition.Decimals {
+			aiLength++
+		}
+		if len(data) < aiLength {
+			return nil, ErrorElementStringInvalid
+		}
+		ai := data[:aiLength]
+		data = data[aiLength:]
+
+		var value string
+		if definition.Length > 0 {
+			if len(data) < definition.Length {
+				return nil, ErrorElementInvalid
+			}
+			value = data[:definition.Length]
+			data = data[definition.Length:]
+		} else {
+			end := strings.IndexByte(data, groupSeparator)
+			if end < 0 {
+				end = len(data)
+			}
+			value = data[:end]
+			data = data[end:]
+		}
+		data = strings.TrimPrefix(data, string(groupSeparator))
+
+		element, err := newElement(definition, ai, value)
+		if err != nil {
+			return nil, err
+		}
+		elements = append(elements, element)
+	}
+	return elements, nil
+}
+
+func findDefinition(data string) (elementDefinition, bool) {
+	for _, definition := range elementDefinitions {
+		if strings.HasPrefix(data, definition.Ai) {
+			return definition, true
+		}
+	}
+	return elementDefinition{}, false
+}
+
+func newElement(definition elementDefinition, ai, value string) (Element, error) {
+	if definition.Length > 0 && len(value) != definition.Length {
+		return Element{}, ErrorElementInvalid
+	}
+	if definition.MaxLength > 0 && (len(value) == 0 || len(value) > definition.MaxLength) {
+		return Element{}, ErrorElementInvalid
+	}
+	if definition.Numeric && !isDigits(value) {
+		return Element{}, ErrorElementInvalid
+	}
+
+	element := Element{Ai: ai, Title: definition.Title, Value: value}
+
+	if definition.Decimals {
+		if len(ai) != len(definition.Ai)+1 {
+			return Element{}, ErrorElementUnknown
+		}
+		decimals := int(ai[len(ai)-1] - '0')
+		number, _ := strconv.ParseFloat(value, 64)
+		number = number / math.Pow10(decimals)
+		element.Number = &number
+	}
+
+	if definition.Date {
+		date, err := parseElementDate(value, time.Now())
+		if err != nil {
+			return Element{}, ErrorElementInvalid
+		}
+		element.Date = &date
+	}
+
+	if definition.Ai == AiGtin && CheckDigit(value[:13]) != value[13] {
+		return Element{}, ErrorElementInvalid
+	}
+
+	return element, nil
+}
+
+// parseElementDate reads a YYMMDD date, a day of 00 is the last day of the
+// month. The century is the one that puts the year between 49 years before
+// and 50 years after now, as the GS1 General Specifications define.
+func parseElementDate(value string, now time.Time) (time.Time, error) {
+	if len(value) != 6 || !isDigits(value) {
+		return time.Time{}, ErrorElementInvalid
+	}
+	yy, _ := strconv.Atoi(value[:2])
+	month, _ := strconv.Atoi(value[2:4])
+	day, _ := strconv.Atoi(value[4:])
+
+	century := now.Year() / 100 * 100
+	switch difference := yy - now.Year()%100; {
+	case difference >= 51:
+		century -= 100
+	case difference <= -50:
+		century += 100
+	}
+	year := century + yy
+
+	if month < 1 || month > 12 {
+		return time.Time{}, ErrorElementInvalid
+	}
+	if day == 0 {
+		return time.Date(year, time.Month(month)+1, 0, 0, 0, 0, 0, time.UTC), nil
+	}
+	date := time.Date(year, time.Month(month), day, 0, 0, 0, 0, time.UTC)
+	if date.Day() != day {
+		return time.Time{}, ErrorElementInvalid
+	}
+	return date, nil
+}
+
+func isDigits(value string) bool {
+	for _, char := range value {
+		if char < '0' || char > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// GtinCandidates lists the EAN-13, UPC-A and EAN-8 codes a GTIN-14 can be
+// stored under, from the longest to the shortest.
+func GtinCandidates(gtin string) []string {
+	candidates := make([]string, 0)
+	if len(gtin) != 14 || gtin[0] != '0' {
+		return candidates
+	}
+	candidates = append(candidates, gtin[1:])
+	if gtin[1] == '0' {
+		candidates = append(candidates, gtin[2:])
+	}
+	if strings.HasPrefix(gtin, "000000") {
+		candidates = append(candidates, gtin[6:])
+	}
+	return candidates
+}
@@ -0,0 +1,160 @@
+package ean
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestParseElementString(t *testing.T) {
+	bestBefore := time.Date(2025, time.December, 31, 0, 0, 0, 0, time.UTC)
+	endOfFebruary := time.Date(2024, time.February, 29, 0, 0, 0, 0, time.UTC)
+	weight := 1.25
+	price := 12.5
+
+	tests := []struct {
+		Name             string
+		Data             string
+		ExpectedElements []Element
+		ExpectedErr      error
+	}{
+		{
+			Name: "parenthesised",
+			Data: "(01)05901234123457(10)ABC123(15)251231(3103)001250",
+			ExpectedElements: []Element{
+				{Ai: "01", Title: "GTIN", Value: "05901234123457"},
+				{Ai: "10", Title: "BATCH/LOT", Value: "ABC123"},
+				{Ai: "15", Title: "BEST BEFORE", Value: "251231", Date: &bestBefore},
+				{Ai: "3103", Title: "NET WEIGHT (kg)", Value: "001250", Number: &weight},
+			},
+		},
+		{
+			Name: "scanned with symbology identifier and fnc1",
+			Data: "]d20105901234123457" + "10ABC123\x1d" + "3103001250" + "15240200",
+			ExpectedElements: []Element{
+				{Ai: "01", Title: "GTIN", Value: "05901234123457"},
+				{Ai: "10", Title: "BATCH/LOT", Value: "ABC123"},
+				{Ai: "3103", Title: "NET WEIGHT (kg)", Value: "001250", Number: &weight},
+				{Ai: "15", Title: "BEST BEFORE", Value: "240200", Date: &endOfFebruary},
+			},
+		},
+		{
+			Name: "scanned with leading fnc1 and trailing variable field",
+			Data: "\x1d01059012341234573922" + "1250",
+			ExpectedElements: []Element{
+				{Ai: "01", Title: "GTIN", Value: "05901234123457"},
+				{Ai: "3922", Title: "PRICE", Value: "1250", Number: &price},
+			},
+		},
+		{
+			Name:        "empty",
+			Data:        "  ",
+			ExpectedErr: ErrorElementStringEmpty,
+		},
+		{
+			Name:        "unknown ai",
+			Data:        "(99)ABC",
+			ExpectedErr: ErrorElementUnknown,
+		},
+		{
+			Name:        "gtin with invalid check digit",
+			Data:        "(01)05901234123458",
+			ExpectedErr: ErrorElementInvalid,
+		},
+		{
+			Name:        "gtin too short",
+			Data:        "010590123412",
+			ExpectedErr: ErrorElementInvalid,
+		},
+		{
+			Name:        "invalid date",
+			Data:        "(15)251341",
+			ExpectedErr: ErrorElementInvalid,
+		},
+		{
+			Name:        "unterminated ai",
+			Data:        "(01",
+			ExpectedErr: ErrorElementStringInvalid,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			elements, err := ParseElementString(test.Data)
+			assert.Equal(t, test.ExpectedErr, err)
+			assert.Equal(t, test.ExpectedElements, elements)
+		})
+	}
+}
+
+func TestParseElementDate(t *testing.T) {
+	tests := []struct {
+		Name         string
+		Value        string
+		Now          time.Time
+		ExpectedDate time.Time
+		ExpectedErr  error
+	}{
+		{
+			Name:         "this century",
+			Value:        "251231",
+			Now:          time.Date(2026, time.October, 19, 0, 0, 0, 0, time.UTC),
+			ExpectedDate: time.Date(2025, time.December, 31, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			Name:         "50 years ahead stays in this century",
+			Value:        "760101",
+			Now:          time.Date(2026, time.October, 19, 0, 0, 0, 0, time.UTC),
+			ExpectedDate: time.Date(2076, time.January, 1, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			Name:         "51 years ahead is the last century",
+			Value:        "770101",
+			Now:          time.Date(2026, time.October, 19, 0, 0, 0, 0, time.UTC),
+			ExpectedDate: time.Date(1977, time.January, 1, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			Name:         "next century near its start",
+			Value:        "020315",
+			Now:          time.Date(2098, time.June, 1, 0, 0, 0, 0, time.UTC),
+			ExpectedDate: time.Date(2102, time.March, 15, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			Name:         "49 years back stays in this century",
+			Value:        "490315",
+			Now:          time.Date(2098, time.June, 1, 0, 0, 0, 0, time.UTC),
+			ExpectedDate: time.Date(2049, time.March, 15, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			Name:         "last day of the month",
+			Value:        "240200",
+			Now:          time.Date(2026, time.October, 19, 0, 0, 0, 0, time.UTC),
+			ExpectedDate: time.Date(2024, time.February, 29, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			Name:        "day past the end of the month",
+			Value:       "250230",
+			Now:         time.Date(2026, time.October, 19, 0, 0, 0, 0, time.UTC),
+			ExpectedErr: ErrorElementInvalid,
+		},
+		{
+			Name:        "invalid month",
+			Value:       "251301",
+			Now:         time.Date(2026, time.October, 19, 0, 0, 0, 0, time.UTC),
+			ExpectedErr: ErrorElementInvalid,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			date, err := parseElementDate(test.Value, test.Now)
+			assert.Equal(t, test.ExpectedErr, err)
+			assert.Equal(t, test.ExpectedDate, date)
+		})
+	}
+}
+
+func TestGtinCandidates(t *testing.T) {
+	assert.Equal(t, []string{"5901234123457"}, GtinCandidates("05901234123457"))
+	assert.Equal(t, []string{"0036000291452", "036000291452"}, GtinCandidates("00036000291452"))
+	assert.Equal(t, []string{"0000059012341", "000059012341", "59012341"}, GtinCandidates("00000059012341"))
+	assert.Equal(t, []string{}, GtinCandidates("15901234123454"))
+}
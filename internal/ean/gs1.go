@@ -0,0 +1,218 @@
+package ean
+
+import (
+	"errors"
+	"strconv"
+)
+
+type Format string
+
+const (
+	FormatEan8  Format = "EAN_8"
+	FormatEan13 Format = "EAN_13"
+	FormatUpcA  Format = "UPC_A"
+)
+
+type Range string
+
+const (
+	RangeGtin          Range = "GTIN"
+	RangeInStore       Range = "IN_STORE"
+	RangeCoupon        Range = "COUPON"
+	RangeIsbn          Range = "ISBN"
+	RangeIssn          Range = "ISSN"
+	RangeRefundReceipt Range = "REFUND_RECEIPT"
+	RangeGlobalOffice  Range = "GS1_GLOBAL_OFFICE"
+	RangeUnassigned    Range = "UNASSIGNED"
+)
+
+var (
+	ErrorCodeInvalid = errors.New("EAN_INVALID")
+)
+
+type Description struct {
+	Code    string
+	Format  Format
+	Prefix  string
+	Country string
+	Range   Range
+}
+
+type prefixRange struct {
+	From    int
+	To      int
+	Country string
+	Range   Range
+}
+
+var prefixes = []prefixRange{
+	{0, 19, "United States and Canada", RangeGtin},
+	{20, 29, "", RangeInStore},
+	{30, 39, "United States", RangeGtin},
+	{40, 49, "", RangeInStore},
+	{50, 59, "", RangeCoupon},
+	{60, 139, "United States", RangeGtin},
+	{200, 299, "", RangeInStore},
+	{300, 379, "France and Monaco", RangeGtin},
+	{380, 380, "Bulgaria", RangeGtin},
+	{383, 383, "Slovenia", RangeGtin},
+	{385, 385, "Croatia", RangeGtin},
+	{387, 387, "Bosnia and Herzegovina", RangeGtin},
+	{389, 389, "Montenegro", RangeGtin},
+	{390, 390, "Kosovo", RangeGtin},
+	{400, 440, "Germany", RangeGtin},
+	{450, 459, "Japan", RangeGtin},
+	{460, 469, "Russia", RangeGtin},
+	{470, 470, "Kyrgyzstan", RangeGtin},
+	{471, 471, "Taiwan", RangeGtin},
+	{474, 474, "Estonia", RangeGtin},
+	{475, 475, "Latvia", RangeGtin},
+	{476, 476, "Azerbaijan", RangeGtin},
+	{477, 477, "Lithuania", RangeGtin},
+	{478, 478, "Uzbekistan", RangeGtin},
+	{479, 479, "Sri Lanka", RangeGtin},
+	{480, 480, "Philippines", RangeGtin},
+	{481, 481, "Belarus", RangeGtin},
+	{482, 482, "Ukraine", RangeGtin},
+	{483, 483, "Turkmenistan", RangeGtin},
+	{484, 484, "Moldova", RangeGtin},
+	{485, 485, "Armenia", RangeGtin},
+	{486, 486, "Georgia", RangeGtin},
+	{487, 487, "Kazakhstan", RangeGtin},
+	{488, 488, "Tajikistan", RangeGtin},
+	{489, 489, "Hong Kong", RangeGtin},
+	{490, 499, "Japan", RangeGtin},
+	{500, 509, "United Kingdom", RangeGtin},
+	{520, 521, "Greece", RangeGtin},
+	{528, 528, "Lebanon", RangeGtin},
+	{529, 529, "Cyprus", RangeGtin},
+	{530, 530, "Albania", RangeGtin},
+	{531, 531, "North Macedonia", RangeGtin},
+	{535, 535, "Malta", RangeGtin},
+	{539, 539, "Ireland", RangeGtin},
+	{540, 549, "Belgium and Luxembourg", RangeGtin},
+	{560, 560, "Portugal", RangeGtin},
+	{569, 569, "Iceland", RangeGtin},
+	{570, 579, "Denmark", RangeGtin},
+	{590, 590, "Poland", RangeGtin},
+	{594, 594, "Romania", RangeGtin},
+	{599, 599, "Hungary", RangeGtin},
+	{600, 601, "South Africa", RangeGtin},
+	{603, 603, "Ghana", RangeGtin},
+	{604, 604, "Senegal", RangeGtin},
+	{608, 608, "Bahrain", RangeGtin},
+	{609, 609, "Mauritius", RangeGtin},
+	{611, 611, "Morocco", RangeGtin},
+	{613, 613, "Algeria", RangeGtin},
+	{615, 615, "Nigeria", RangeGtin},
+	{616, 616, "Kenya", RangeGtin},
+	{618, 618, "Ivory Coast", RangeGtin},
+	{619, 619, "Tunisia", RangeGtin},
+	{620, 620, "Tanzania", RangeGtin},
+	{621, 621, "Syria", RangeGtin},
+	{622, 622, "Egypt", RangeGtin},
+	{623, 623, "Brunei", RangeGtin},
+	{624, 624, "Libya", RangeGtin},
+	{625, 625, "Jordan", RangeGtin},
+	{626, 626, "Iran", RangeGtin},
+	{627, 627, "Kuwait", RangeGtin},
+	{628, 628, "Saudi Arabia", RangeGtin},
+	{629, 629, "United Arab Emirates", RangeGtin},
+	{630, 630, "Qatar", RangeGtin},
+	{640, 649, "Finland", RangeGtin},
+	{690, 699, "China", RangeGtin},
+	{700, 709, "Norway", RangeGtin},
+	{729, 729, "Israel", RangeGtin},
+	{730, 739, "Sweden", RangeGtin},
+	{740, 740, "Guatemala", RangeGtin},
+	{741, 741, "El Salvador", RangeGtin},
+	{742, 742, "Honduras", RangeGtin},
+	{743, 743, "Nicaragua", RangeGtin},
+	{744, 744, "Costa Rica", RangeGtin},
+	{745, 745, "Panama", RangeGtin},
+	{746, 746, "Dominican Republic", RangeGtin},
+	{750, 750, "Mexico", RangeGtin},
+	{754, 755, "Canada", RangeGtin},
+	{759, 759, "Venezuela", RangeGtin},
+	{760, 769, "Switzerland and Liechtenstein", RangeGtin},
+	{770, 771, "Colombia", RangeGtin},
+	{773, 773, "Uruguay", RangeGtin},
+	{775, 775, "Peru", RangeGtin},
+	{777, 777, "Bolivia", RangeGtin},
+	{778, 779, "Argentina", RangeGtin},
+	{780, 780, "Chile", RangeGtin},
+	{784, 784, "Paraguay", RangeGtin},
+	{786, 786, "Ecuador", RangeGtin},
+	{789, 790, "Brazil", RangeGtin},
+	{800, 839, "Italy, San Marino and Vatican City", RangeGtin},
+	{840, 849, "Spain and Andorra", RangeGtin},
+	{850, 850, "Cuba", RangeGtin},
+	{858, 858, "Slovakia", RangeGtin},
+	{859, 859, "Czech Republic", RangeGtin},
+	{860, 860, "Serbia", RangeGtin},
+	{865, 865, "Mongolia", RangeGtin},
+	{867, 867, "North Korea", RangeGtin},
+	{868, 869, "Turkey", RangeGtin},
+	{870, 879, "Netherlands", RangeGtin},
+	{880, 880, "South Korea", RangeGtin},
+	{883, 883, "Myanmar", RangeGtin},
+	{884, 884, "Cambodia", RangeGtin},
+	{885, 885, "Thailand", RangeGtin},
+	{888, 888, "Singapore", RangeGtin},
+	{890, 890, "India", RangeGtin},
+	{893, 893, "Vietnam", RangeGtin},
+	{896, 896, "Pakistan", RangeGtin},
+	{899, 899, "Indonesia", RangeGtin},
+	{900, 919, "Austria", RangeGtin},
+	{930, 939, "Australia", RangeGtin},
+	{940, 949, "New Zealand", RangeGtin},
+	{950, 951, "", RangeGlobalOffice},
+	{955, 955, "Malaysia", RangeGtin},
+	{958, 958, "Macau", RangeGtin},
+	{960, 969, "", RangeGlobalOffice},
+	{977, 977, "", RangeIssn},
+	{978, 979, "", RangeIsbn},
+	{980, 980, "", RangeRefundReceipt},
+	{981, 984, "", RangeCoupon},
+	{990, 999, "", RangeCoupon},
+}
+
+func Describe(code string) (Description, error) {
+	if !IsValid(code) {
+		return Description{}, ErrorCodeInvalid
+	}
+
+	description := Description{Code: code}
+
+	var gtin string
+	switch len(code) {
+	case 8:
+		description.Format = FormatEan8
+		gtin = code
+	case 12:
+		description.Format = FormatUpcA
+		gtin = "0" + code
+	default:
+		description.Format = FormatEan13
+		gtin = code
+	}
+
+	description.Prefix = gtin[:3]
+	prefix, _ := strconv.Atoi(description.Prefix)
+
+	if description.Format == FormatEan8 && prefix < 100 {
+		description.Range = RangeInStore
+		return description, nil
+	}
+
+	description.Range = RangeUnassigned
+	for _, item := range prefixes {
+		if prefix >= item.From && prefix <= item.To {
+			description.Country = item.Country
+			description.Range = item.Range
+			break
+		}
+	}
+
+	return description, nil
+}
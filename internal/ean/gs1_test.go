@@ -0,0 +1,153 @@
+package ean
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestDescribe(t *testing.T) {
+	tests := []struct {
+		Name                string
+		Ean                 string
+		ExpectedDescription Description
+		ExpectedErr         error
+	}{
+		{
+			Name: "polish ean-13",
+			Ean:  "5901234123457",
+			ExpectedDescription: Description{
+				Code:    "5901234123457",
+				Format:  FormatEan13,
+				Prefix:  "590",
+				Country: "Poland",
+				Range:   RangeGtin,
+			},
+		},
+		{
+			Name: "german ean-13 at range start",
+			Ean:  "4006381333931",
+			ExpectedDescription: Description{
+				Code:    "4006381333931",
+				Format:  FormatEan13,
+				Prefix:  "400",
+				Country: "Germany",
+				Range:   RangeGtin,
+			},
+		},
+		{
+			Name: "german ean-13 at range end",
+			Ean:  "4401234567890",
+			ExpectedDescription: Description{
+				Code:    "4401234567890",
+				Format:  FormatEan13,
+				Prefix:  "440",
+				Country: "Germany",
+				Range:   RangeGtin,
+			},
+		},
+		{
+			Name: "in-store ean-13",
+			Ean:  "2112345012344",
+			ExpectedDescription: Description{
+				Code:   "2112345012344",
+				Format: FormatEan13,
+				Prefix: "211",
+				Range:  RangeInStore,
+			},
+		},
+		{
+			Name: "isbn",
+			Ean:  "9788328302341",
+			ExpectedDescription: Description{
+				Code:   "9788328302341",
+				Format: FormatEan13,
+				Prefix: "978",
+				Range:  RangeIsbn,
+			},
+		},
+		{
+			Name: "issn",
+			Ean:  "9770317847001",
+			ExpectedDescription: Description{
+				Code:   "9770317847001",
+				Format: FormatEan13,
+				Prefix: "977",
+				Range:  RangeIssn,
+			},
+		},
+		{
+			Name: "coupon",
+			Ean:  "9912345678901",
+			ExpectedDescription: Description{
+				Code:   "9912345678901",
+				Format: FormatEan13,
+				Prefix: "991",
+				Range:  RangeCoupon,
+			},
+		},
+		{
+			Name: "upc-a coupon",
+			Ean:  "512345678900",
+			ExpectedDescription: Description{
+				Code:   "512345678900",
+				Format: FormatUpcA,
+				Prefix: "051",
+				Range:  RangeCoupon,
+			},
+		},
+		{
+			Name: "upc-a",
+			Ean:  "036000291452",
+			ExpectedDescription: Description{
+				Code:    "036000291452",
+				Format:  FormatUpcA,
+				Prefix:  "003",
+				Country: "United States and Canada",
+				Range:   RangeGtin,
+			},
+		},
+		{
+			Name: "ean-8 velocity code",
+			Ean:  "01234565",
+			ExpectedDescription: Description{
+				Code:   "01234565",
+				Format: FormatEan8,
+				Prefix: "012",
+				Range:  RangeInStore,
+			},
+		},
+		{
+			Name: "ean-8",
+			Ean:  "59012341",
+			ExpectedDescription: Description{
+				Code:    "59012341",
+				Format:  FormatEan8,
+				Prefix:  "590",
+				Country: "Poland",
+				Range:   RangeGtin,
+			},
+		},
+		{
+			Name: "unassigned prefix",
+			Ean:  "1401234567890",
+			ExpectedDescription: Description{
+				Code:   "1401234567890",
+				Format: FormatEan13,
+				Prefix: "140",
+				Range:  RangeUnassigned,
+			},
+		},
+		{
+			Name:        "invalid",
+			Ean:         "1234",
+			ExpectedErr: ErrorCodeInvalid,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			description, err := Describe(test.Ean)
+			assert.Equal(t, test.ExpectedErr, err)
+			assert.Equal(t, test.ExpectedDescription, description)
+		})
+	}
+}
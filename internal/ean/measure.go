@@ -0,0 +1,199 @@
+package ean
+
+import (
+	"errors"
+	"math"
+	"strconv"
+	"strings"
+)
+
+type Measure string
+
+const (
+	MeasureWeight Measure = "WEIGHT"
+	MeasurePrice  Measure = "PRICE"
+)
+
+const (
+	templateArticle = 'I'
+	templateWeight  = 'W'
+	templatePrice   = 'P'
+	templateIgnored = 'X'
+	templateCheck   = 'C'
+)
+
+var (
+	ErrorTemplateInvalid  = errors.New("MEASURE_TEMPLATE_INVALID")
+	ErrorTemplateNotFound = errors.New("MEASURE_TEMPLATE_NOT_FOUND")
+)
+
+type Template struct {
+	Pattern  string
+	Measure  Measure
+	Decimals int
+}
+
+type VariableMeasure struct {
+	Code     string
+	BaseCode string
+	Article  string
+	Measure  Measure
+	Value    float64
+}
+
+var DefaultTemplates = []Template{
+	MustParseTemplate("02IIIIIXPPPPC"),
+	MustParseTemplate("20IIIIIWWWWWC"),
+	MustParseTemplate("21IIIIIWWWWWC"),
+	MustParseTemplate("22IIIIIWWWWWC"),
+	MustParseTemplate("23IIIIIWWWWWC"),
+	MustParseTemplate("24IIIIIWWWWWC"),
+	MustParseTemplate("25IIIIIPPPPPC"),
+	MustParseTemplate("26IIIIIPPPPPC"),
+	MustParseTemplate("27IIIIIPPPPPC"),
+	MustParseTemplate("28IIIIIPPPPPC"),
+	MustParseTemplate("29IIIIIPPPPPC"),
+}
+
+// ParseTemplate reads a 13 character pattern such as "20IIIIIWWWWWC" optionally
+// followed by ":<decimals>". Digits must match literally, I marks the article
+// number, W the weight in kilograms, P the price, X an ignored digit and C the
+// check digit. Weights default to 3 decimals and prices to 2.
+func ParseTemplate(value string) (Template, error) {
+	pattern, decimalsValue, hasDecimals := strings.Cut(strings.TrimSpace(value), ":")
+	if len(pattern) != 13 || pattern[12] != templateCheck {
+		return Template{}, ErrorTemplateInvalid
+	}
+
+	template := Template{Pattern: pattern}
+	hasArticle := false
+	for _, char := range pattern[:12] {
+		switch {
+		case char >= '0' && char <= '9', char == templateIgnored:
+		case char == templateArticle:
+			hasArticle = true
+		case char == templateWeight, char == templatePrice:
+			measure := MeasureWeight
+			if char == templatePrice {
+				measure = MeasurePrice
+			}
+			if template.Measure != "" && template.Measure != measure {
+				return Template{}, ErrorTemplateInvalid
+			}
+			template.Measure = measure
+		default:
+			return Template{}, ErrorTemplateInvalid
+		}
+	}
+
+	if !hasArticle || template.Measure == "" {
+		return Template{}, ErrorTemplateInvalid
+	}
+
+	switch {
+	case hasDecimals:
+		decimals, err := strconv.Atoi(decimalsValue)
+		if err != nil || decimals < 0 {
+			return Template{}, ErrorTemplateInvalid
+		}
+		template.Decimals = decimals
+	case template.Measure == MeasureWeight:
+		template.Decimals = 3
+	default:
+		template.Decimals = 2
+	}
+
+	return template, nil
+}
+
+func MustParseTemplate(value string) Template {
+	template, err := ParseTemplate(value)
+	if err != nil {
+		panic(err)
+	}
+	return template
+}
+
+func ParseTemplates(value string) ([]Template, error) {
+	templates := make([]Template, 0)
+	for _, item := range strings.Split(value, ",") {
+		if strings.TrimSpace(item) == "" {
+			continue
+		}
+		template, err := ParseTemplate(item)
+		if err != nil {
+			return nil, err
+		}
+		templates = append(templates, template)
+	}
+	return templates, nil
+}
+
+func DecodeVariableMeasure(code string, templates []Template) (VariableMeasure, error) {
+	if !IsValid(code) || len(code) == 8 {
+		return VariableMeasure{}, ErrorCodeInvalid
+	}
+
+	gtin := code
+	if len(code) == 12 {
+		gtin = "0" + code
+	}
+
+	for _, template := range templates {
+		if !template.matches(gtin) {
+			continue
+		}
+
+		article := make([]byte, 0)
+		value := make([]byte, 0)
+		base := []byte(gtin)
+		for i := 0; i < 12; i++ {
+			switch template.Pattern[i] {
+			case templateArticle:
+				article = append(article, gtin[i])
+			case templateWeight, templatePrice:
+				value = append(value, gtin[i])
+				base[i] = '0'
+			case templateIgnored:
+				base[i] = '0'
+			}
+		}
+		base[12] = CheckDigit(string(base[:12]))
+
+		number, _ := strconv.Atoi(string(value))
+		baseCode := string(base)
+		if len(code) == 12 {
+			baseCode = baseCode[1:]
+		}
+
+		return VariableMeasure{
+			Code:     code,
+			BaseCode: baseCode,
+			Article:  string(article),
+			Measure:  template.Measure,
+			Value:    float64(number) / math.Pow10(template.Decimals),
+		}, nil
+	}
+
+	return VariableMeasure{}, ErrorTemplateNotFound
+}
+
+func (t Template) matches(gtin string) bool {
+	for i := 0; i < 12; i++ {
+		char := t.Pattern[i]
+		if char >= '0' && char <= '9' && gtin[i] != char {
+			return false
+		}
+	}
+	return true
+}
+
+func CheckDigit(digits string) byte {
+	sum := 0
+	weight := 3
+	for i := len(digits) - 1; i >= 0; i-- {
+		sum += int(digits[i]-'0') * weight
+		weight = 4 - weight
+	}
+	return byte('0' + (10-sum%10)%10)
+}
@@ -0,0 +1,151 @@
+package ean
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestParseTemplate(t *testing.T) {
+	tests := []struct {
+		Name             string
+		Value            string
+		ExpectedTemplate Template
+		ExpectedErr      error
+	}{
+		{
+			Name:             "weight template",
+			Value:            "20IIIIIWWWWWC",
+			ExpectedTemplate: Template{Pattern: "20IIIIIWWWWWC", Measure: MeasureWeight, Decimals: 3},
+		},
+		{
+			Name:             "price template with decimals",
+			Value:            "29IIIIIPPPPPC:1",
+			ExpectedTemplate: Template{Pattern: "29IIIIIPPPPPC", Measure: MeasurePrice, Decimals: 1},
+		},
+		{
+			Name:        "too short",
+			Value:       "20IIIIIWWWWC",
+			ExpectedErr: ErrorTemplateInvalid,
+		},
+		{
+			Name:        "no check digit",
+			Value:       "20IIIIIWWWWWW",
+			ExpectedErr: ErrorTemplateInvalid,
+		},
+		{
+			Name:        "no article",
+			Value:       "2000000WWWWWC",
+			ExpectedErr: ErrorTemplateInvalid,
+		},
+		{
+			Name:        "no measure",
+			Value:       "20IIIIIIIIIIC",
+			ExpectedErr: ErrorTemplateInvalid,
+		},
+		{
+			Name:        "mixed measures",
+			Value:       "20IIIIIWWPPPC",
+			ExpectedErr: ErrorTemplateInvalid,
+		},
+		{
+			Name:        "unknown character",
+			Value:       "20IIIIIWWWWZC",
+			ExpectedErr: ErrorTemplateInvalid,
+		},
+		{
+			Name:        "invalid decimals",
+			Value:       "20IIIIIWWWWWC:x",
+			ExpectedErr: ErrorTemplateInvalid,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			template, err := ParseTemplate(test.Value)
+			assert.Equal(t, test.ExpectedErr, err)
+			assert.Equal(t, test.ExpectedTemplate, template)
+		})
+	}
+}
+
+func TestParseTemplates(t *testing.T) {
+	templates, err := ParseTemplates("20IIIIIWWWWWC, 25IIIIIPPPPPC,")
+	assert.NoError(t, err)
+	assert.Equal(t, []Template{
+		{Pattern: "20IIIIIWWWWWC", Measure: MeasureWeight, Decimals: 3},
+		{Pattern: "25IIIIIPPPPPC", Measure: MeasurePrice, Decimals: 2},
+	}, templates)
+
+	_, err = ParseTemplates("20IIIIIWWWWWC,invalid")
+	assert.Equal(t, ErrorTemplateInvalid, err)
+}
+
+func TestDecodeVariableMeasure(t *testing.T) {
+	tests := []struct {
+		Name            string
+		Code            string
+		ExpectedMeasure VariableMeasure
+		ExpectedErr     error
+	}{
+		{
+			Name: "weight",
+			Code: "2012345012509",
+			ExpectedMeasure: VariableMeasure{
+				Code:     "2012345012509",
+				BaseCode: "2012345000001",
+				Article:  "12345",
+				Measure:  MeasureWeight,
+				Value:    1.25,
+			},
+		},
+		{
+			Name: "price",
+			Code: "2512345003496",
+			ExpectedMeasure: VariableMeasure{
+				Code:     "2512345003496",
+				BaseCode: "2512345000006",
+				Article:  "12345",
+				Measure:  MeasurePrice,
+				Value:    3.49,
+			},
+		},
+		{
+			Name: "upc-a price",
+			Code: "212345012994",
+			ExpectedMeasure: VariableMeasure{
+				Code:     "212345012994",
+				BaseCode: "212345000007",
+				Article:  "12345",
+				Measure:  MeasurePrice,
+				Value:    12.99,
+			},
+		},
+		{
+			Name:        "regular gtin",
+			Code:        "5901234123457",
+			ExpectedErr: ErrorTemplateNotFound,
+		},
+		{
+			Name:        "ean-8",
+			Code:        "20123451",
+			ExpectedErr: ErrorCodeInvalid,
+		},
+		{
+			Name:        "invalid",
+			Code:        "20123",
+			ExpectedErr: ErrorCodeInvalid,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			measure, err := DecodeVariableMeasure(test.Code, DefaultTemplates)
+			assert.Equal(t, test.ExpectedErr, err)
+			assert.Equal(t, test.ExpectedMeasure, measure)
+		})
+	}
+}
+
+func TestCheckDigit(t *testing.T) {
+	assert.Equal(t, byte('7'), CheckDigit("590123412345"))
+	assert.Equal(t, byte('2'), CheckDigit("03600029145"))
+	assert.Equal(t, byte('5'), CheckDigit("0123456"))
+}
@@ -0,0 +1,86 @@
+package ean
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+)
+
+var digitGlyphs = [10][7]string{
+	{"01110", "10001", "10011", "10101", "11001", "10001", "01110"},
+	{"00100", "01100", "00100", "00100", "00100", "00100", "01110"},
+	{"01110", "10001", "00001", "00010", "00100", "01000", "11111"},
+	{"11111", "00010", "00100", "00010", "00001", "10001", "01110"},
+	{"00010", "00110", "01010", "10010", "11111", "00010", "00010"},
+	{"11111", "10000", "11110", "00001", "00001", "10001", "01110"},
+	{"00110", "01000", "10000", "11110", "10001", "10001", "01110"},
+	{"11111", "00001", "00010", "00100", "01000", "01000", "01000"},
+	{"01110", "10001", "10001", "01110", "10001", "10001", "01110"},
+	{"01110", "10001", "10001", "01111", "00001", "00010", "01100"},
+}
+
+func (s Symbol) barHeight(bar Bar) int {
+	if bar.Guard {
+		return s.BarHeight + guardHeight
+	}
+	return s.BarHeight
+}
+
+func (s Symbol) Svg(scale int) []byte {
+	var buffer bytes.Buffer
+	fmt.Fprintf(
+		&buffer,
+		`<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d">`,
+		s.Width*scale, s.Height()*scale, s.Width, s.Height(),
+	)
+	fmt.Fprintf(&buffer, `<rect width="%d" height="%d" fill="#fff"/>`, s.Width, s.Height())
+	buffer.WriteString(`<g fill="#000">`)
+	for _, bar := range s.Bars {
+		fmt.Fprintf(&buffer, `<rect x="%d" y="0" width="%d" height="%d"/>`, bar.X, bar.Width, s.barHeight(bar))
+	}
+	buffer.WriteString(`</g>`)
+	buffer.WriteString(`<g font-family="monospace" font-size="9" text-anchor="middle">`)
+	for _, digit := range s.Digits {
+		fmt.Fprintf(&buffer, `<text x="%.1f" y="%d">%c</text>`, float64(digit.X)+3.5, s.Height()-1, digit.Value)
+	}
+	buffer.WriteString(`</g></svg>`)
+	return buffer.Bytes()
+}
+
+func (s Symbol) Png(scale int) ([]byte, error) {
+	img := image.NewGray(image.Rect(0, 0, s.Width*scale, s.Height()*scale))
+	for i := range img.Pix {
+		img.Pix[i] = 0xff
+	}
+
+	fill := func(x, y, width, height int) {
+		for py := y * scale; py < (y+height)*scale; py++ {
+			for px := x * scale; px < (x+width)*scale; px++ {
+				img.SetGray(px, py, color.Gray{Y: 0})
+			}
+		}
+	}
+
+	for _, bar := range s.Bars {
+		fill(bar.X, 0, bar.Width, s.barHeight(bar))
+	}
+
+	for _, digit := range s.Digits {
+		glyph := digitGlyphs[digit.Value-'0']
+		for row, line := range glyph {
+			for column := range line {
+				if line[column] == '1' {
+					fill(digit.X+1+column, s.BarHeight+1+row, 1, 1)
+				}
+			}
+		}
+	}
+
+	var buffer bytes.Buffer
+	if err := png.Encode(&buffer, img); err != nil {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
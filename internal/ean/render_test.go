@@ -0,0 +1,44 @@
+package ean
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"image/png"
+	"strings"
+	"testing"
+)
+
+func TestSvg(t *testing.T) {
+	symbol, err := Encode("96385074")
+	assert.NoError(t, err)
+
+	svg := string(symbol.Svg(3))
+	assert.True(t, strings.HasPrefix(svg, `<svg xmlns="http://www.w3.org/2000/svg" width="243" height="192" viewBox="0 0 81 64">`))
+	assert.Equal(t, len(symbol.Bars)+1, strings.Count(svg, "<rect"))
+	assert.Equal(t, 8, strings.Count(svg, "<text"))
+}
+
+func TestPng(t *testing.T) {
+	symbol, err := Encode("5901234123457")
+	assert.NoError(t, err)
+
+	data, err := symbol.Png(2)
+	assert.NoError(t, err)
+
+	img, err := png.Decode(bytes.NewReader(data))
+	assert.NoError(t, err)
+	assert.Equal(t, 226, img.Bounds().Dx())
+	assert.Equal(t, 156, img.Bounds().Dy())
+
+	row := symbol.BarHeight
+	for x := 0; x < symbol.Width; x++ {
+		r, _, _, _ := img.At(x*2, row*2).RGBA()
+		bar := false
+		for _, item := range symbol.Bars {
+			if x >= item.X && x < item.X+item.Width && item.Guard {
+				bar = true
+			}
+		}
+		assert.Equal(t, bar, r == 0, "module %d", x)
+	}
+}
@@ -0,0 +1,200 @@
+package ean
+
+import "errors"
+
+var (
+	ErrorCheckDigitInvalid = errors.New("EAN_CHECK_DIGIT_INVALID")
+)
+
+var leftOddCodes = []string{
+	"0001101", "0011001", "0010011", "0111101", "0100011",
+	"0110001", "0101111", "0111011", "0110111", "0001011",
+}
+
+var leftEvenCodes = []string{
+	"0100111", "0110011", "0011011", "0100001", "0011101",
+	"0111001", "0000101", "0010001", "0001001", "0010111",
+}
+
+var rightCodes = []string{
+	"1110010", "1100110", "1101100", "1000010", "1011100",
+	"1001110", "1010000", "1000100", "1001000", "1110100",
+}
+
+var ean13Parities = []string{
+	"OOOOOO", "OOEOEE", "OOEEOE", "OOEEEO", "OEOOEE",
+	"OEEOOE", "OEEEOO", "OEOEOE", "OEOEEO", "OEEOEO",
+}
+
+const (
+	sideGuard   = "101"
+	centreGuard = "01010"
+	digitWidth  = 7
+	textHeight  = 9
+	guardHeight = 5
+)
+
+type Bar struct {
+	X     int
+	Width int
+	Guard bool
+}
+
+type Digit struct {
+	X     int
+	Value byte
+}
+
+// Symbol is the module layout of a barcode including its quiet zones. All
+// coordinates are expressed in modules, the narrowest bar width.
+type Symbol struct {
+	Code      string
+	Format    Format
+	Width     int
+	BarHeight int
+	Bars      []Bar
+	Digits    []Digit
+}
+
+func (s Symbol) Height() int {
+	return s.BarHeight + textHeight
+}
+
+func Encode(code string) (Symbol, error) {
+	if !IsValid(code) {
+		return Symbol{}, ErrorCodeInvalid
+	}
+	if CheckDigit(code[:len(code)-1]) != code[len(code)-1] {
+		return Symbol{}, ErrorCheckDigitInvalid
+	}
+
+	switch len(code) {
+	case 8:
+		return encodeEan8(code), nil
+	case 12:
+		return encodeUpcA(code), nil
+	default:
+		return encodeEan13(code), nil
+	}
+}
+
+type symbolBuilder struct {
+	modules []byte
+	guards  []bool
+}
+
+func (b *symbolBuilder) add(pattern string, guard bool) {
+	for i := range pattern {
+		b.modules = append(b.modules, pattern[i])
+		b.guards = append(b.guards, guard)
+	}
+}
+
+func (b *symbolBuilder) bars(offset int) []Bar {
+	bars := make([]Bar, 0)
+	for i := 0; i < len(b.modules); i++ {
+		if b.modules[i] != '1' {
+			continue
+		}
+		start := i
+		for i+1 < len(b.modules) && b.modules[i+1] == '1' && b.guards[i+1] == b.guards[start] {
+			i++
+		}
+		bars = append(bars, Bar{X: offset + start, Width: i - start + 1, Guard: b.guards[start]})
+	}
+	return bars
+}
+
+func slotDigits(digits string, x int) []Digit {
+	result := make([]Digit, len(digits))
+	for i := range digits {
+		result[i] = Digit{X: x + i*digitWidth, Value: digits[i]}
+	}
+	return result
+}
+
+func encodeEan13(code string) Symbol {
+	const quietLeft, quietRight = 11, 7
+	builder := symbolBuilder{}
+	builder.add(sideGuard, true)
+	parity := ean13Parities[code[0]-'0']
+	for i := 1; i <= 6; i++ {
+		if parity[i-1] == 'O' {
+			builder.add(leftOddCodes[code[i]-'0'], false)
+		} else {
+			builder.add(leftEvenCodes[code[i]-'0'], false)
+		}
+	}
+	builder.add(centreGuard, true)
+	for i := 7; i <= 12; i++ {
+		builder.add(rightCodes[code[i]-'0'], false)
+	}
+	builder.add(sideGuard, true)
+
+	digits := []Digit{{X: quietLeft - digitWidth - 1, Value: code[0]}}
+	digits = append(digits, slotDigits(code[1:7], quietLeft+3)...)
+	digits = append(digits, slotDigits(code[7:], quietLeft+3+42+5)...)
+
+	return Symbol{
+		Code:      code,
+		Format:    FormatEan13,
+		Width:     quietLeft + len(builder.modules) + quietRight,
+		BarHeight: 69,
+		Bars:      builder.bars(quietLeft),
+		Digits:    digits,
+	}
+}
+
+func encodeEan8(code string) Symbol {
+	const quietLeft, quietRight = 7, 7
+	builder := symbolBuilder{}
+	builder.add(sideGuard, true)
+	for i := 0; i < 4; i++ {
+		builder.add(leftOddCodes[code[i]-'0'], false)
+	}
+	builder.add(centreGuard, true)
+	for i := 4; i < 8; i++ {
+		builder.add(rightCodes[code[i]-'0'], false)
+	}
+	builder.add(sideGuard, true)
+
+	digits := slotDigits(code[:4], quietLeft+3)
+	digits = append(digits, slotDigits(code[4:], quietLeft+3+28+5)...)
+
+	return Symbol{
+		Code:      code,
+		Format:    FormatEan8,
+		Width:     quietLeft + len(builder.modules) + quietRight,
+		BarHeight: 55,
+		Bars:      builder.bars(quietLeft),
+		Digits:    digits,
+	}
+}
+
+func encodeUpcA(code string) Symbol {
+	const quietLeft, quietRight = 9, 9
+	builder := symbolBuilder{}
+	builder.add(sideGuard, true)
+	for i := 0; i < 6; i++ {
+		builder.add(leftOddCodes[code[i]-'0'], i == 0)
+	}
+	builder.add(centreGuard, true)
+	for i := 6; i < 12; i++ {
+		builder.add(rightCodes[code[i]-'0'], i == 11)
+	}
+	builder.add(sideGuard, true)
+
+	digits := []Digit{{X: quietLeft - digitWidth - 1, Value: code[0]}}
+	digits = append(digits, slotDigits(code[1:6], quietLeft+3+digitWidth)...)
+	digits = append(digits, slotDigits(code[6:11], quietLeft+3+42+5)...)
+	digits = append(digits, Digit{X: quietLeft + len(builder.modules) + 1, Value: code[11]})
+
+	return Symbol{
+		Code:      code,
+		Format:    FormatUpcA,
+		Width:     quietLeft + len(builder.modules) + quietRight,
+		BarHeight: 69,
+		Bars:      builder.bars(quietLeft),
+		Digits:    digits,
+	}
+}
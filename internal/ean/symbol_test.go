@@ -0,0 +1,99 @@
+package ean
+
+import (
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+func modules(symbol Symbol) string {
+	pattern := []byte(strings.Repeat("0", symbol.Width))
+	for _, bar := range symbol.Bars {
+		for x := bar.X; x < bar.X+bar.Width; x++ {
+			pattern[x] = '1'
+		}
+	}
+	return strings.Trim(string(pattern), "0")
+}
+
+func TestEncode(t *testing.T) {
+	tests := []struct {
+		Name            string
+		Code            string
+		ExpectedFormat  Format
+		ExpectedWidth   int
+		ExpectedModules string
+		ExpectedDigits  string
+		ExpectedErr     error
+	}{
+		{
+			Name:           "ean-8",
+			Code:           "96385074",
+			ExpectedFormat: FormatEan8,
+			ExpectedWidth:  81,
+			ExpectedModules: "101" + "0001011" + "0101111" + "0111101" + "0110111" + "01010" +
+				"1001110" + "1110010" + "1000100" + "1011100" + "101",
+			ExpectedDigits: "96385074",
+		},
+		{
+			Name:           "ean-13",
+			Code:           "5901234123457",
+			ExpectedFormat: FormatEan13,
+			ExpectedWidth:  113,
+			ExpectedModules: "101" + "0001011" + "0100111" + "0110011" + "0010011" + "0111101" + "0011101" + "01010" +
+				"1100110" + "1101100" + "1000010" + "1011100" + "1001110" + "1000100" + "101",
+			ExpectedDigits: "5901234123457",
+		},
+		{
+			Name:           "upc-a",
+			Code:           "036000291452",
+			ExpectedFormat: FormatUpcA,
+			ExpectedWidth:  113,
+			ExpectedModules: "101" + "0001101" + "0111101" + "0101111" + "0001101" + "0001101" + "0001101" + "01010" +
+				"1101100" + "1110100" + "1100110" + "1011100" + "1001110" + "1101100" + "101",
+			ExpectedDigits: "036000291452",
+		},
+		{
+			Name:        "invalid check digit",
+			Code:        "5901234123458",
+			ExpectedErr: ErrorCheckDigitInvalid,
+		},
+		{
+			Name:        "invalid code",
+			Code:        "59012",
+			ExpectedErr: ErrorCodeInvalid,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			symbol, err := Encode(test.Code)
+			assert.Equal(t, test.ExpectedErr, err)
+			if test.ExpectedErr != nil {
+				return
+			}
+			assert.Equal(t, test.ExpectedFormat, symbol.Format)
+			assert.Equal(t, test.ExpectedWidth, symbol.Width)
+			assert.Equal(t, test.ExpectedModules, modules(symbol))
+
+			digits := ""
+			for _, digit := range symbol.Digits {
+				digits += string(digit.Value)
+				assert.True(t, digit.X >= 0 && digit.X+digitWidth <= symbol.Width)
+			}
+			assert.Equal(t, test.ExpectedDigits, digits)
+		})
+	}
+}
+
+func TestEncodeGuards(t *testing.T) {
+	symbol, err := Encode("5901234123457")
+	assert.NoError(t, err)
+
+	guards := 0
+	for _, bar := range symbol.Bars {
+		if bar.Guard {
+			guards++
+		}
+	}
+	assert.Equal(t, 6, guards)
+}
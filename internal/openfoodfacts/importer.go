@@ -0,0 +1,258 @@
+package openfoodfacts
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"github.com/Kobietka/product-service/internal/products"
+	"github.com/Kobietka/product-service/internal/types"
+	v1 "github.com/Kobietka/product-service/pkg/api/v1"
+	"github.com/Kobietka/product-service/pkg/array"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+const defaultChunkSize = 500
+
+var (
+	ErrorRecordInvalid = errors.New("OFF_RECORD_INVALID")
+)
+
+type Summary struct {
+	Imported int
+	Rejected int
+	Skipped  int
+	Warned   int
+	LastLine int
+}
+
+type pendingProduct struct {
+	record  Record
+	product v1.Product
+	warned  bool
+}
+
+// chunk holds the products to write and the rejected records of a chunk, the
+// rejects are only written once the chunk is committed so that a chunk
+// imported again after an interruption does not reject its records twice.
+type chunk struct {
+	products []pendingProduct
+	rejects  [][]string
+}
+
+// Importer writes the products of a dump in chunks of chunkSize records, one
+// unit of work per chunk, and saves the progress after every chunk.
+type Importer struct {
+	store        products.Store
+	rejects      *csv.Writer
+	progressPath string
+	chunkSize    int
+	rules        products.Rules
+	dictionaries *types.Dictionaries
+}
+
+func NewImporter(store products.Store, rejects io.Writer, progressPath string) Importer {
+	return Importer{
+		store:        store,
+		rejects:      csv.NewWriter(rejects),
+		progressPath: progressPath,
+		chunkSize:    defaultChunkSize,
+		rules:        products.DefaultRules,
+	}
+}
+
+func (i Importer) WithChunkSize(size int) Importer {
+	i.chunkSize = max(size, 1)
+	return i
+}
+
+func (i Importer) WithRules(rules products.Rules) Importer {
+	i.rules = rules
+	return i
+}
+
+func (i Importer) WithDictionaries(dictionaries types.Dictionaries) Importer {
+	i.dictionaries = &dictionaries
+	return i
+}
+
+func (i Importer) Import(ctx context.Context, reader Reader) (Summary, error) {
+	resumeAfter, err := i.loadProgress()
+	if err != nil {
+		return Summary{}, err
+	}
+
+	summary := Summary{LastLine: resumeAfter}
+	defer i.rejects.Flush()
+
+	pending := chunk{products: make([]pendingProduct, 0, i.chunkSize)}
+	line, read := resumeAfter, 0
+	for {
+		if err := ctx.Err(); err != nil {
+			return summary, errors.Join(err, i.saveProgress(summary.LastLine))
+		}
+
+		record, readErr := reader.Read()
+		if errors.Is(readErr, io.EOF) {
+			break
+		}
+		if readErr != nil && record.Line == 0 {
+			return summary, errors.Join(readErr, i.saveProgress(summary.LastLine))
+		}
+		if record.Line <= resumeAfter {
+			summary.Skipped++
+			continue
+		}
+
+		if readErr != nil {
+			pending.reject(record, ErrorRecordInvalid)
+		} else if err := i.prepare(ctx, &pending, record); err != nil {
+			return summary, errors.Join(err, i.saveProgress(summary.LastLine))
+		}
+
+		line, read = record.Line, read+1
+		if read%i.chunkSize == 0 {
+			if err := i.commitChunk(ctx, &summary, &pending, line); err != nil {
+				return summary, err
+			}
+		}
+	}
+
+	return summary, i.commitChunk(ctx, &summary, &pending, line)
+}
+
+// prepare adds the product of the record to the chunk, or rejects the record.
+func (i Importer) prepare(ctx context.Context, pending *chunk, record Record) error {
+	product, err := ToProduct(record)
+	if err != nil {
+		pending.reject(record, err)
+		return nil
+	}
+
+	warned := false
+	if err := i.validate(ctx, product); err != nil {
+		if !products.IsWarning(err) {
+			pending.reject(record, err)
+			return nil
+		}
+		warned = true
+	}
+
+	pending.products = append(pending.products, pendingProduct{record: record, product: product, warned: warned})
+	return nil
+}
+
+// commitChunk writes the chunk, then its rejects and saves line as the
+// progress. When the chunk cannot be written the progress of the previous
+// chunk is saved and the rejects of this one are dropped.
+func (i Importer) commitChunk(ctx context.Context, summary *Summary, pending *chunk, line int) error {
+	if err := i.writeChunk(ctx, summary, pending); err != nil {
+		return errors.Join(err, i.saveProgress(summary.LastLine))
+	}
+
+	summary.Rejected += len(pending.rejects)
+	if err := i.rejects.WriteAll(pending.rejects); err != nil {
+		return err
+	}
+	pending.products, pending.rejects, summary.LastLine = pending.products[:0], nil, line
+	return i.saveProgress(summary.LastLine)
+}
+
+// writeChunk upserts the products of the chunk in one unit of work. Products
+// that already exist are replaced, so the chunk an interruption stopped can be
+// imported again. A chunk the store rejects as invalid is written again
+// product by product to reject only the records at fault.
+func (i Importer) writeChunk(ctx context.Context, summary *Summary, pending *chunk) error {
+	if len(pending.products) == 0 {
+		return nil
+	}
+
+	err := i.store.UpsertProducts(ctx, array.MapArray(pending.products, func(product pendingProduct) v1.Product {
+		return product.product
+	}))
+	if errors.Is(err, v1.ErrorInvalidData) {
+		for _, product := range pending.products {
+			if err := i.writeProduct(ctx, summary, pending, product); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	for _, product := range pending.products {
+		count(summary, product)
+	}
+	return nil
+}
+
+func (i Importer) writeProduct(ctx context.Context, summary *Summary, pending *chunk, product pendingProduct) error {
+	_, err := i.store.UpsertProduct(ctx, product.product)
+	if errors.Is(err, v1.ErrorInvalidData) {
+		pending.reject(product.record, err)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	count(summary, product)
+	return nil
+}
+
+func count(summary *Summary, pending pendingProduct) {
+	summary.Imported++
+	if pending.warned {
+		summary.Warned++
+	}
+}
+
+// validate runs the rules with the dictionaries only, the components of a
+// dump are not looked up.
+func (i Importer) validate(ctx context.Context, product v1.Product) error {
+	violations, err := i.rules.Check(ctx, product, products.Env{Dictionaries: i.dictionaries})
+	if err != nil || len(violations) == 0 {
+		return err
+	}
+	return violations
+}
+
+func (c *chunk) reject(record Record, reason error) {
+	c.rejects = append(c.rejects, []string{strconv.Itoa(record.Line), record.Code, reason.Error()})
+}
+
+func (i Importer) loadProgress() (int, error) {
+	if i.progressPath == "" {
+		return 0, nil
+	}
+
+	data, err := os.ReadFile(i.progressPath)
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	return strconv.Atoi(strings.TrimSpace(string(data)))
+}
+
+func (i Importer) saveProgress(line int) error {
+	i.rejects.Flush()
+	if err := i.rejects.Error(); err != nil {
+		return err
+	}
+	if i.progressPath == "" {
+		return nil
+	}
+
+	temporaryPath := i.progressPath + ".tmp"
+	if err := os.WriteFile(temporaryPath, []byte(strconv.Itoa(line)+"\n"), 0o644); err != nil {
+		return err
+	}
+	return os.Rename(temporaryPath, i.progressPath)
+}
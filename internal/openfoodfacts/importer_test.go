@@ -0,0 +1,263 @@
+package openfoodfacts
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"github.com/Kobietka/product-service/internal/products"
+	"github.com/Kobietka/product-service/internal/types"
+	v1 "github.com/Kobietka/product-service/pkg/api/v1"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+type fakeStore struct {
+	products map[string]v1.Product
+	updated  []string
+	chunks   [][]string
+	err      error
+}
+
+func (s *fakeStore) GetProduct(_ context.Context, ean string) (v1.Product, error) {
+	product, ok := s.products[ean]
+	if !ok {
+		return v1.Product{}, v1.ErrorDataNotFound
+	}
+	return product, nil
+}
+
+func (s *fakeStore) GetProducts(_ context.Context, eans []string) (map[string]v1.Product, error) {
+	products := make(map[string]v1.Product)
+	for _, ean := range eans {
+		if product, ok := s.products[ean]; ok {
+			products[ean] = product
+		}
+	}
+	return products, nil
+}
+
+func (s *fakeStore) SearchProducts(context.Context, string, int8) ([]v1.Product, error) {
+	return nil, nil
+}
+
+func (s *fakeStore) CreateProduct(_ context.Context, product v1.Product) error {
+	if product.Packaging.Unit == "mg" {
+		return v1.ErrorInvalidData
+	}
+	s.products[product.Ean] = product
+	return nil
+}
+
+func (s *fakeStore) UpdateProduct(_ context.Context, product v1.Product) error {
+	s.products[product.Ean] = product
+	s.updated = append(s.updated, product.Ean)
+	return nil
+}
+
+func (s *fakeStore) UpsertProduct(ctx context.Context, product v1.Product) (bool, error) {
+	if _, ok := s.products[product.Ean]; ok {
+		return false, s.UpdateProduct(ctx, product)
+	}
+	return true, s.CreateProduct(ctx, product)
+}
+
+func (s *fakeStore) UpsertProducts(ctx context.Context, products []v1.Product) error {
+	if s.err != nil {
+		return s.err
+	}
+	for _, product := range products {
+		if product.Packaging.Unit == "mg" {
+			return v1.ErrorInvalidData
+		}
+	}
+	chunk := make([]string, 0, len(products))
+	for _, product := range products {
+		if _, err := s.UpsertProduct(ctx, product); err != nil {
+			return err
+		}
+		chunk = append(chunk, product.Ean)
+	}
+	s.chunks = append(s.chunks, chunk)
+	return nil
+}
+
+func (s *fakeStore) DeleteProduct(context.Context, string) error {
+	return nil
+}
+
+const importDump = `{"code":"5901234123457","product_name":"Milk","quantity":"1 l","nutriments":{"energy-kcal_100g":64,"fat_100g":3.2,"carbohydrates_100g":4.7,"proteins_100g":3.4}}
+{"code":"4006381333931","product_name":"","quantity":"500 g","nutriments":{"fat_100g":1,"carbohydrates_100g":2,"proteins_100g":3}}
+{"code":"12345678","product_name":"Butter","quantity":"200 g","nutriments":{"carbohydrates_100g":1,"proteins_100g":1}}
+{"code":"87654321","product_name":"Salt","quantity":"500 mg","nutriments":{"fat_100g":0,"carbohydrates_100g":0,"proteins_100g":0}}
+broken
+{"code":"11111111","product_name":"Water","quantity":"1.5 l","nutriments":{"fat_100g":0,"carbohydrates_100g":0,"proteins_100g":0}}
+`
+
+func TestImport(t *testing.T) {
+	store := &fakeStore{
+		products: map[string]v1.Product{"11111111": {Ean: "11111111", Name: "Old water"}},
+	}
+	var rejects bytes.Buffer
+	progressPath := filepath.Join(t.TempDir(), "dump.progress")
+
+	reader, err := NewReader(strings.NewReader(importDump), FormatJsonl)
+	assert.NoError(t, err)
+
+	summary, err := NewImporter(store, &rejects, progressPath).Import(context.Background(), reader)
+	assert.NoError(t, err)
+	assert.Equal(t, Summary{Imported: 2, Rejected: 4, LastLine: 6}, summary)
+	assert.Equal(t, "Milk", store.products["5901234123457"].Name)
+	assert.Equal(t, "Water", store.products["11111111"].Name)
+	assert.Equal(t, []string{"11111111"}, store.updated)
+	assert.Equal(t, "2,4006381333931,\"PRODUCT_NAME_MISSING, NUTRITION_ENERGY_MISMATCH\"\n"+
+		"3,12345678,NUTRIENT_FAT_MISSING\n"+
+		"5,,OFF_RECORD_INVALID\n"+
+		"4,87654321,PROVIDED_DATA_INVALID\n", rejects.String(), "the store rejects a record when its chunk is written")
+
+	progress, err := os.ReadFile(progressPath)
+	assert.NoError(t, err)
+	assert.Equal(t, "6\n", string(progress))
+}
+
+func TestImportWritesChunks(t *testing.T) {
+	store := &fakeStore{products: map[string]v1.Product{}}
+	var rejects bytes.Buffer
+
+	reader, err := NewReader(strings.NewReader(importDump), FormatJsonl)
+	assert.NoError(t, err)
+
+	summary, err := NewImporter(store, &rejects, "").WithChunkSize(2).Import(context.Background(), reader)
+	assert.NoError(t, err)
+	assert.Equal(t, Summary{Imported: 2, Rejected: 4, LastLine: 6}, summary)
+	assert.Equal(t, [][]string{{"5901234123457"}, {"11111111"}}, store.chunks, "the chunk of the invalid record is written product by product")
+	assert.Contains(t, rejects.String(), "4,87654321,PROVIDED_DATA_INVALID\n")
+}
+
+func TestImportResumes(t *testing.T) {
+	store := &fakeStore{products: map[string]v1.Product{}}
+	var rejects bytes.Buffer
+	progressPath := filepath.Join(t.TempDir(), "dump.progress")
+	assert.NoError(t, os.WriteFile(progressPath, []byte("5\n"), 0o644))
+
+	reader, err := NewReader(strings.NewReader(importDump), FormatJsonl)
+	assert.NoError(t, err)
+
+	summary, err := NewImporter(store, &rejects, progressPath).Import(context.Background(), reader)
+	assert.NoError(t, err)
+	assert.Equal(t, Summary{Imported: 1, Skipped: 5, LastLine: 6}, summary)
+	assert.Equal(t, []string{"11111111"}, mapKeys(store.products))
+	assert.Empty(t, rejects.String())
+}
+
+func TestImportRejectsOnceAfterInterruptedChunk(t *testing.T) {
+	store := &fakeStore{products: map[string]v1.Product{}, err: errors.New("error")}
+	var rejects bytes.Buffer
+	progressPath := filepath.Join(t.TempDir(), "dump.progress")
+
+	reader, err := NewReader(strings.NewReader(importDump), FormatJsonl)
+	assert.NoError(t, err)
+
+	_, err = NewImporter(store, &rejects, progressPath).Import(context.Background(), reader)
+	assert.Error(t, err)
+	assert.Empty(t, rejects.String(), "the rejects of a chunk that was not written are dropped")
+	progress, err := os.ReadFile(progressPath)
+	assert.NoError(t, err)
+	assert.Equal(t, "0\n", string(progress))
+
+	store.err = nil
+	reader, err = NewReader(strings.NewReader(importDump), FormatJsonl)
+	assert.NoError(t, err)
+
+	summary, err := NewImporter(store, &rejects, progressPath).Import(context.Background(), reader)
+	assert.NoError(t, err)
+	assert.Equal(t, Summary{Imported: 2, Rejected: 4, LastLine: 6}, summary)
+	assert.Equal(t, "2,4006381333931,\"PRODUCT_NAME_MISSING, NUTRITION_ENERGY_MISMATCH\"\n"+
+		"3,12345678,NUTRIENT_FAT_MISSING\n"+
+		"5,,OFF_RECORD_INVALID\n"+
+		"4,87654321,PROVIDED_DATA_INVALID\n", rejects.String())
+}
+
+func TestImportEnergyCheck(t *testing.T) {
+	const dump = `{"code":"5901234123457","product_name":"Milk","quantity":"1 l","nutriments":{"energy-kcal_100g":640,"fat_100g":3.2,"carbohydrates_100g":4.7,"proteins_100g":3.4}}
+`
+
+	tests := []struct {
+		Name            string
+		Config          products.RuleConfig
+		ExpectedSummary Summary
+		ExpectedRejects string
+	}{
+		{
+			Name:            "rejects by default",
+			Config:          products.RuleConfig{},
+			ExpectedSummary: Summary{Rejected: 1, LastLine: 1},
+			ExpectedRejects: "1,5901234123457,NUTRITION_ENERGY_MISMATCH\n",
+		},
+		{
+			Name:            "imports with a warning",
+			Config:          products.RuleConfig{Severity: products.SeverityWarning},
+			ExpectedSummary: Summary{Imported: 1, Warned: 1, LastLine: 1},
+			ExpectedRejects: "",
+		},
+		{
+			Name:            "disabled",
+			Config:          products.RuleConfig{Params: products.Params{"tolerance": 0.0}},
+			ExpectedSummary: Summary{Imported: 1, LastLine: 1},
+			ExpectedRejects: "",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			store := &fakeStore{products: map[string]v1.Product{}}
+			var rejects bytes.Buffer
+			progressPath := filepath.Join(t.TempDir(), "dump.progress")
+
+			rules, err := products.DefaultRules.Configure(map[string]products.RuleConfig{products.RuleEnergy: test.Config})
+			assert.NoError(t, err)
+
+			reader, err := NewReader(strings.NewReader(dump), FormatJsonl)
+			assert.NoError(t, err)
+
+			summary, err := NewImporter(store, &rejects, progressPath).
+				WithRules(rules).
+				Import(context.Background(), reader)
+			assert.NoError(t, err)
+			assert.Equal(t, test.ExpectedSummary, summary)
+			assert.Equal(t, test.ExpectedRejects, rejects.String())
+		})
+	}
+}
+
+func TestImportDictionaries(t *testing.T) {
+	const dump = `{"code":"5901234123457","product_name":"Milk","quantity":"1 l","nutriments":{"energy-kcal_100g":64,"fat_100g":3.2,"carbohydrates_100g":4.7,"proteins_100g":3.4}}
+`
+	dictionaries := types.Dictionaries{
+		Units:         map[string]types.Dimension{"g": types.DimensionMass, "ml": types.DimensionVolume},
+		NutrientTypes: map[string]bool{"FAT": true, "CARBOHYDRATES": true, "PROTEIN": true},
+	}
+
+	store := &fakeStore{products: map[string]v1.Product{}}
+	var rejects bytes.Buffer
+
+	reader, err := NewReader(strings.NewReader(dump), FormatJsonl)
+	assert.NoError(t, err)
+
+	summary, err := NewImporter(store, &rejects, "").
+		WithDictionaries(dictionaries).
+		Import(context.Background(), reader)
+	assert.NoError(t, err)
+	assert.Equal(t, Summary{Rejected: 1, LastLine: 1}, summary)
+	assert.Equal(t, "1,5901234123457,QUANTITY_UNIT_UNKNOWN\n", rejects.String())
+}
+
+func mapKeys(products map[string]v1.Product) []string {
+	keys := make([]string, 0, len(products))
+	for key := range products {
+		keys = append(keys, key)
+	}
+	return keys
+}
@@ -0,0 +1,168 @@
+package openfoodfacts
+
+import (
+	"errors"
+	v1 "github.com/Kobietka/product-service/pkg/api/v1"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const per100gSuffix = "_100g"
+
+var (
+	ErrorCodeMissing = errors.New("OFF_CODE_MISSING")
+)
+
+type nutrimentMapping struct {
+	Key    string
+	Type   string
+	Unit   string
+	Factor float64
+}
+
+var nutrientMappings = []nutrimentMapping{
+	{Key: "fat", Type: "FAT", Unit: "g", Factor: 1},
+	{Key: "saturated-fat", Type: "SATURATED_FAT", Unit: "g", Factor: 1},
+	{Key: "monounsaturated-fat", Type: "MONO_UNSATURATED_FAT", Unit: "g", Factor: 1},
+	{Key: "polyunsaturated-fat", Type: "POLY_UNSATURATED_FAT", Unit: "g", Factor: 1},
+	{Key: "trans-fat", Type: "TRANS_FAT", Unit: "g", Factor: 1},
+	{Key: "carbohydrates", Type: "CARBOHYDRATES", Unit: "g", Factor: 1},
+	{Key: "sugars", Type: "SUGAR", Unit: "g", Factor: 1},
+	{Key: "fiber", Type: "FIBER", Unit: "g", Factor: 1},
+	{Key: "proteins", Type: "PROTEIN", Unit: "g", Factor: 1},
+	{Key: "salt", Type: "SALT", Unit: "g", Factor: 1},
+	{Key: "polyols", Type: "POLYOLS", Unit: "g", Factor: 1},
+	// alcohol is given in % vol, 0.789 g of ethanol per ml
+	{Key: "alcohol", Type: "ALCOHOL", Unit: "g", Factor: 0.789},
+}
+
+// Open Food Facts stores every *_100g value in grams, vitamins and minerals
+// are converted to the unit they are usually labelled with.
+var vitaminMappings = []nutrimentMapping{
+	{Key: "vitamin-a", Type: "VITAMIN_A", Unit: "µg", Factor: 1e6},
+	{Key: "vitamin-b1", Type: "VITAMIN_B1", Unit: "mg", Factor: 1e3},
+	{Key: "vitamin-b2", Type: "VITAMIN_B2", Unit: "mg", Factor: 1e3},
+	{Key: "vitamin-pp", Type: "VITAMIN_B3", Unit: "mg", Factor: 1e3},
+	{Key: "pantothenic-acid", Type: "VITAMIN_B5", Unit: "mg", Factor: 1e3},
+	{Key: "vitamin-b6", Type: "VITAMIN_B6", Unit: "mg", Factor: 1e3},
+	{Key: "biotin", Type: "VITAMIN_B7", Unit: "µg", Factor: 1e6},
+	{Key: "vitamin-b9", Type: "VITAMIN_B9", Unit: "µg", Factor: 1e6},
+	{Key: "vitamin-b12", Type: "VITAMIN_B12", Unit: "µg", Factor: 1e6},
+	{Key: "vitamin-c", Type: "VITAMIN_C", Unit: "mg", Factor: 1e3},
+	{Key: "vitamin-d", Type: "VITAMIN_D", Unit: "µg", Factor: 1e6},
+	{Key: "vitamin-e", Type: "VITAMIN_E", Unit: "mg", Factor: 1e3},
+}
+
+var mineralMappings = []nutrimentMapping{
+	{Key: "magnesium", Type: "MAGNESIUM", Unit: "mg", Factor: 1e3},
+	{Key: "sodium", Type: "SODIUM", Unit: "mg", Factor: 1e3},
+	{Key: "potassium", Type: "POTASSIUM", Unit: "mg", Factor: 1e3},
+	{Key: "calcium", Type: "CALCIUM", Unit: "mg", Factor: 1e3},
+	{Key: "phosphorus", Type: "PHOSPHORUS", Unit: "mg", Factor: 1e3},
+	{Key: "zinc", Type: "ZINC", Unit: "mg", Factor: 1e3},
+	{Key: "iron", Type: "IRON", Unit: "mg", Factor: 1e3},
+	{Key: "copper", Type: "COPPER", Unit: "mg", Factor: 1e3},
+	{Key: "manganese", Type: "MANGANESE", Unit: "mg", Factor: 1e3},
+	{Key: "iodine", Type: "IODINE", Unit: "µg", Factor: 1e6},
+}
+
+var quantityUnits = map[string]struct {
+	Unit   string
+	Factor float64
+}{
+	"kg": {Unit: "kg", Factor: 1},
+	"g":  {Unit: "g", Factor: 1},
+	"gr": {Unit: "g", Factor: 1},
+	"mg": {Unit: "mg", Factor: 1},
+	"l":  {Unit: "l", Factor: 1},
+	"dl": {Unit: "ml", Factor: 100},
+	"cl": {Unit: "ml", Factor: 10},
+	"ml": {Unit: "ml", Factor: 1},
+}
+
+var quantityRegex = regexp.MustCompile(`(?i)^\s*(?:(\d+)\s*[x×]\s*)?(\d+(?:[.,]\d+)?)\s*(kg|gr|g|mg|l|dl|cl|ml)\b`)
+
+func ToProduct(record Record) (v1.Product, error) {
+	code := strings.TrimSpace(record.Code)
+	if code == "" {
+		return v1.Product{}, ErrorCodeMissing
+	}
+
+	packaging := parsePackaging(record)
+	per := v1.Quantity{Value: 100, Unit: "g"}
+	if packaging.Unit == "l" || packaging.Unit == "ml" {
+		per.Unit = "ml"
+	}
+
+	return v1.Product{
+		Ean:       code,
+		Name:      record.Name,
+		Packaging: packaging,
+		Nutrition: v1.Nutrition{
+			Per:       per,
+			Kcal:      energyKcal(record.Nutriments),
+			Nutrients: mapNutriments(record.Nutriments, nutrientMappings, newNutrient),
+			Vitamins:  mapNutriments(record.Nutriments, vitaminMappings, newVitamin),
+			Minerals:  mapNutriments(record.Nutriments, mineralMappings, newMineral),
+		},
+	}, nil
+}
+
+func parsePackaging(record Record) v1.Quantity {
+	if match := quantityRegex.FindStringSubmatch(record.Quantity); match != nil {
+		count := 1.0
+		if match[1] != "" {
+			count, _ = strconv.ParseFloat(match[1], 64)
+		}
+		value, _ := strconv.ParseFloat(strings.ReplaceAll(match[2], ",", "."), 64)
+		unit := quantityUnits[strings.ToLower(match[3])]
+		return v1.Quantity{Value: float32(count * value * unit.Factor), Unit: unit.Unit}
+	}
+
+	if unit, ok := quantityUnits[strings.ToLower(record.ProductQuantityUnit)]; ok && record.ProductQuantity > 0 {
+		return v1.Quantity{Value: float32(record.ProductQuantity * unit.Factor), Unit: unit.Unit}
+	}
+
+	return v1.Quantity{}
+}
+
+func energyKcal(nutriments map[string]float64) int32 {
+	if kcal, ok := nutriments["energy-kcal"+per100gSuffix]; ok {
+		return int32(math.Round(kcal))
+	}
+	if kj, ok := nutriments["energy"+per100gSuffix]; ok {
+		return int32(math.Round(kj / 4.184))
+	}
+	return 0
+}
+
+func mapNutriments[T any](
+	nutriments map[string]float64,
+	mappings []nutrimentMapping,
+	create func(t string, quantity v1.Quantity) T,
+) []T {
+	result := make([]T, 0)
+	for _, mapping := range mappings {
+		value, ok := nutriments[mapping.Key+per100gSuffix]
+		if !ok {
+			continue
+		}
+		rounded := math.Round(value*mapping.Factor*1000) / 1000
+		result = append(result, create(mapping.Type, v1.Quantity{Value: float32(rounded), Unit: mapping.Unit}))
+	}
+	return result
+}
+
+func newNutrient(t string, quantity v1.Quantity) v1.Nutrient {
+	return v1.Nutrient{T: t, Quantity: quantity}
+}
+
+func newVitamin(t string, quantity v1.Quantity) v1.Vitamin {
+	return v1.Vitamin{T: t, Quantity: quantity}
+}
+
+func newMineral(t string, quantity v1.Quantity) v1.Mineral {
+	return v1.Mineral{T: t, Quantity: quantity}
+}
@@ -0,0 +1,106 @@
+package openfoodfacts
+
+import (
+	v1 "github.com/Kobietka/product-service/pkg/api/v1"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestToProduct(t *testing.T) {
+	tests := []struct {
+		Name            string
+		Record          Record
+		ExpectedProduct v1.Product
+		ExpectedErr     error
+	}{
+		{
+			Name: "maps nutriments, vitamins and minerals",
+			Record: Record{
+				Code:     "5901234123457",
+				Name:     "Milk",
+				Quantity: "1,5 l",
+				Nutriments: map[string]float64{
+					"energy-kcal_100g":   64.4,
+					"fat_100g":           3.2,
+					"carbohydrates_100g": 4.7,
+					"proteins_100g":      3.4,
+					"vitamin-d_100g":     0.0000012,
+					"calcium_100g":       0.12,
+				},
+			},
+			ExpectedProduct: v1.Product{
+				Ean:       "5901234123457",
+				Name:      "Milk",
+				Packaging: v1.Quantity{Value: 1.5, Unit: "l"},
+				Nutrition: v1.Nutrition{
+					Per:  v1.Quantity{Value: 100, Unit: "ml"},
+					Kcal: 64,
+					Nutrients: []v1.Nutrient{
+						{T: "FAT", Quantity: v1.Quantity{Value: 3.2, Unit: "g"}},
+						{T: "CARBOHYDRATES", Quantity: v1.Quantity{Value: 4.7, Unit: "g"}},
+						{T: "PROTEIN", Quantity: v1.Quantity{Value: 3.4, Unit: "g"}},
+					},
+					Vitamins: []v1.Vitamin{
+						{T: "VITAMIN_D", Quantity: v1.Quantity{Value: 1.2, Unit: "µg"}},
+					},
+					Minerals: []v1.Mineral{
+						{T: "CALCIUM", Quantity: v1.Quantity{Value: 120, Unit: "mg"}},
+					},
+				},
+			},
+		},
+		{
+			Name: "multipack quantity and energy in kj",
+			Record: Record{
+				Code:       "4006381333931",
+				Name:       "Cola",
+				Quantity:   "6 x 33 cl",
+				Nutriments: map[string]float64{"energy_100g": 180},
+			},
+			ExpectedProduct: v1.Product{
+				Ean:       "4006381333931",
+				Name:      "Cola",
+				Packaging: v1.Quantity{Value: 1980, Unit: "ml"},
+				Nutrition: v1.Nutrition{
+					Per:       v1.Quantity{Value: 100, Unit: "ml"},
+					Kcal:      43,
+					Nutrients: []v1.Nutrient{},
+					Vitamins:  []v1.Vitamin{},
+					Minerals:  []v1.Mineral{},
+				},
+			},
+		},
+		{
+			Name: "product quantity fallback",
+			Record: Record{
+				Code:                "4006381333931",
+				Name:                "Bread",
+				ProductQuantity:     500,
+				ProductQuantityUnit: "g",
+			},
+			ExpectedProduct: v1.Product{
+				Ean:       "4006381333931",
+				Name:      "Bread",
+				Packaging: v1.Quantity{Value: 500, Unit: "g"},
+				Nutrition: v1.Nutrition{
+					Per:       v1.Quantity{Value: 100, Unit: "g"},
+					Nutrients: []v1.Nutrient{},
+					Vitamins:  []v1.Vitamin{},
+					Minerals:  []v1.Mineral{},
+				},
+			},
+		},
+		{
+			Name:        "code missing",
+			Record:      Record{Name: "Bread"},
+			ExpectedErr: ErrorCodeMissing,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			product, err := ToProduct(test.Record)
+			assert.Equal(t, test.ExpectedErr, err)
+			assert.Equal(t, test.ExpectedProduct, product)
+		})
+	}
+}
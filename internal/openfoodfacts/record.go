@@ -0,0 +1,212 @@
+package openfoodfacts
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+)
+
+const maxLineBytes = 64 << 20
+
+var (
+	ErrorFormatUnknown = errors.New("FORMAT_UNKNOWN")
+)
+
+type Format string
+
+const (
+	FormatJsonl Format = "jsonl"
+	FormatCsv   Format = "csv"
+)
+
+type Record struct {
+	Line                int
+	Code                string
+	Name                string
+	Quantity            string
+	ProductQuantity     float64
+	ProductQuantityUnit string
+	Nutriments          map[string]float64
+}
+
+type Reader interface {
+	Read() (Record, error)
+}
+
+func NewReader(r io.Reader, format Format) (Reader, error) {
+	switch format {
+	case FormatJsonl:
+		return &jsonlRecordReader{reader: bufio.NewReaderSize(r, 1<<20)}, nil
+	case FormatCsv:
+		csvReader := csv.NewReader(r)
+		csvReader.Comma = '\t'
+		csvReader.LazyQuotes = true
+		csvReader.FieldsPerRecord = -1
+		csvReader.ReuseRecord = true
+		return &csvRecordReader{reader: csvReader}, nil
+	}
+	return nil, ErrorFormatUnknown
+}
+
+func FormatFromPath(path string) (Format, error) {
+	path = strings.TrimSuffix(strings.ToLower(path), ".gz")
+	switch {
+	case strings.HasSuffix(path, ".jsonl"), strings.HasSuffix(path, ".json"):
+		return FormatJsonl, nil
+	case strings.HasSuffix(path, ".csv"), strings.HasSuffix(path, ".tsv"):
+		return FormatCsv, nil
+	}
+	return "", ErrorFormatUnknown
+}
+
+type jsonlRecordReader struct {
+	reader *bufio.Reader
+	line   int
+}
+
+type jsonlRecord struct {
+	Code                string                     `json:"code"`
+	ProductName         string                     `json:"product_name"`
+	GenericName         string                     `json:"generic_name"`
+	Quantity            string                     `json:"quantity"`
+	ProductQuantity     json.RawMessage            `json:"product_quantity"`
+	ProductQuantityUnit string                     `json:"product_quantity_unit"`
+	Nutriments          map[string]json.RawMessage `json:"nutriments"`
+}
+
+func (r *jsonlRecordReader) Read() (Record, error) {
+	for {
+		line, err := r.reader.ReadSlice('\n')
+		if errors.Is(err, bufio.ErrBufferFull) {
+			line, err = r.readLong(line)
+		}
+		if len(line) == 0 && err != nil {
+			return Record{}, err
+		}
+		r.line++
+
+		if strings.TrimSpace(string(line)) == "" {
+			continue
+		}
+
+		var raw jsonlRecord
+		if jsonErr := json.Unmarshal(line, &raw); jsonErr != nil {
+			return Record{Line: r.line}, jsonErr
+		}
+
+		record := Record{
+			Line:                r.line,
+			Code:                raw.Code,
+			Name:                firstNonBlank(raw.ProductName, raw.GenericName),
+			Quantity:            raw.Quantity,
+			ProductQuantity:     parseRawNumber(raw.ProductQuantity),
+			ProductQuantityUnit: raw.ProductQuantityUnit,
+			Nutriments:          make(map[string]float64),
+		}
+		for key, value := range raw.Nutriments {
+			if !strings.HasSuffix(key, per100gSuffix) {
+				continue
+			}
+			if number, ok := parseNumber(string(value)); ok {
+				record.Nutriments[key] = number
+			}
+		}
+		return record, nil
+	}
+}
+
+func (r *jsonlRecordReader) readLong(start []byte) ([]byte, error) {
+	line := append([]byte{}, start...)
+	for len(line) < maxLineBytes {
+		chunk, err := r.reader.ReadSlice('\n')
+		line = append(line, chunk...)
+		if !errors.Is(err, bufio.ErrBufferFull) {
+			return line, err
+		}
+	}
+	return line, bufio.ErrTooLong
+}
+
+type csvRecordReader struct {
+	reader  *csv.Reader
+	columns map[string]int
+	line    int
+}
+
+func (r *csvRecordReader) Read() (Record, error) {
+	if r.columns == nil {
+		header, err := r.reader.Read()
+		if err != nil {
+			return Record{}, err
+		}
+		r.line++
+		r.columns = make(map[string]int, len(header))
+		for index, name := range header {
+			r.columns[name] = index
+		}
+	}
+
+	fields, err := r.reader.Read()
+	if err != nil {
+		return Record{}, err
+	}
+	r.line++
+
+	field := func(name string) string {
+		index, ok := r.columns[name]
+		if !ok || index >= len(fields) {
+			return ""
+		}
+		return fields[index]
+	}
+
+	productQuantity, _ := parseNumber(field("product_quantity"))
+	record := Record{
+		Line:                r.line,
+		Code:                field("code"),
+		Name:                firstNonBlank(field("product_name"), field("generic_name")),
+		Quantity:            field("quantity"),
+		ProductQuantity:     productQuantity,
+		ProductQuantityUnit: field("product_quantity_unit"),
+		Nutriments:          make(map[string]float64),
+	}
+	for name, index := range r.columns {
+		if !strings.HasSuffix(name, per100gSuffix) || index >= len(fields) {
+			continue
+		}
+		if number, ok := parseNumber(fields[index]); ok {
+			record.Nutriments[name] = number
+		}
+	}
+	return record, nil
+}
+
+func parseRawNumber(value json.RawMessage) float64 {
+	number, _ := parseNumber(string(value))
+	return number
+}
+
+func parseNumber(value string) (float64, bool) {
+	value = strings.Trim(strings.TrimSpace(value), `"`)
+	if value == "" {
+		return 0, false
+	}
+	number, err := strconv.ParseFloat(strings.ReplaceAll(value, ",", "."), 64)
+	if err != nil {
+		return 0, false
+	}
+	return number, true
+}
+
+func firstNonBlank(values ...string) string {
+	for _, value := range values {
+		if strings.TrimSpace(value) != "" {
+			return strings.TrimSpace(value)
+		}
+	}
+	return ""
+}
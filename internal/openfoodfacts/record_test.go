@@ -0,0 +1,96 @@
+package openfoodfacts
+
+import (
+	"github.com/stretchr/testify/assert"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestJsonlReader(t *testing.T) {
+	dump := `{"code":"5901234123457","product_name":"Milk","quantity":"1 l","nutriments":{"fat_100g":3.2,"proteins_100g":"3,4","energy-kcal":64,"energy-kcal_100g":64}}
+
+{"code":"4006381333931","generic_name":"Bread","product_quantity":"500","product_quantity_unit":"g","nutriments":{}}
+not json
+`
+	reader, err := NewReader(strings.NewReader(dump), FormatJsonl)
+	assert.NoError(t, err)
+
+	record, err := reader.Read()
+	assert.NoError(t, err)
+	assert.Equal(t, Record{
+		Line:     1,
+		Code:     "5901234123457",
+		Name:     "Milk",
+		Quantity: "1 l",
+		Nutriments: map[string]float64{
+			"fat_100g":         3.2,
+			"proteins_100g":    3.4,
+			"energy-kcal_100g": 64,
+		},
+	}, record)
+
+	record, err = reader.Read()
+	assert.NoError(t, err)
+	assert.Equal(t, Record{
+		Line:                3,
+		Code:                "4006381333931",
+		Name:                "Bread",
+		ProductQuantity:     500,
+		ProductQuantityUnit: "g",
+		Nutriments:          map[string]float64{},
+	}, record)
+
+	record, err = reader.Read()
+	assert.Error(t, err)
+	assert.Equal(t, 4, record.Line)
+
+	_, err = reader.Read()
+	assert.Equal(t, io.EOF, err)
+}
+
+func TestCsvReader(t *testing.T) {
+	dump := "code\tproduct_name\tquantity\tfat_100g\tsugars_100g\n" +
+		"5901234123457\tMilk\t1 l\t3.2\t\n" +
+		"4006381333931\tBread\t500 g\t1\t2.5\n"
+	reader, err := NewReader(strings.NewReader(dump), FormatCsv)
+	assert.NoError(t, err)
+
+	record, err := reader.Read()
+	assert.NoError(t, err)
+	assert.Equal(t, Record{
+		Line:       2,
+		Code:       "5901234123457",
+		Name:       "Milk",
+		Quantity:   "1 l",
+		Nutriments: map[string]float64{"fat_100g": 3.2},
+	}, record)
+
+	record, err = reader.Read()
+	assert.NoError(t, err)
+	assert.Equal(t, 3, record.Line)
+	assert.Equal(t, map[string]float64{"fat_100g": 1, "sugars_100g": 2.5}, record.Nutriments)
+
+	_, err = reader.Read()
+	assert.Equal(t, io.EOF, err)
+}
+
+func TestFormatFromPath(t *testing.T) {
+	tests := []struct {
+		Path           string
+		ExpectedFormat Format
+		ExpectedErr    error
+	}{
+		{Path: "products.jsonl", ExpectedFormat: FormatJsonl},
+		{Path: "openfoodfacts-products.jsonl.gz", ExpectedFormat: FormatJsonl},
+		{Path: "en.openfoodfacts.org.products.csv", ExpectedFormat: FormatCsv},
+		{Path: "products.xml", ExpectedErr: ErrorFormatUnknown},
+	}
+	for _, test := range tests {
+		t.Run(test.Path, func(t *testing.T) {
+			format, err := FormatFromPath(test.Path)
+			assert.Equal(t, test.ExpectedErr, err)
+			assert.Equal(t, test.ExpectedFormat, format)
+		})
+	}
+}
@@ -0,0 +1,76 @@
+package products
+
+import (
+	"context"
+	"errors"
+	"github.com/Kobietka/product-service/internal/ean"
+	v1 "github.com/Kobietka/product-service/pkg/api/v1"
+	"github.com/labstack/echo/v4"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+func describeBarcode(code string) *v1.Barcode {
+	description, err := ean.Describe(code)
+	if err != nil {
+		return nil
+	}
+
+	return &v1.Barcode{
+		Code:    description.Code,
+		Format:  string(description.Format),
+		Prefix:  description.Prefix,
+		Country: description.Country,
+		Range:   string(description.Range),
+	}
+}
+
+func withBarcode(product v1.Product) v1.Product {
+	product.Barcode = describeBarcode(product.Ean)
+	return product
+}
+
+func toApplicationIdentifier(element ean.Element) v1.ApplicationIdentifier {
+	identifier := v1.ApplicationIdentifier{
+		Ai:     element.Ai,
+		Title:  element.Title,
+		Value:  element.Value,
+		Number: element.Number,
+	}
+	if element.Date != nil {
+		identifier.Date = element.Date.Format(time.DateOnly)
+	}
+	return identifier
+}
+
+func (s Server) findByGtin(ctx context.Context, gtin string) (*v1.Product, error) {
+	for _, code := range ean.GtinCandidates(gtin) {
+		product, err := s.getProduct(ctx, code)
+		if err != nil {
+			if errors.Is(err, v1.ErrorDataNotFound) {
+				continue
+			}
+			return nil, err
+		}
+		product = withServings(withBarcode(product))
+		return &product, nil
+	}
+	return nil, nil
+}
+
+func readImage(c echo.Context) (io.ReadCloser, error) {
+	request := c.Request()
+	request.Body = http.MaxBytesReader(c.Response(), request.Body, maxImageBytes)
+
+	if strings.HasPrefix(request.Header.Get(echo.HeaderContentType), echo.MIMEMultipartForm) {
+		file, err := c.FormFile(imageFormField)
+		if err != nil {
+			return nil, err
+		}
+		return file.Open()
+	}
+
+	return request.Body, nil
+}
@@ -0,0 +1,243 @@
+package products
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	v1 "github.com/Kobietka/product-service/pkg/api/v1"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	DefaultCacheSize        = 10000
+	DefaultCacheTtl         = time.Minute
+	DefaultNotFoundCacheTtl = 10 * time.Second
+)
+
+type CacheStats struct {
+	Hits   uint64 `json:"hits"`
+	Misses uint64 `json:"misses"`
+	Size   int    `json:"size"`
+}
+
+type cachedProduct struct {
+	ean     string
+	product v1.Product
+	found   bool
+	expires time.Time
+}
+
+// CachedStore keeps the products read by GetProduct and GetProducts in a least
+// recently used cache of size entries. Products expire after ttl, EANs that
+// were not found after the shorter notFoundTtl. Writes through the store
+// invalidate the product, writes of other instances have to be passed to
+// Invalidate. Searches are not cached.
+type CachedStore struct {
+	store       Store
+	size        int
+	ttl         time.Duration
+	notFoundTtl time.Duration
+	now         func() time.Time
+	mutex       sync.Mutex
+	entries     map[string]*list.Element
+	order       *list.List
+	generation  uint64
+	hits        atomic.Uint64
+	misses      atomic.Uint64
+}
+
+func NewCachedStore(store Store, size int, ttl time.Duration) *CachedStore {
+	return &CachedStore{
+		store:       store,
+		size:        max(size, 1),
+		ttl:         ttl,
+		notFoundTtl: DefaultNotFoundCacheTtl,
+		now:         time.Now,
+		entries:     make(map[string]*list.Element),
+		order:       list.New(),
+	}
+}
+
+// WithNotFoundTtl sets how long a missing product is remembered, zero turns
+// off caching of missing products.
+func (s *CachedStore) WithNotFoundTtl(ttl time.Duration) *CachedStore {
+	s.notFoundTtl = ttl
+	return s
+}
+
+func (s *CachedStore) GetProduct(ctx context.Context, ean string) (v1.Product, error) {
+	s.mutex.Lock()
+	entry, ok := s.cached(ean)
+	generation := s.generation
+	s.mutex.Unlock()
+	if ok {
+		s.hits.Add(1)
+		if !entry.found {
+			return v1.Product{}, v1.ErrorDataNotFound
+		}
+		return entry.product.Clone(), nil
+	}
+	s.misses.Add(1)
+
+	product, err := s.store.GetProduct(ctx, ean)
+	if err != nil && !errors.Is(err, v1.ErrorDataNotFound) {
+		return v1.Product{}, err
+	}
+
+	s.mutex.Lock()
+	s.put(ean, product, err == nil, generation)
+	s.mutex.Unlock()
+	return product, err
+}
+
+// GetProducts answers the cached EANs from the cache and reads the others
+// from the store in one call.
+func (s *CachedStore) GetProducts(ctx context.Context, eans []string) (map[string]v1.Product, error) {
+	products := make(map[string]v1.Product, len(eans))
+	missing := make([]string, 0)
+
+	s.mutex.Lock()
+	for _, ean := range eans {
+		entry, ok := s.cached(ean)
+		if !ok {
+			missing = append(missing, ean)
+		} else if entry.found {
+			products[ean] = entry.product.Clone()
+		}
+	}
+	generation := s.generation
+	s.mutex.Unlock()
+	s.hits.Add(uint64(len(eans) - len(missing)))
+	s.misses.Add(uint64(len(missing)))
+	if len(missing) == 0 {
+		return products, nil
+	}
+
+	loaded, err := s.store.GetProducts(ctx, missing)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	for _, ean := range missing {
+		product, found := loaded[ean]
+		if found {
+			products[ean] = product
+		}
+		s.put(ean, product, found, generation)
+	}
+	return products, nil
+}
+
+func (s *CachedStore) SearchProducts(ctx context.Context, query string, limit int8) ([]v1.Product, error) {
+	return s.store.SearchProducts(ctx, query, limit)
+}
+
+func (s *CachedStore) CreateProduct(ctx context.Context, product v1.Product) error {
+	defer s.Invalidate(product.Ean)
+	return s.store.CreateProduct(ctx, product)
+}
+
+func (s *CachedStore) UpdateProduct(ctx context.Context, product v1.Product) error {
+	defer s.Invalidate(product.Ean)
+	return s.store.UpdateProduct(ctx, product)
+}
+
+func (s *CachedStore) UpsertProduct(ctx context.Context, product v1.Product) (bool, error) {
+	defer s.Invalidate(product.Ean)
+	return s.store.UpsertProduct(ctx, product)
+}
+
+func (s *CachedStore) UpsertProducts(ctx context.Context, products []v1.Product) error {
+	defer s.invalidateProducts(products)
+	return s.store.UpsertProducts(ctx, products)
+}
+
+func (s *CachedStore) DeleteProduct(ctx context.Context, ean string) error {
+	defer s.Invalidate(ean)
+	return s.store.DeleteProduct(ctx, ean)
+}
+
+// Invalidate drops the product from the cache. Reads of any product that are
+// in flight are not cached, they may have started before the write.
+func (s *CachedStore) Invalidate(ean string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.generation++
+	if element, ok := s.entries[ean]; ok {
+		s.remove(element)
+	}
+}
+
+func (s *CachedStore) invalidateProducts(products []v1.Product) {
+	for _, product := range products {
+		s.Invalidate(product.Ean)
+	}
+}
+
+// Purge drops every product, for when notifications of writes may have been
+// missed.
+func (s *CachedStore) Purge() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.generation++
+	s.entries = make(map[string]*list.Element)
+	s.order.Init()
+}
+
+func (s *CachedStore) Stats() CacheStats {
+	s.mutex.Lock()
+	size := s.order.Len()
+	s.mutex.Unlock()
+
+	return CacheStats{Hits: s.hits.Load(), Misses: s.misses.Load(), Size: size}
+}
+
+// cached returns the entry of the EAN unless it expired, the mutex has to be
+// held.
+func (s *CachedStore) cached(ean string) (*cachedProduct, bool) {
+	element, ok := s.entries[ean]
+	if !ok {
+		return nil, false
+	}
+	entry := element.Value.(*cachedProduct)
+	if !s.now().Before(entry.expires) {
+		s.remove(element)
+		return nil, false
+	}
+	s.order.MoveToFront(element)
+	return entry, true
+}
+
+// put caches what was read at generation, unless a product was invalidated
+// since and it may be stale already. The mutex has to be held.
+func (s *CachedStore) put(ean string, product v1.Product, found bool, generation uint64) {
+	ttl := s.ttl
+	if !found {
+		ttl = s.notFoundTtl
+	}
+	if ttl <= 0 || s.generation != generation {
+		return
+	}
+	s.add(&cachedProduct{ean: ean, product: product.Clone(), found: found, expires: s.now().Add(ttl)})
+}
+
+func (s *CachedStore) add(entry *cachedProduct) {
+	if element, ok := s.entries[entry.ean]; ok {
+		s.remove(element)
+	}
+	s.entries[entry.ean] = s.order.PushFront(entry)
+	for s.order.Len() > s.size {
+		s.remove(s.order.Back())
+	}
+}
+
+func (s *CachedStore) remove(element *list.Element) {
+	delete(s.entries, element.Value.(*cachedProduct).ean)
+	s.order.Remove(element)
+}
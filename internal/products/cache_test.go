@@ -0,0 +1,163 @@
+package products
+
+import (
+	"context"
+	"errors"
+	v1 "github.com/Kobietka/product-service/pkg/api/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"testing"
+	"time"
+)
+
+func TestCachedStoreGetProduct(t *testing.T) {
+	product := v1.Product{
+		Ean:        "12345670",
+		Name:       "Sticker",
+		Packaging:  v1.Quantity{Value: 1, Unit: "pcs"},
+		Components: []v1.Component{{Ean: "87654325", Count: 1}},
+	}
+	store := new(MockStore)
+	store.On("GetProduct", mock.Anything, "12345670").Return(product, nil)
+	store.On("GetProduct", mock.Anything, "87654325").Return(v1.Product{}, v1.ErrorDataNotFound)
+	store.On("GetProduct", mock.Anything, "11111116").Return(v1.Product{}, errors.New("err")).Once()
+	store.On("GetProduct", mock.Anything, "11111116").Return(product, nil)
+
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	cached := NewCachedStore(store, 10, time.Minute).WithNotFoundTtl(time.Second)
+	cached.now = func() time.Time { return now }
+
+	got, err := cached.GetProduct(context.Background(), "12345670")
+	assert.NoError(t, err)
+	assert.Equal(t, product, got)
+
+	got, err = cached.GetProduct(context.Background(), "12345670")
+	assert.NoError(t, err)
+	got.Components[0].Count = 5
+	got, err = cached.GetProduct(context.Background(), "12345670")
+	assert.NoError(t, err)
+	assert.Equal(t, product, got, "cached products are copies")
+	store.AssertNumberOfCalls(t, "GetProduct", 1)
+
+	_, err = cached.GetProduct(context.Background(), "87654325")
+	assert.ErrorIs(t, err, v1.ErrorDataNotFound)
+	_, err = cached.GetProduct(context.Background(), "87654325")
+	assert.ErrorIs(t, err, v1.ErrorDataNotFound)
+	store.AssertNumberOfCalls(t, "GetProduct", 2)
+
+	now = now.Add(time.Second)
+	_, err = cached.GetProduct(context.Background(), "87654325")
+	assert.ErrorIs(t, err, v1.ErrorDataNotFound)
+	_, err = cached.GetProduct(context.Background(), "12345670")
+	assert.NoError(t, err)
+	store.AssertNumberOfCalls(t, "GetProduct", 3)
+
+	_, err = cached.GetProduct(context.Background(), "11111116")
+	assert.Error(t, err)
+	_, err = cached.GetProduct(context.Background(), "11111116")
+	assert.NoError(t, err)
+	store.AssertNumberOfCalls(t, "GetProduct", 5)
+
+	assert.Equal(t, CacheStats{Hits: 4, Misses: 5, Size: 3}, cached.Stats())
+}
+
+func TestCachedStoreEvictsLeastRecentlyUsed(t *testing.T) {
+	store := new(MockStore)
+	store.On("GetProduct", mock.Anything, mock.Anything).Return(v1.Product{Name: "Product"}, nil)
+	cached := NewCachedStore(store, 2, time.Minute)
+	ctx := context.Background()
+
+	for _, ean := range []string{"11111116", "22222222", "11111116", "33333338", "11111116"} {
+		_, err := cached.GetProduct(ctx, ean)
+		assert.NoError(t, err)
+	}
+	store.AssertNumberOfCalls(t, "GetProduct", 3)
+
+	_, err := cached.GetProduct(ctx, "22222222")
+	assert.NoError(t, err)
+	store.AssertNumberOfCalls(t, "GetProduct", 4)
+	assert.Equal(t, 2, cached.Stats().Size)
+}
+
+func TestCachedStoreInvalidates(t *testing.T) {
+	product := v1.Product{Ean: "12345670", Name: "Sticker", Packaging: v1.Quantity{Value: 1, Unit: "pcs"}}
+	store := new(MockStore)
+	store.On("GetProduct", mock.Anything, product.Ean).Return(product, nil)
+	store.On("CreateProduct", mock.Anything, product).Return(nil)
+	store.On("UpdateProduct", mock.Anything, product).Return(errors.New("err"))
+	store.On("UpsertProduct", mock.Anything, product).Return(false, nil)
+	store.On("DeleteProduct", mock.Anything, product.Ean).Return(nil)
+	cached := NewCachedStore(store, 10, time.Minute)
+	ctx := context.Background()
+
+	writes := []func() error{
+		func() error { return cached.CreateProduct(ctx, product) },
+		func() error { return cached.UpdateProduct(ctx, product) },
+		func() error {
+			_, err := cached.UpsertProduct(ctx, product)
+			return err
+		},
+		func() error { return cached.DeleteProduct(ctx, product.Ean) },
+		func() error {
+			cached.Invalidate(product.Ean)
+			return nil
+		},
+		func() error {
+			cached.Purge()
+			return nil
+		},
+	}
+	_, err := cached.GetProduct(ctx, product.Ean)
+	assert.NoError(t, err)
+	for i, write := range writes {
+		_ = write()
+		_, err = cached.GetProduct(ctx, product.Ean)
+		assert.NoError(t, err)
+		_, err = cached.GetProduct(ctx, product.Ean)
+		assert.NoError(t, err)
+		store.AssertNumberOfCalls(t, "GetProduct", i+2)
+	}
+}
+
+func TestCachedStoreDoesNotCacheReadsRacingWrites(t *testing.T) {
+	product := v1.Product{Ean: "12345670", Name: "Sticker", Packaging: v1.Quantity{Value: 1, Unit: "pcs"}}
+	store := new(MockStore)
+	cached := NewCachedStore(store, 10, time.Minute)
+	store.On("GetProduct", mock.Anything, product.Ean).Return(product, nil).Run(func(mock.Arguments) {
+		cached.Invalidate(product.Ean)
+	})
+	ctx := context.Background()
+
+	_, err := cached.GetProduct(ctx, product.Ean)
+	assert.NoError(t, err)
+	_, err = cached.GetProduct(ctx, product.Ean)
+	assert.NoError(t, err)
+	store.AssertNumberOfCalls(t, "GetProduct", 2)
+}
+
+func TestCachedStoreGetProducts(t *testing.T) {
+	sticker := v1.Product{Ean: "12345670", Name: "Sticker", Packaging: v1.Quantity{Value: 1, Unit: "pcs"}}
+	ham := v1.Product{Ean: "2012345000001", Name: "Ham", Packaging: v1.Quantity{Value: 1, Unit: "kg"}}
+	store := new(MockStore)
+	store.On("GetProduct", mock.Anything, sticker.Ean).Return(sticker, nil)
+	store.On("GetProducts", mock.Anything, []string{ham.Ean, "87654325"}).Return(map[string]v1.Product{ham.Ean: ham}, nil).Once()
+	cached := NewCachedStore(store, 10, time.Minute)
+	ctx := context.Background()
+
+	_, err := cached.GetProduct(ctx, sticker.Ean)
+	assert.NoError(t, err)
+
+	products, err := cached.GetProducts(ctx, []string{sticker.Ean, ham.Ean, "87654325"})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]v1.Product{sticker.Ean: sticker, ham.Ean: ham}, products)
+
+	products, err = cached.GetProducts(ctx, []string{sticker.Ean, ham.Ean, "87654325"})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]v1.Product{sticker.Ean: sticker, ham.Ean: ham}, products)
+	_, err = cached.GetProduct(ctx, "87654325")
+	assert.ErrorIs(t, err, v1.ErrorDataNotFound)
+
+	store.AssertNumberOfCalls(t, "GetProduct", 1)
+	store.AssertNumberOfCalls(t, "GetProducts", 1)
+	assert.Equal(t, CacheStats{Hits: 5, Misses: 3, Size: 3}, cached.Stats())
+}
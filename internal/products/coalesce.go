@@ -0,0 +1,158 @@
+package products
+
+import (
+	"container/list"
+	"context"
+	v1 "github.com/Kobietka/product-service/pkg/api/v1"
+	"golang.org/x/sync/singleflight"
+	"maps"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultCoalesceTimeout = 10 * time.Second
+	maxCoalesceKeys        = 1000
+)
+
+// CoalesceStats counts the calls of GetProduct, the reads of the store they
+// shared and the callers that went away before the read finished. Keys are
+// kept for the most recently used EANs only, the totals count every EAN.
+type CoalesceStats struct {
+	Calls     uint64              `json:"calls"`
+	Loads     uint64              `json:"loads"`
+	Cancelled uint64              `json:"cancelled"`
+	Keys      map[string]KeyStats `json:"keys"`
+}
+
+type KeyStats struct {
+	Calls     uint64 `json:"calls"`
+	Loads     uint64 `json:"loads"`
+	Cancelled uint64 `json:"cancelled"`
+}
+
+// CoalescingStore shares one GetProduct of the store between the concurrent
+// calls for the same EAN. The shared read does not end with the context of
+// the caller that started it, it runs for up to timeout so that the callers
+// still waiting get the product. A caller whose context ends returns at once.
+type CoalescingStore struct {
+	store   Store
+	timeout time.Duration
+	group   singleflight.Group
+	mutex   sync.Mutex
+	stats   CoalesceStats
+	keys    map[string]*list.Element
+	order   *list.List
+}
+
+func NewCoalescingStore(store Store) *CoalescingStore {
+	return &CoalescingStore{
+		store:   store,
+		timeout: DefaultCoalesceTimeout,
+		stats:   CoalesceStats{Keys: make(map[string]KeyStats)},
+		keys:    make(map[string]*list.Element),
+		order:   list.New(),
+	}
+}
+
+func (s *CoalescingStore) WithTimeout(timeout time.Duration) *CoalescingStore {
+	s.timeout = timeout
+	return s
+}
+
+func (s *CoalescingStore) GetProduct(ctx context.Context, ean string) (v1.Product, error) {
+	results := s.group.DoChan(ean, func() (any, error) {
+		s.count(ean, func(stats *KeyStats) { stats.Loads++ })
+
+		loadCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), s.timeout)
+		defer cancel()
+		return s.store.GetProduct(loadCtx, ean)
+	})
+	s.count(ean, func(stats *KeyStats) { stats.Calls++ })
+
+	select {
+	case result := <-results:
+		if result.Err != nil {
+			return v1.Product{}, result.Err
+		}
+		return result.Val.(v1.Product).Clone(), nil
+	case <-ctx.Done():
+		s.count(ean, func(stats *KeyStats) { stats.Cancelled++ })
+		return v1.Product{}, ctx.Err()
+	}
+}
+
+func (s *CoalescingStore) GetProducts(ctx context.Context, eans []string) (map[string]v1.Product, error) {
+	return s.store.GetProducts(ctx, eans)
+}
+
+func (s *CoalescingStore) SearchProducts(ctx context.Context, query string, limit int8) ([]v1.Product, error) {
+	return s.store.SearchProducts(ctx, query, limit)
+}
+
+// The writes forget the read in flight, calls after a write read the
+// product again.
+
+func (s *CoalescingStore) CreateProduct(ctx context.Context, product v1.Product) error {
+	defer s.group.Forget(product.Ean)
+	return s.store.CreateProduct(ctx, product)
+}
+
+func (s *CoalescingStore) UpdateProduct(ctx context.Context, product v1.Product) error {
+	defer s.group.Forget(product.Ean)
+	return s.store.UpdateProduct(ctx, product)
+}
+
+func (s *CoalescingStore) UpsertProduct(ctx context.Context, product v1.Product) (bool, error) {
+	defer s.group.Forget(product.Ean)
+	return s.store.UpsertProduct(ctx, product)
+}
+
+func (s *CoalescingStore) UpsertProducts(ctx context.Context, products []v1.Product) error {
+	defer func() {
+		for _, product := range products {
+			s.group.Forget(product.Ean)
+		}
+	}()
+	return s.store.UpsertProducts(ctx, products)
+}
+
+func (s *CoalescingStore) DeleteProduct(ctx context.Context, ean string) error {
+	defer s.group.Forget(ean)
+	return s.store.DeleteProduct(ctx, ean)
+}
+
+func (s *CoalescingStore) Stats() CoalesceStats {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	stats := s.stats
+	stats.Keys = maps.Clone(s.stats.Keys)
+	return stats
+}
+
+// count applies change to the totals and to the stats of the EAN. The stats of
+// the least recently used EAN are dropped to make room for a new one.
+func (s *CoalescingStore) count(ean string, change func(stats *KeyStats)) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	total := KeyStats{Calls: s.stats.Calls, Loads: s.stats.Loads, Cancelled: s.stats.Cancelled}
+	change(&total)
+	s.stats.Calls, s.stats.Loads, s.stats.Cancelled = total.Calls, total.Loads, total.Cancelled
+
+	if element, ok := s.keys[ean]; ok {
+		s.order.MoveToFront(element)
+	} else {
+		s.keys[ean] = s.order.PushFront(ean)
+		for s.order.Len() > maxCoalesceKeys {
+			oldest := s.order.Remove(s.order.Back()).(string)
+			delete(s.keys, oldest)
+			delete(s.stats.Keys, oldest)
+		}
+	}
+
+	key := s.stats.Keys[ean]
+	change(&key)
+	s.stats.Keys[ean] = key
+}
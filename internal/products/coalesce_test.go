@@ -0,0 +1,167 @@
+package products
+
+import (
+	"context"
+	"fmt"
+	v1 "github.com/Kobietka/product-service/pkg/api/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"sync"
+	"testing"
+	"time"
+)
+
+// waitForCalls waits until count calls of GetProduct joined the store, calls
+// are counted once they share a read.
+func waitForCalls(t *testing.T, store *CoalescingStore, count uint64) {
+	t.Helper()
+	assert.Eventually(t, func() bool {
+		return store.Stats().Calls >= count
+	}, time.Second, time.Millisecond)
+}
+
+func TestCoalescingStoreSharesReads(t *testing.T) {
+	product := v1.Product{Ean: "12345670", Name: "Sticker", Components: []v1.Component{{Ean: "87654325", Count: 1}}}
+	release := make(chan struct{})
+	store := new(MockStore)
+	store.On("GetProduct", mock.Anything, product.Ean).Return(product, nil).Run(func(mock.Arguments) {
+		<-release
+	})
+	store.On("GetProduct", mock.Anything, "87654325").Return(v1.Product{}, v1.ErrorDataNotFound)
+	coalescing := NewCoalescingStore(store)
+
+	const callers = 10
+	var wg sync.WaitGroup
+	results := make([]v1.Product, callers)
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			result, err := coalescing.GetProduct(context.Background(), product.Ean)
+			assert.NoError(t, err)
+			results[i] = result
+		}()
+	}
+	waitForCalls(t, coalescing, callers)
+	close(release)
+	wg.Wait()
+
+	store.AssertNumberOfCalls(t, "GetProduct", 1)
+	results[0].Components[0].Count = 5
+	for _, result := range results[1:] {
+		assert.Equal(t, product, result, "callers get copies")
+	}
+
+	_, err := coalescing.GetProduct(context.Background(), "87654325")
+	assert.ErrorIs(t, err, v1.ErrorDataNotFound)
+
+	assert.Equal(t, CoalesceStats{
+		Calls: callers + 1,
+		Loads: 2,
+		Keys: map[string]KeyStats{
+			product.Ean: {Calls: callers, Loads: 1},
+			"87654325":  {Calls: 1, Loads: 1},
+		},
+	}, coalescing.Stats())
+}
+
+func TestCoalescingStoreOutlivesLeadingCaller(t *testing.T) {
+	product := v1.Product{Ean: "12345670", Name: "Sticker"}
+	release := make(chan struct{})
+	var loadErr error
+	store := new(MockStore)
+	store.On("GetProduct", mock.Anything, product.Ean).Return(product, nil).Run(func(args mock.Arguments) {
+		<-release
+		loadErr = args.Get(0).(context.Context).Err()
+	})
+	coalescing := NewCoalescingStore(store)
+
+	leaderCtx, cancelLeader := context.WithCancel(context.Background())
+	leaderErr := make(chan error)
+	go func() {
+		_, err := coalescing.GetProduct(leaderCtx, product.Ean)
+		leaderErr <- err
+	}()
+	waitForCalls(t, coalescing, 1)
+
+	followerResult := make(chan v1.Product)
+	go func() {
+		result, err := coalescing.GetProduct(context.Background(), product.Ean)
+		assert.NoError(t, err)
+		followerResult <- result
+	}()
+	waitForCalls(t, coalescing, 2)
+
+	cancelLeader()
+	assert.ErrorIs(t, <-leaderErr, context.Canceled)
+
+	close(release)
+	assert.Equal(t, product, <-followerResult)
+	assert.NoError(t, loadErr, "the shared read is not cancelled with the leading caller")
+	store.AssertNumberOfCalls(t, "GetProduct", 1)
+	assert.Equal(t, KeyStats{Calls: 2, Loads: 1, Cancelled: 1}, coalescing.Stats().Keys[product.Ean])
+}
+
+func TestCoalescingStoreReadsAgainAfterWrites(t *testing.T) {
+	product := v1.Product{Ean: "12345670", Name: "Sticker"}
+	release := make(chan struct{})
+	store := new(MockStore)
+	store.On("GetProduct", mock.Anything, product.Ean).Return(product, nil).Run(func(mock.Arguments) {
+		<-release
+	}).Once()
+	store.On("GetProduct", mock.Anything, product.Ean).Return(product, nil)
+	store.On("UpdateProduct", mock.Anything, product).Return(nil)
+	coalescing := NewCoalescingStore(store)
+
+	done := make(chan struct{})
+	go func() {
+		_, err := coalescing.GetProduct(context.Background(), product.Ean)
+		assert.NoError(t, err)
+		close(done)
+	}()
+	waitForCalls(t, coalescing, 1)
+
+	assert.NoError(t, coalescing.UpdateProduct(context.Background(), product))
+	_, err := coalescing.GetProduct(context.Background(), product.Ean)
+	assert.NoError(t, err)
+
+	close(release)
+	<-done
+	store.AssertNumberOfCalls(t, "GetProduct", 2)
+}
+
+func TestCoalescingStoreLimitsKeyStats(t *testing.T) {
+	store := new(MockStore)
+	store.On("GetProduct", mock.Anything, mock.Anything).Return(v1.Product{}, v1.ErrorDataNotFound)
+	coalescing := NewCoalescingStore(store)
+
+	for i := 0; i <= maxCoalesceKeys; i++ {
+		_, _ = coalescing.GetProduct(context.Background(), fmt.Sprintf("%08d", i))
+	}
+
+	stats := coalescing.Stats()
+	assert.Equal(t, uint64(maxCoalesceKeys+1), stats.Calls)
+	assert.Len(t, stats.Keys, maxCoalesceKeys)
+	assert.NotContains(t, stats.Keys, fmt.Sprintf("%08d", 0))
+}
+
+func TestCoalescingStoreKeepsRecentlyHotKeys(t *testing.T) {
+	store := new(MockStore)
+	store.On("GetProduct", mock.Anything, mock.Anything).Return(v1.Product{}, v1.ErrorDataNotFound)
+	coalescing := NewCoalescingStore(store)
+
+	for i := 0; i < maxCoalesceKeys; i++ {
+		_, _ = coalescing.GetProduct(context.Background(), fmt.Sprintf("%08d", i))
+	}
+	for i := 0; i < 3; i++ {
+		_, _ = coalescing.GetProduct(context.Background(), "promotion")
+		_, _ = coalescing.GetProduct(context.Background(), fmt.Sprintf("%08d", 1))
+	}
+
+	stats := coalescing.Stats()
+	assert.Len(t, stats.Keys, maxCoalesceKeys)
+	assert.Equal(t, KeyStats{Calls: 3, Loads: 3}, stats.Keys["promotion"])
+	assert.Equal(t, KeyStats{Calls: 4, Loads: 4}, stats.Keys[fmt.Sprintf("%08d", 1)])
+	assert.NotContains(t, stats.Keys, fmt.Sprintf("%08d", 0))
+	assert.Contains(t, stats.Keys, fmt.Sprintf("%08d", 2))
+}
@@ -0,0 +1,304 @@
+package products
+
+import (
+	"context"
+	"errors"
+	v1 "github.com/Kobietka/product-service/pkg/api/v1"
+	"maps"
+	"math"
+	"slices"
+)
+
+const (
+	maxCompositionDepth      = 8
+	packagingToleranceFactor = 0.01
+)
+
+var (
+	ErrorComponentNotFound          = errors.New("COMPONENT_NOT_FOUND")
+	ErrorComponentPackagingMismatch = errors.New("COMPONENT_PACKAGING_MISMATCH")
+	ErrorCompositionTooDeep         = errors.New("COMPOSITION_TOO_DEEP")
+)
+
+// validateComposition checks the rules that need the catalogue: every
+// component has to exist, must not contain the product itself further down the
+// tree and the components have to add up to the declared packaging. Failures
+// are returned as Violations, any other error comes from the store.
+func validateComposition(ctx context.Context, store Store, product v1.Product) error {
+	if len(product.Components) == 0 {
+		return nil
+	}
+
+	var violations Violations
+	packaging, dimension, ok := toBase(product.Packaging)
+	total := 0.0
+	complete := true
+	for i, component := range product.Components {
+		child, err := store.GetProduct(ctx, component.Ean)
+		if err != nil {
+			if errors.Is(err, v1.ErrorDataNotFound) {
+				violations.add(pointer("/components", i, "ean"), ErrorComponentNotFound, "ean", component.Ean)
+				complete = false
+				continue
+			}
+			return err
+		}
+
+		err = checkCycle(ctx, store, product.Ean, child, 1)
+		if errors.Is(err, ErrorComponentCycle) || errors.Is(err, ErrorCompositionTooDeep) {
+			violations.add(pointer("/components", i, "ean"), err, "ean", component.Ean)
+		} else if err != nil {
+			return err
+		}
+
+		childPackaging, childDimension, childOk := toBase(child.Packaging)
+		if !ok || !childOk || childDimension != dimension {
+			violations.add(
+				pointer("/components", i, "ean"), ErrorComponentPackagingMismatch,
+				"unit", child.Packaging.Unit, "packagingUnit", product.Packaging.Unit,
+			)
+			complete = false
+			continue
+		}
+		total += float64(component.Count) * childPackaging
+	}
+
+	if complete && math.Abs(total-packaging) > packagingToleranceFactor*total {
+		violations.add("/packaging/value", ErrorComponentPackagingMismatch, "total", total, "packaging", packaging)
+	}
+
+	return violations.err()
+}
+
+func checkCycle(ctx context.Context, store Store, root string, product v1.Product, depth int) error {
+	if depth > maxCompositionDepth {
+		return ErrorCompositionTooDeep
+	}
+
+	for _, component := range product.Components {
+		if component.Ean == root {
+			return ErrorComponentCycle
+		}
+
+		child, err := store.GetProduct(ctx, component.Ean)
+		if err != nil {
+			return err
+		}
+
+		if err := checkCycle(ctx, store, root, child, depth+1); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// resolveNutrition fills in the nutrition of a multipack that does not declare
+// its own from its components, weighting every component by its share of the
+// packaging.
+// A component missing from the store is reported as ErrorComponentNotFound.
+func (s Server) resolveNutrition(ctx context.Context, product v1.Product, depth int) (v1.Product, error) {
+	return resolveNutritionWith(product, depth, func(ean string) (v1.Product, error) {
+		child, err := s.store.GetProduct(ctx, ean)
+		if errors.Is(err, v1.ErrorDataNotFound) {
+			return child, ErrorComponentNotFound
+		}
+		return child, err
+	})
+}
+
+// getProduct reads a product like every endpoint returning one does, with the
+// nutrition of a multipack resolved from its components.
+func (s Server) getProduct(ctx context.Context, ean string) (v1.Product, error) {
+	product, err := s.store.GetProduct(ctx, ean)
+	if err != nil {
+		return product, err
+	}
+	return s.resolveNutrition(ctx, product, 0)
+}
+
+// resolveNutritions resolves the nutrition of every product like
+// resolveNutrition, reading the components of each level of the composition
+// tree in one call to the store. The products that cannot be resolved are
+// returned in failed with their error, the error is only set when the store
+// fails.
+func (s Server) resolveNutritions(ctx context.Context, products map[string]v1.Product) (resolved map[string]v1.Product, failed map[string]error, err error) {
+	known := make(map[string]v1.Product, len(products))
+	maps.Copy(known, products)
+	requested := make(map[string]bool)
+	pending := slices.Collect(maps.Values(products))
+	for depth := 0; depth <= maxCompositionDepth && len(pending) > 0; depth++ {
+		missing := make([]string, 0)
+		for _, product := range pending {
+			if !product.Nutrition.IsEmpty() {
+				continue
+			}
+			for _, component := range product.Components {
+				if _, ok := known[component.Ean]; ok || requested[component.Ean] {
+					continue
+				}
+				requested[component.Ean] = true
+				missing = append(missing, component.Ean)
+			}
+		}
+		if len(missing) == 0 {
+			break
+		}
+		slices.Sort(missing)
+
+		loaded, err := s.store.GetProducts(ctx, missing)
+		if err != nil {
+			return nil, nil, err
+		}
+		pending = pending[:0]
+		for _, child := range loaded {
+			known[child.Ean] = child
+			pending = append(pending, child)
+		}
+	}
+
+	get := func(ean string) (v1.Product, error) {
+		if product, ok := known[ean]; ok {
+			return product, nil
+		}
+		return v1.Product{}, ErrorComponentNotFound
+	}
+	resolved = make(map[string]v1.Product, len(products))
+	failed = make(map[string]error)
+	for code, product := range products {
+		product, err := resolveNutritionWith(product, 0, get)
+		if err != nil {
+			failed[code] = err
+			continue
+		}
+		resolved[code] = product
+	}
+	return resolved, failed, nil
+}
+
+func resolveNutritionWith(product v1.Product, depth int, get func(ean string) (v1.Product, error)) (v1.Product, error) {
+	if len(product.Components) == 0 || !product.Nutrition.IsEmpty() {
+		return product, nil
+	}
+	if depth > maxCompositionDepth {
+		return product, ErrorCompositionTooDeep
+	}
+
+	children := make([]v1.Product, 0, len(product.Components))
+	for _, component := range product.Components {
+		child, err := get(component.Ean)
+		if err != nil {
+			return product, err
+		}
+
+		child, err = resolveNutritionWith(child, depth+1, get)
+		if err != nil {
+			return product, err
+		}
+		children = append(children, child)
+	}
+
+	if len(children) == 1 {
+		product.Nutrition = children[0].Nutrition
+		return product, nil
+	}
+
+	if nutrition, ok := mixNutrition(product.Components, children); ok {
+		product.Nutrition = nutrition
+	}
+	return product, nil
+}
+
+func mixNutrition(components []v1.Component, children []v1.Product) (v1.Nutrition, bool) {
+	per := children[0].Nutrition.Per
+	perBase, dimension, ok := toBase(per)
+	if !ok || perBase == 0 {
+		return v1.Nutrition{}, false
+	}
+
+	weights := make([]float64, len(children))
+	total := 0.0
+	for i, child := range children {
+		packaging, packagingDimension, packagingOk := toBase(child.Packaging)
+		childPer, perDimension, perOk := toBase(child.Nutrition.Per)
+		if !packagingOk || !perOk || packagingDimension != dimension || perDimension != dimension || childPer == 0 {
+			return v1.Nutrition{}, false
+		}
+		amount := float64(components[i].Count) * packaging
+		weights[i] = amount / childPer
+		total += amount
+	}
+	if total == 0 {
+		return v1.Nutrition{}, false
+	}
+	scale := perBase / total
+
+	kcal := 0.0
+	nutrients := newMix[v1.Nutrient]()
+	vitamins := newMix[v1.Vitamin]()
+	minerals := newMix[v1.Mineral]()
+	for i, child := range children {
+		weight := weights[i] * scale
+		kcal += float64(child.Nutrition.Kcal) * weight
+		for _, nutrient := range child.Nutrition.Nutrients {
+			nutrients.add(nutrient.T, nutrient.Quantity, weight)
+		}
+		for _, vitamin := range child.Nutrition.Vitamins {
+			vitamins.add(vitamin.T, vitamin.Quantity, weight)
+		}
+		for _, mineral := range child.Nutrition.Minerals {
+			minerals.add(mineral.T, mineral.Quantity, weight)
+		}
+	}
+
+	return v1.Nutrition{
+		Per:  per,
+		Kcal: int32(math.Round(kcal)),
+		Nutrients: nutrients.result(func(t string, quantity v1.Quantity) v1.Nutrient {
+			return v1.Nutrient{T: t, Quantity: quantity}
+		}),
+		Vitamins: vitamins.result(func(t string, quantity v1.Quantity) v1.Vitamin {
+			return v1.Vitamin{T: t, Quantity: quantity}
+		}),
+		Minerals: minerals.result(func(t string, quantity v1.Quantity) v1.Mineral {
+			return v1.Mineral{T: t, Quantity: quantity}
+		}),
+	}, true
+}
+
+type mix[T any] struct {
+	order  []string
+	values map[string]float64
+	units  map[string]string
+}
+
+func newMix[T any]() *mix[T] {
+	return &mix[T]{values: make(map[string]float64), units: make(map[string]string)}
+}
+
+func (m *mix[T]) add(t string, quantity v1.Quantity, weight float64) {
+	unit, ok := m.units[t]
+	if !ok {
+		m.order = append(m.order, t)
+		m.units[t] = quantity.Unit
+		unit = quantity.Unit
+	}
+
+	value := float64(quantity.Value)
+	if quantity.Unit != unit {
+		from, _, fromOk := toBase(quantity)
+		to, _, toOk := toBase(v1.Quantity{Value: 1, Unit: unit})
+		if !fromOk || !toOk {
+			return
+		}
+		value = from / to
+	}
+	m.values[t] += value * weight
+}
+
+func (m *mix[T]) result(create func(t string, quantity v1.Quantity) T) []T {
+	result := make([]T, 0, len(m.order))
+	for _, t := range m.order {
+		result = append(result, create(t, v1.Quantity{Value: float32(m.values[t]), Unit: m.units[t]}))
+	}
+	return result
+}
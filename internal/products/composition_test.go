@@ -0,0 +1,270 @@
+package products
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	v1 "github.com/Kobietka/product-service/pkg/api/v1"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+var water = v1.Product{
+	Ean:       "5901234123457",
+	Name:      "Water",
+	Packaging: v1.Quantity{Value: 1.5, Unit: "l"},
+	Nutrition: v1.Nutrition{
+		Per:  v1.Quantity{Value: 100, Unit: "ml"},
+		Kcal: 0,
+		Minerals: []v1.Mineral{
+			{T: "MAGNESIUM", Quantity: v1.Quantity{Value: 10, Unit: "mg"}},
+		},
+	},
+}
+
+var juice = v1.Product{
+	Ean:       "4006381333931",
+	Name:      "Juice",
+	Packaging: v1.Quantity{Value: 500, Unit: "ml"},
+	Nutrition: v1.Nutrition{
+		Per:  v1.Quantity{Value: 100, Unit: "ml"},
+		Kcal: 40,
+		Minerals: []v1.Mineral{
+			{T: "MAGNESIUM", Quantity: v1.Quantity{Value: 0.02, Unit: "g"}},
+		},
+	},
+}
+
+func TestValidateComposition(t *testing.T) {
+	tests := []struct {
+		Name        string
+		Product     v1.Product
+		ExpectedErr error
+	}{
+		{
+			Name: "six pack",
+			Product: v1.Product{
+				Ean:        "5901234000000",
+				Packaging:  v1.Quantity{Value: 9, Unit: "l"},
+				Components: []v1.Component{{Ean: water.Ean, Count: 6}},
+			},
+		},
+		{
+			Name: "mixed pack",
+			Product: v1.Product{
+				Ean:        "5901234000000",
+				Packaging:  v1.Quantity{Value: 2500, Unit: "ml"},
+				Components: []v1.Component{{Ean: water.Ean, Count: 1}, {Ean: juice.Ean, Count: 2}},
+			},
+		},
+		{
+			Name: "packaging does not add up",
+			Product: v1.Product{
+				Ean:        "5901234000000",
+				Packaging:  v1.Quantity{Value: 6, Unit: "l"},
+				Components: []v1.Component{{Ean: water.Ean, Count: 6}},
+			},
+			ExpectedErr: ErrorComponentPackagingMismatch,
+		},
+		{
+			Name: "packaging in other dimension",
+			Product: v1.Product{
+				Ean:        "5901234000000",
+				Packaging:  v1.Quantity{Value: 9, Unit: "kg"},
+				Components: []v1.Component{{Ean: water.Ean, Count: 6}},
+			},
+			ExpectedErr: ErrorComponentPackagingMismatch,
+		},
+		{
+			Name: "component not found",
+			Product: v1.Product{
+				Ean:        "5901234000000",
+				Packaging:  v1.Quantity{Value: 9, Unit: "l"},
+				Components: []v1.Component{{Ean: "12345678", Count: 6}},
+			},
+			ExpectedErr: ErrorComponentNotFound,
+		},
+		{
+			Name: "cycle",
+			Product: v1.Product{
+				Ean:        "5901234999999",
+				Packaging:  v1.Quantity{Value: 18, Unit: "l"},
+				Components: []v1.Component{{Ean: "5901234000000", Count: 2}},
+			},
+			ExpectedErr: ErrorComponentCycle,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			store := new(MockStore)
+			store.On("GetProduct", mock.Anything, water.Ean).Return(water, nil)
+			store.On("GetProduct", mock.Anything, juice.Ean).Return(juice, nil)
+			store.On("GetProduct", mock.Anything, "12345678").Return(v1.Product{}, v1.ErrorDataNotFound)
+			store.On("GetProduct", mock.Anything, "5901234000000").Return(v1.Product{
+				Ean:        "5901234000000",
+				Packaging:  v1.Quantity{Value: 9, Unit: "l"},
+				Components: []v1.Component{{Ean: "5901234999999", Count: 1}},
+			}, nil)
+
+			err := validateComposition(context.Background(), store, test.Product)
+			assertValidationError(t, test.ExpectedErr, err)
+		})
+	}
+}
+
+func TestResolveNutrition(t *testing.T) {
+	tests := []struct {
+		Name              string
+		Product           v1.Product
+		ExpectedNutrition v1.Nutrition
+		ExpectedErr       error
+	}{
+		{
+			Name: "explicit nutrition is kept",
+			Product: v1.Product{
+				Components: []v1.Component{{Ean: water.Ean, Count: 6}},
+				Nutrition:  juice.Nutrition,
+			},
+			ExpectedNutrition: juice.Nutrition,
+		},
+		{
+			Name: "single component",
+			Product: v1.Product{
+				Components: []v1.Component{{Ean: water.Ean, Count: 6}},
+			},
+			ExpectedNutrition: water.Nutrition,
+		},
+		{
+			Name: "weighted by packaging",
+			Product: v1.Product{
+				Components: []v1.Component{{Ean: water.Ean, Count: 1}, {Ean: juice.Ean, Count: 3}},
+			},
+			ExpectedNutrition: v1.Nutrition{
+				Per:       v1.Quantity{Value: 100, Unit: "ml"},
+				Kcal:      20,
+				Nutrients: []v1.Nutrient{},
+				Vitamins:  []v1.Vitamin{},
+				Minerals: []v1.Mineral{
+					{T: "MAGNESIUM", Quantity: v1.Quantity{Value: 15, Unit: "mg"}},
+				},
+			},
+		},
+		{
+			Name: "store returns an error",
+			Product: v1.Product{
+				Components: []v1.Component{{Ean: "12345678", Count: 1}},
+			},
+			ExpectedErr: errors.New("error"),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			store := new(MockStore)
+			server := NewServer(store)
+			store.On("GetProduct", mock.Anything, water.Ean).Return(water, nil)
+			store.On("GetProduct", mock.Anything, juice.Ean).Return(juice, nil)
+			store.On("GetProduct", mock.Anything, "12345678").Return(v1.Product{}, errors.New("error"))
+
+			product, err := server.resolveNutrition(context.Background(), test.Product, 0)
+			assert.Equal(t, test.ExpectedErr, err)
+			if test.ExpectedErr == nil {
+				assert.Equal(t, test.ExpectedNutrition, product.Nutrition)
+			}
+		})
+	}
+}
+
+func TestReadPathsResolveNutrition(t *testing.T) {
+	waterPack := v1.Product{
+		Ean:        "2512345000006",
+		Name:       "Water",
+		Packaging:  v1.Quantity{Value: 1.5, Unit: "l"},
+		Components: []v1.Component{{Ean: water.Ean, Count: 1}},
+	}
+
+	tests := []struct {
+		Name    string
+		Request *http.Request
+		Params  []string
+		Handle  func(server Server, c echo.Context) error
+		Decode  func(body io.Reader) (v1.Product, error)
+	}{
+		{
+			Name:    "variable measure",
+			Request: httptest.NewRequest(http.MethodGet, "/", nil),
+			Params:  []string{"2512345003496"},
+			Handle:  Server.handleGetProduct,
+			Decode: func(body io.Reader) (v1.Product, error) {
+				var product v1.Product
+				err := json.NewDecoder(body).Decode(&product)
+				return product, err
+			},
+		},
+		{
+			Name:    "element string",
+			Request: jsonRequest(`{"data": "(01)02512345000006"}`),
+			Handle:  Server.handleResolveBarcode,
+			Decode: func(body io.Reader) (v1.Product, error) {
+				var resolution v1.Resolution
+				err := json.NewDecoder(body).Decode(&resolution)
+				if resolution.Product == nil {
+					return v1.Product{}, err
+				}
+				return *resolution.Product, err
+			},
+		},
+		{
+			Name:    "search",
+			Request: httptest.NewRequest(http.MethodGet, "/products?query=water&limit=5", nil),
+			Handle:  Server.handleSearchProduct,
+			Decode: func(body io.Reader) (v1.Product, error) {
+				var products []v1.Product
+				err := json.NewDecoder(body).Decode(&products)
+				if len(products) != 1 {
+					return v1.Product{}, err
+				}
+				return products[0], err
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			store := new(MockStore)
+			server := NewServer(store)
+			store.On("GetProduct", mock.Anything, waterPack.Ean).Return(waterPack, nil)
+			store.On("GetProduct", mock.Anything, water.Ean).Return(water, nil)
+			store.On("GetProduct", mock.Anything, mock.Anything).Return(v1.Product{}, v1.ErrorDataNotFound)
+			store.On("GetProducts", mock.Anything, []string{water.Ean}).Return(map[string]v1.Product{water.Ean: water}, nil)
+			store.On("SearchProducts", mock.Anything, "water", int8(5)).Return([]v1.Product{waterPack}, nil)
+
+			response := httptest.NewRecorder()
+			c := echo.New().NewContext(test.Request, response)
+			if test.Params != nil {
+				c.SetParamNames("ean")
+				c.SetParamValues(test.Params...)
+			}
+
+			err := test.Handle(server, c)
+			assert.NoError(t, err)
+			assert.Equal(t, http.StatusOK, response.Code)
+			product, err := test.Decode(response.Body)
+			assert.NoError(t, err)
+			assert.Equal(t, water.Nutrition, product.Nutrition)
+		})
+	}
+}
+
+func jsonRequest(body string) *http.Request {
+	request := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+	request.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	return request
+}
@@ -0,0 +1,41 @@
+package database
+
+import (
+	"github.com/Kobietka/product-service/internal/database/setup"
+	"github.com/Kobietka/product-service/internal/database/storetest"
+	"github.com/Kobietka/product-service/internal/products"
+	typesdb "github.com/Kobietka/product-service/internal/types/database"
+	"testing"
+)
+
+func TestMemoryStoreConformance(t *testing.T) {
+	storetest.RunProductStore(t, func(t *testing.T) products.Store {
+		return NewMemoryStore(typesdb.NewMemoryStore(setup.DefaultFixture()))
+	})
+}
+
+func TestSqliteStoreConformance(t *testing.T) {
+	storetest.RunProductStore(t, func(t *testing.T) products.Store {
+		return NewSqliteStore(storetest.SqliteDb(t))
+	})
+}
+
+// TestPostgresStoreConformance runs when DATABASE_URL points at a local
+// Postgres instance.
+func TestPostgresStoreConformance(t *testing.T) {
+	storetest.RunProductStore(t, func(t *testing.T) products.Store {
+		return NewPostgresStore(storetest.PostgresPool(t))
+	})
+}
+
+func TestCachedStoreConformance(t *testing.T) {
+	storetest.RunProductStore(t, func(t *testing.T) products.Store {
+		return products.NewCachedStore(NewSqliteStore(storetest.SqliteDb(t)), products.DefaultCacheSize, products.DefaultCacheTtl)
+	})
+}
+
+func TestCoalescingStoreConformance(t *testing.T) {
+	storetest.RunProductStore(t, func(t *testing.T) products.Store {
+		return products.NewCoalescingStore(NewSqliteStore(storetest.SqliteDb(t)))
+	})
+}
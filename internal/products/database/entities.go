@@ -42,3 +42,57 @@ type mineralEntity struct {
 	Value float32
 	Unit  string
 }
+
+type componentEntity struct {
+	Ean          string
+	ComponentEan string
+	Count        int32
+}
+
+type servingEntity struct {
+	Ean   string
+	Name  string
+	Value float32
+	Unit  string
+}
+
+// productDocumentEntity is a document of product_document, the nutrition of
+// a product without one is null but for its lists.
+type productDocumentEntity struct {
+	Ean        string              `json:"ean"`
+	Name       string              `json:"name"`
+	Packaging  quantityDocument    `json:"packaging"`
+	Nutrition  nutritionDocument   `json:"nutrition"`
+	Components []componentDocument `json:"components"`
+	Servings   []servingDocument   `json:"servings"`
+}
+
+type quantityDocument struct {
+	Value float32 `json:"value"`
+	Unit  string  `json:"unit"`
+}
+
+type nutritionDocument struct {
+	Kcal      int32                   `json:"kcal"`
+	Per       quantityDocument        `json:"per"`
+	Nutrients []typedQuantityDocument `json:"nutrients"`
+	Vitamins  []typedQuantityDocument `json:"vitamins"`
+	Minerals  []typedQuantityDocument `json:"minerals"`
+}
+
+type typedQuantityDocument struct {
+	Type  string  `json:"type"`
+	Value float32 `json:"value"`
+	Unit  string  `json:"unit"`
+}
+
+type componentDocument struct {
+	Ean   string `json:"ean"`
+	Count int32  `json:"count"`
+}
+
+type servingDocument struct {
+	Name  string  `json:"name"`
+	Value float32 `json:"value"`
+	Unit  string  `json:"unit"`
+}
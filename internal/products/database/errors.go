@@ -0,0 +1,34 @@
+package database
+
+import (
+	v1 "github.com/Kobietka/product-service/pkg/api/v1"
+	"github.com/Kobietka/product-service/pkg/postgres"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// The results below translate the failure of a single write into a store
+// error. Other errors, serialization failures among them, are returned as they
+// are so that the unit of work can retry them.
+
+func insertProductResult(tag pgconn.CommandTag, err error) error {
+	if postgres.HasCode(err, postgres.UniqueViolation) {
+		return v1.ErrorProductAlreadyExists
+	}
+	return childRowResult(tag, err)
+}
+
+func updateProductResult(tag pgconn.CommandTag, err error) error {
+	if err == nil && tag.RowsAffected() == 0 {
+		return v1.ErrorProductDoesNotExist
+	}
+	return childRowResult(tag, err)
+}
+
+// childRowResult reports constraint violations as invalid data, they are
+// caused by unknown units or types, duplicates or missing components.
+func childRowResult(_ pgconn.CommandTag, err error) error {
+	if postgres.IsConstraintViolation(err) {
+		return v1.ErrorInvalidData
+	}
+	return err
+}
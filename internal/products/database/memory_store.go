@@ -0,0 +1,335 @@
+package database
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/Kobietka/product-service/internal/types"
+	v1 "github.com/Kobietka/product-service/pkg/api/v1"
+	"maps"
+	"math"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// MemoryStore keeps products in memory for local development and tests. It
+// enforces what the schema enforces for PostgresStore: units and types have to
+// be in the dictionaries of the type store, components have to exist and
+// products used as components cannot be deleted.
+type MemoryStore struct {
+	types    types.Store
+	mutex    sync.RWMutex
+	products map[string]v1.Product
+}
+
+func NewMemoryStore(types types.Store) *MemoryStore {
+	return &MemoryStore{
+		types:    types,
+		products: make(map[string]v1.Product),
+	}
+}
+
+// Load creates the products in order, so components have to come before the
+// products made of them.
+func (s *MemoryStore) Load(ctx context.Context, products []v1.Product) error {
+	for _, product := range products {
+		if err := s.CreateProduct(ctx, product); err != nil {
+			return fmt.Errorf("product %s: %w", product.Ean, err)
+		}
+	}
+	return nil
+}
+
+func (s *MemoryStore) GetProduct(_ context.Context, ean string) (v1.Product, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	product, ok := s.products[ean]
+	if !ok {
+		return v1.Product{}, v1.ErrorDataNotFound
+	}
+	return product.Clone(), nil
+}
+
+func (s *MemoryStore) GetProducts(_ context.Context, eans []string) (map[string]v1.Product, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	products := make(map[string]v1.Product, len(eans))
+	for _, ean := range eans {
+		if product, ok := s.products[ean]; ok {
+			products[ean] = product.Clone()
+		}
+	}
+	return products, nil
+}
+
+// SearchProducts matches names like PostgresStore does, case-insensitive with
+// the wildcards of LIKE, ordered by EAN.
+func (s *MemoryStore) SearchProducts(_ context.Context, query string, limit int8) ([]v1.Product, error) {
+	pattern, err := likePattern("%" + strings.ToLower(query) + "%")
+	if err != nil {
+		return nil, err
+	}
+
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	products := make([]v1.Product, 0)
+	for _, ean := range slices.Sorted(maps.Keys(s.products)) {
+		if len(products) >= int(limit) {
+			break
+		}
+		product := s.products[ean]
+		if pattern.MatchString(strings.ToLower(product.Name)) {
+			products = append(products, product.Clone())
+		}
+	}
+	return products, nil
+}
+
+func (s *MemoryStore) CreateProduct(ctx context.Context, product v1.Product) error {
+	dictionaries, err := types.LoadDictionaries(ctx, s.types)
+	if err != nil {
+		return err
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if _, ok := s.products[product.Ean]; ok {
+		return v1.ErrorProductAlreadyExists
+	}
+	if !s.isValid(product, dictionaries) {
+		return v1.ErrorInvalidData
+	}
+
+	s.products[product.Ean] = normalizeProduct(product)
+	return nil
+}
+
+func (s *MemoryStore) UpdateProduct(ctx context.Context, product v1.Product) error {
+	dictionaries, err := types.LoadDictionaries(ctx, s.types)
+	if err != nil {
+		return err
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if _, ok := s.products[product.Ean]; !ok {
+		return v1.ErrorProductDoesNotExist
+	}
+	if !s.isValid(product, dictionaries) {
+		return v1.ErrorInvalidData
+	}
+
+	s.products[product.Ean] = normalizeProduct(product)
+	return nil
+}
+
+func (s *MemoryStore) UpsertProduct(ctx context.Context, product v1.Product) (bool, error) {
+	dictionaries, err := types.LoadDictionaries(ctx, s.types)
+	if err != nil {
+		return false, err
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if !s.isValid(product, dictionaries) {
+		return false, v1.ErrorInvalidData
+	}
+
+	_, exists := s.products[product.Ean]
+	s.products[product.Ean] = normalizeProduct(product)
+	return !exists, nil
+}
+
+func (s *MemoryStore) UpsertProducts(ctx context.Context, products []v1.Product) error {
+	dictionaries, err := types.LoadDictionaries(ctx, s.types)
+	if err != nil {
+		return err
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	previous := maps.Clone(s.products)
+	for _, product := range products {
+		if !s.isValid(product, dictionaries) {
+			s.products = previous
+			return v1.ErrorInvalidData
+		}
+
+		s.products[product.Ean] = normalizeProduct(product)
+	}
+	return nil
+}
+
+func (s *MemoryStore) DeleteProduct(_ context.Context, ean string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if _, ok := s.products[ean]; !ok {
+		return v1.ErrorProductDoesNotExist
+	}
+	for _, product := range s.products {
+		if product.Ean == ean {
+			continue
+		}
+		if slices.ContainsFunc(product.Components, func(component v1.Component) bool {
+			return component.Ean == ean
+		}) {
+			return v1.ErrorProductInUse
+		}
+	}
+
+	delete(s.products, ean)
+	return nil
+}
+
+// isValid reports whether the product satisfies the constraints of the
+// schema, a violation is a failed write in PostgresStore.
+func (s *MemoryStore) isValid(product v1.Product, dictionaries types.Dictionaries) bool {
+	isUnit := func(unit string) bool {
+		_, ok := dictionaries.Units[unit]
+		return ok
+	}
+
+	if !isUnit(product.Packaging.Unit) {
+		return false
+	}
+
+	if !product.Nutrition.IsEmpty() {
+		if !isUnit(product.Nutrition.Per.Unit) {
+			return false
+		}
+		nutrients := make(map[string]bool)
+		for _, nutrient := range product.Nutrition.Nutrients {
+			if !dictionaries.NutrientTypes[nutrient.T] || !isUnit(nutrient.Quantity.Unit) || nutrients[nutrient.T] {
+				return false
+			}
+			nutrients[nutrient.T] = true
+		}
+		vitamins := make(map[string]bool)
+		for _, vitamin := range product.Nutrition.Vitamins {
+			if !dictionaries.VitaminTypes[vitamin.T] || !isUnit(vitamin.Quantity.Unit) || vitamins[vitamin.T] {
+				return false
+			}
+			vitamins[vitamin.T] = true
+		}
+		minerals := make(map[string]bool)
+		for _, mineral := range product.Nutrition.Minerals {
+			if !dictionaries.MineralTypes[mineral.T] || !isUnit(mineral.Quantity.Unit) || minerals[mineral.T] {
+				return false
+			}
+			minerals[mineral.T] = true
+		}
+	}
+
+	components := make(map[string]bool)
+	for _, component := range product.Components {
+		_, exists := s.products[component.Ean]
+		if (!exists && component.Ean != product.Ean) || component.Count <= 0 || components[component.Ean] {
+			return false
+		}
+		components[component.Ean] = true
+	}
+
+	servings := make(map[string]bool)
+	for _, serving := range product.Servings {
+		if !isUnit(serving.Quantity.Unit) || serving.Quantity.Value <= 0 || servings[serving.Name] {
+			return false
+		}
+		servings[serving.Name] = true
+	}
+
+	return true
+}
+
+// normalizeProduct returns the product as PostgresStore reads it back: the
+// nutrition quantity is a whole number, lists are never nil, nutrients,
+// vitamins and minerals are ordered by type, components by EAN and servings
+// by quantity and name.
+func normalizeProduct(product v1.Product) v1.Product {
+	nutrition := v1.Nutrition{
+		Nutrients: []v1.Nutrient{},
+		Vitamins:  []v1.Vitamin{},
+		Minerals:  []v1.Mineral{},
+	}
+	if !product.Nutrition.IsEmpty() {
+		nutrition = v1.Nutrition{
+			Per: v1.Quantity{
+				Value: float32(math.RoundToEven(float64(product.Nutrition.Per.Value))),
+				Unit:  product.Nutrition.Per.Unit,
+			},
+			Kcal:      product.Nutrition.Kcal,
+			Nutrients: append([]v1.Nutrient{}, product.Nutrition.Nutrients...),
+			Vitamins:  append([]v1.Vitamin{}, product.Nutrition.Vitamins...),
+			Minerals:  append([]v1.Mineral{}, product.Nutrition.Minerals...),
+		}
+		slices.SortFunc(nutrition.Nutrients, func(a, b v1.Nutrient) int {
+			return strings.Compare(a.T, b.T)
+		})
+		slices.SortFunc(nutrition.Vitamins, func(a, b v1.Vitamin) int {
+			return strings.Compare(a.T, b.T)
+		})
+		slices.SortFunc(nutrition.Minerals, func(a, b v1.Mineral) int {
+			return strings.Compare(a.T, b.T)
+		})
+	}
+
+	components := append([]v1.Component{}, product.Components...)
+	slices.SortFunc(components, func(a, b v1.Component) int {
+		return strings.Compare(a.Ean, b.Ean)
+	})
+
+	servings := make([]v1.Serving, 0, len(product.Servings))
+	for _, serving := range product.Servings {
+		servings = append(servings, v1.Serving{Name: serving.Name, Quantity: serving.Quantity})
+	}
+	slices.SortFunc(servings, func(a, b v1.Serving) int {
+		return cmp.Or(cmp.Compare(a.Quantity.Value, b.Quantity.Value), strings.Compare(a.Name, b.Name))
+	})
+
+	return v1.Product{
+		Ean:        product.Ean,
+		Name:       product.Name,
+		Packaging:  product.Packaging,
+		Nutrition:  nutrition,
+		Components: components,
+		Servings:   servings,
+	}
+}
+
+// likePattern compiles a LIKE pattern, % matches any text, _ a single
+// character and a backslash escapes the next character.
+func likePattern(pattern string) (*regexp.Regexp, error) {
+	var expression strings.Builder
+	expression.WriteString(`(?s)^`)
+	escaped := false
+	for _, r := range pattern {
+		switch {
+		case escaped:
+			expression.WriteString(regexp.QuoteMeta(string(r)))
+			escaped = false
+		case r == '\\':
+			escaped = true
+		case r == '%':
+			expression.WriteString(`.*`)
+		case r == '_':
+			expression.WriteString(`.`)
+		default:
+			expression.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	if escaped {
+		return nil, errors.New("LIKE pattern must not end with escape character")
+	}
+	expression.WriteString(`$`)
+	return regexp.Compile(expression.String())
+}
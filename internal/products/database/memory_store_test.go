@@ -0,0 +1,252 @@
+package database
+
+import (
+	"context"
+	"github.com/Kobietka/product-service/internal/database/setup"
+	typesdb "github.com/Kobietka/product-service/internal/types/database"
+	v1 "github.com/Kobietka/product-service/pkg/api/v1"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"testing"
+)
+
+var oatBar = v1.Product{
+	Ean:       "4006381333931",
+	Name:      "Oat Bar",
+	Packaging: v1.Quantity{Value: 40, Unit: "g"},
+	Nutrition: v1.Nutrition{
+		Per:  v1.Quantity{Value: 100, Unit: "g"},
+		Kcal: 420,
+		Nutrients: []v1.Nutrient{
+			{T: "FAT", Quantity: v1.Quantity{Value: 15, Unit: "g"}},
+			{T: "CARBOHYDRATES", Quantity: v1.Quantity{Value: 60, Unit: "g"}},
+			{T: "PROTEIN", Quantity: v1.Quantity{Value: 9, Unit: "g"}},
+		},
+	},
+}
+
+func newTestMemoryStore(t *testing.T, products ...v1.Product) *MemoryStore {
+	store := NewMemoryStore(typesdb.NewMemoryStore(setup.DefaultFixture()))
+	assert.NoError(t, store.Load(context.Background(), products))
+	return store
+}
+
+func TestMemoryStoreCreateProduct(t *testing.T) {
+	withProduct := func(change func(product *v1.Product)) v1.Product {
+		product := oatBar
+		product.Nutrition.Nutrients = append([]v1.Nutrient{}, oatBar.Nutrition.Nutrients...)
+		product.Ean = "12345678"
+		change(&product)
+		return product
+	}
+
+	tests := []struct {
+		Name        string
+		Product     v1.Product
+		ExpectedErr error
+	}{
+		{
+			Name:    "creates product",
+			Product: withProduct(func(*v1.Product) {}),
+		},
+		{
+			Name:        "product already exists",
+			Product:     oatBar,
+			ExpectedErr: v1.ErrorProductAlreadyExists,
+		},
+		{
+			Name: "unknown packaging unit",
+			Product: withProduct(func(product *v1.Product) {
+				product.Packaging.Unit = "oz"
+			}),
+			ExpectedErr: v1.ErrorInvalidData,
+		},
+		{
+			Name: "unknown nutrient type",
+			Product: withProduct(func(product *v1.Product) {
+				product.Nutrition.Nutrients[0].T = "CAFFEINE"
+			}),
+			ExpectedErr: v1.ErrorInvalidData,
+		},
+		{
+			Name: "duplicate nutrient",
+			Product: withProduct(func(product *v1.Product) {
+				product.Nutrition.Nutrients[1].T = "FAT"
+			}),
+			ExpectedErr: v1.ErrorInvalidData,
+		},
+		{
+			Name: "unknown vitamin type",
+			Product: withProduct(func(product *v1.Product) {
+				product.Nutrition.Vitamins = []v1.Vitamin{{T: "VITAMIN_Z", Quantity: v1.Quantity{Value: 1, Unit: "mg"}}}
+			}),
+			ExpectedErr: v1.ErrorInvalidData,
+		},
+		{
+			Name: "missing component",
+			Product: withProduct(func(product *v1.Product) {
+				product.Components = []v1.Component{{Ean: "87654321", Count: 1}}
+			}),
+			ExpectedErr: v1.ErrorInvalidData,
+		},
+		{
+			Name: "component count not positive",
+			Product: withProduct(func(product *v1.Product) {
+				product.Components = []v1.Component{{Ean: oatBar.Ean, Count: 0}}
+			}),
+			ExpectedErr: v1.ErrorInvalidData,
+		},
+		{
+			Name: "duplicate serving",
+			Product: withProduct(func(product *v1.Product) {
+				product.Servings = []v1.Serving{
+					{Name: "1 bar", Quantity: v1.Quantity{Value: 40, Unit: "g"}},
+					{Name: "1 bar", Quantity: v1.Quantity{Value: 20, Unit: "g"}},
+				}
+			}),
+			ExpectedErr: v1.ErrorInvalidData,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			store := newTestMemoryStore(t, oatBar)
+
+			err := store.CreateProduct(context.Background(), test.Product)
+			assert.ErrorIs(t, err, test.ExpectedErr)
+			if test.ExpectedErr != nil {
+				return
+			}
+			_, err = store.GetProduct(context.Background(), test.Product.Ean)
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestMemoryStoreGetProduct(t *testing.T) {
+	multipack := v1.Product{
+		Ean:       "4006381333948",
+		Name:      "Oat Bar Multipack",
+		Packaging: v1.Quantity{Value: 240, Unit: "g"},
+		Components: []v1.Component{
+			{Ean: oatBar.Ean, Count: 6},
+			{Ean: "12345670", Count: 1},
+		},
+		Servings: []v1.Serving{
+			{Name: "2 bars", Quantity: v1.Quantity{Value: 80, Unit: "g"}},
+			{Name: "1 bar", Quantity: v1.Quantity{Value: 40, Unit: "g"}},
+		},
+	}
+	store := newTestMemoryStore(t, oatBar, v1.Product{Ean: "12345670", Name: "Sticker", Packaging: v1.Quantity{Value: 1, Unit: "pcs"}}, multipack)
+
+	product, err := store.GetProduct(context.Background(), multipack.Ean)
+	assert.NoError(t, err)
+	assert.Equal(t, v1.Product{
+		Ean:       multipack.Ean,
+		Name:      multipack.Name,
+		Packaging: multipack.Packaging,
+		Nutrition: v1.Nutrition{Nutrients: []v1.Nutrient{}, Vitamins: []v1.Vitamin{}, Minerals: []v1.Mineral{}},
+		Components: []v1.Component{
+			{Ean: "12345670", Count: 1},
+			{Ean: oatBar.Ean, Count: 6},
+		},
+		Servings: []v1.Serving{
+			{Name: "1 bar", Quantity: v1.Quantity{Value: 40, Unit: "g"}},
+			{Name: "2 bars", Quantity: v1.Quantity{Value: 80, Unit: "g"}},
+		},
+	}, product)
+
+	product.Components[0].Count = 10
+	product, err = store.GetProduct(context.Background(), multipack.Ean)
+	assert.NoError(t, err)
+	assert.Equal(t, int32(1), product.Components[0].Count)
+
+	_, err = store.GetProduct(context.Background(), "87654321")
+	assert.ErrorIs(t, err, v1.ErrorDataNotFound)
+}
+
+func TestMemoryStoreSearchProducts(t *testing.T) {
+	store := newTestMemoryStore(t,
+		v1.Product{Ean: "44444444", Name: "Barley", Packaging: v1.Quantity{Value: 1, Unit: "kg"}},
+		v1.Product{Ean: "11111111", Name: "Oat Bar", Packaging: v1.Quantity{Value: 40, Unit: "g"}},
+		v1.Product{Ean: "22222222", Name: "Chocolate bar", Packaging: v1.Quantity{Value: 100, Unit: "g"}},
+		v1.Product{Ean: "33333333", Name: "Milk 100%", Packaging: v1.Quantity{Value: 1, Unit: "l"}},
+	)
+
+	tests := []struct {
+		Name         string
+		Query        string
+		Limit        int8
+		ExpectedEans []string
+	}{
+		{Name: "case-insensitive by ean", Query: "BAR", Limit: 15, ExpectedEans: []string{"11111111", "22222222", "44444444"}},
+		{Name: "limited", Query: "bar", Limit: 2, ExpectedEans: []string{"11111111", "22222222"}},
+		{Name: "single character wildcard", Query: "t_bar", Limit: 15, ExpectedEans: []string{"11111111"}},
+		{Name: "escaped wildcard", Query: `0\%`, Limit: 15, ExpectedEans: []string{"33333333"}},
+		{Name: "no match", Query: "bread", Limit: 15, ExpectedEans: []string{}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			products, err := store.SearchProducts(context.Background(), test.Query, test.Limit)
+			assert.NoError(t, err)
+			eans := make([]string, 0)
+			for _, product := range products {
+				eans = append(eans, product.Ean)
+			}
+			assert.Equal(t, test.ExpectedEans, eans)
+		})
+	}
+}
+
+func TestMemoryStoreWrites(t *testing.T) {
+	ctx := context.Background()
+	multipack := v1.Product{
+		Ean:        "4006381333948",
+		Name:       "Oat Bar Multipack",
+		Packaging:  v1.Quantity{Value: 240, Unit: "g"},
+		Components: []v1.Component{{Ean: oatBar.Ean, Count: 6}},
+	}
+	store := newTestMemoryStore(t, oatBar)
+
+	assert.ErrorIs(t, store.UpdateProduct(ctx, multipack), v1.ErrorProductDoesNotExist)
+
+	created, err := store.UpsertProduct(ctx, multipack)
+	assert.NoError(t, err)
+	assert.True(t, created)
+
+	multipack.Name = "Oat Bar 6 Pack"
+	created, err = store.UpsertProduct(ctx, multipack)
+	assert.NoError(t, err)
+	assert.False(t, created)
+
+	renamed := oatBar
+	renamed.Name = "Oat Bar Classic"
+	assert.NoError(t, store.UpdateProduct(ctx, renamed))
+
+	products, err := store.SearchProducts(ctx, "oat", 15)
+	assert.NoError(t, err)
+	assert.Len(t, products, 2)
+	assert.Equal(t, "Oat Bar Classic", products[0].Name)
+	assert.Equal(t, "Oat Bar 6 Pack", products[1].Name)
+
+	assert.ErrorIs(t, store.DeleteProduct(ctx, oatBar.Ean), v1.ErrorProductInUse)
+	assert.NoError(t, store.DeleteProduct(ctx, multipack.Ean))
+	assert.NoError(t, store.DeleteProduct(ctx, oatBar.Ean))
+	assert.ErrorIs(t, store.DeleteProduct(ctx, oatBar.Ean), v1.ErrorProductDoesNotExist)
+
+	products, err = store.SearchProducts(ctx, "oat", 15)
+	assert.NoError(t, err)
+	assert.Empty(t, products)
+}
+
+func TestMemoryStoreLoadsDevelopmentFixture(t *testing.T) {
+	file, err := os.Open("../../../fixtures/products.json")
+	assert.NoError(t, err)
+	defer file.Close()
+
+	fixture, err := setup.ReadFixture(file)
+	assert.NoError(t, err)
+	store := NewMemoryStore(typesdb.NewMemoryStore(fixture))
+	assert.NoError(t, store.Load(context.Background(), fixture.Products))
+}
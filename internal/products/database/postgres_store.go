@@ -7,40 +7,30 @@ import (
 	"github.com/Kobietka/product-service/pkg/array"
 	"github.com/Kobietka/product-service/pkg/postgres"
 	"github.com/jackc/pgx/v5"
-	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
 type PostgresStore struct {
-	pool *pgxpool.Pool
+	pool       *pgxpool.Pool
+	unitOfWork postgres.UnitOfWork
 }
 
+// NewPostgresStore writes in serializable transactions, concurrent writes of
+// the same product are retried by the unit of work.
 func NewPostgresStore(pool *pgxpool.Pool) PostgresStore {
-	return PostgresStore{pool: pool}
+	return PostgresStore{
+		pool:       pool,
+		unitOfWork: postgres.NewUnitOfWork(pool).WithOptions(pgx.TxOptions{IsoLevel: pgx.Serializable}),
+	}
 }
 
 func (s PostgresStore) GetProduct(ctx context.Context, ean string) (v1.Product, error) {
-	batch := pgx.Batch{}
-	addProductQueries(&batch, ean)
-	batchResults := s.pool.SendBatch(ctx, &batch)
-
-	productE, err := postgres.CollectOneRow[productEntity](batchResults)
-	if err != nil {
-		if errors.Is(err, pgx.ErrNoRows) {
-			return v1.Product{}, v1.ErrorDataNotFound
-		}
-		return v1.Product{}, err
-	}
-
-	packagingE, err := postgres.CollectOneRow[packagingEntity](batchResults)
+	rows, err := s.pool.Query(ctx, selectDocumentQuery, ean)
 	if err != nil {
-		if errors.Is(err, pgx.ErrNoRows) {
-			return v1.Product{}, v1.ErrorDataNotFound
-		}
 		return v1.Product{}, err
 	}
 
-	nutritionE, err := postgres.CollectOneRow[nutritionEntity](batchResults)
+	document, err := pgx.CollectExactlyOneRow(rows, pgx.RowTo[productDocumentEntity])
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return v1.Product{}, v1.ErrorDataNotFound
@@ -48,349 +38,179 @@ func (s PostgresStore) GetProduct(ctx context.Context, ean string) (v1.Product,
 		return v1.Product{}, err
 	}
 
-	nutritionQuantityE, err := postgres.CollectOneRow[nutritionQuantityEntity](batchResults)
-	if err != nil {
-		if errors.Is(err, pgx.ErrNoRows) {
-			return v1.Product{}, v1.ErrorDataNotFound
-		}
-		return v1.Product{}, err
-	}
-
-	nutrientEntities, err := postgres.CollectRows[nutrientEntity](batchResults)
-	if err != nil {
-		if !errors.Is(err, pgx.ErrNoRows) {
-			return v1.Product{}, err
-		}
-	}
+	return toProduct(document), nil
+}
 
-	vitaminEntities, err := postgres.CollectRows[vitaminEntity](batchResults)
+func (s PostgresStore) GetProducts(ctx context.Context, eans []string) (map[string]v1.Product, error) {
+	rows, err := s.pool.Query(ctx, selectDocumentsQuery, eans)
 	if err != nil {
-		if !errors.Is(err, pgx.ErrNoRows) {
-			return v1.Product{}, err
-		}
+		return nil, err
 	}
 
-	mineralEntities, err := postgres.CollectRows[mineralEntity](batchResults)
+	documents, err := pgx.CollectRows(rows, pgx.RowTo[productDocumentEntity])
 	if err != nil {
-		if !errors.Is(err, pgx.ErrNoRows) {
-			return v1.Product{}, err
-		}
+		return nil, err
 	}
 
-	err = batchResults.Close()
-	if err != nil {
-		return v1.Product{}, err
+	products := make(map[string]v1.Product, len(documents))
+	for _, document := range documents {
+		products[document.Ean] = toProduct(document)
 	}
-
-	return v1.Product{
-		Ean:  productE.Ean,
-		Name: productE.Name,
-		Packaging: v1.Quantity{
-			Value: packagingE.Value,
-			Unit:  packagingE.Unit,
-		},
-		Nutrition: v1.Nutrition{
-			Per: v1.Quantity{
-				Value: nutritionQuantityE.Value,
-				Unit:  nutritionQuantityE.Unit,
-			},
-			Kcal: nutritionE.Kcal,
-			Nutrients: array.MapArray(nutrientEntities, func(entity nutrientEntity) v1.Nutrient {
-				return v1.Nutrient{
-					T: entity.Type,
-					Quantity: v1.Quantity{
-						Value: entity.Value,
-						Unit:  entity.Unit,
-					},
-				}
-			}),
-			Vitamins: array.MapArray(vitaminEntities, func(entity vitaminEntity) v1.Vitamin {
-				return v1.Vitamin{
-					T: entity.Type,
-					Quantity: v1.Quantity{
-						Value: entity.Value,
-						Unit:  entity.Unit,
-					},
-				}
-			}),
-			Minerals: array.MapArray(mineralEntities, func(entity mineralEntity) v1.Mineral {
-				return v1.Mineral{
-					T: entity.Type,
-					Quantity: v1.Quantity{
-						Value: entity.Value,
-						Unit:  entity.Unit,
-					},
-				}
-			}),
-		},
-	}, nil
+	return products, nil
 }
 
+// SearchProducts reads the documents of the matching products ordered by
+// EAN, a document that cannot be read fails the search.
 func (s PostgresStore) SearchProducts(ctx context.Context, query string, limit int8) ([]v1.Product, error) {
-	conn, err := s.pool.Acquire(ctx)
+	rows, err := s.pool.Query(ctx, searchDocumentsQuery, "%"+query+"%", limit)
 	if err != nil {
 		return nil, err
 	}
-	defer conn.Release()
 
-	productsSearchQuery := `
-		SELECT 
-		ean, 
-		name 
-		FROM product 
-		WHERE LOWER(name) LIKE LOWER($1) 
-		LIMIT $2
-	`
-	rows, err := conn.Query(ctx, productsSearchQuery, "%"+query+"%", limit)
+	documents, err := pgx.CollectRows(rows, pgx.RowTo[productDocumentEntity])
 	if err != nil {
 		return nil, err
 	}
 
-	productEntities, err := pgx.CollectRows(rows, pgx.RowToStructByPos[productEntity])
-	if err != nil {
-		if errors.Is(err, pgx.ErrNoRows) {
-			return make([]v1.Product, 0), nil
-		}
-		return nil, err
-	}
-
-	batch := pgx.Batch{}
-	for _, entity := range productEntities {
-		addProductQueries(&batch, entity.Ean)
-	}
-
-	batchResults := conn.SendBatch(ctx, &batch)
-
-	products := make([]v1.Product, 0)
-	for range productEntities {
-		productE, entityErr := postgres.CollectOneRow[productEntity](batchResults)
-		packagingE, entityErr := postgres.CollectOneRow[packagingEntity](batchResults)
-		nutritionE, entityErr := postgres.CollectOneRow[nutritionEntity](batchResults)
-		nutritionQuantityE, entityErr := postgres.CollectOneRow[nutritionQuantityEntity](batchResults)
-		nutrientEntities, entityErr := postgres.CollectRows[nutrientEntity](batchResults)
-		vitaminEntities, entityErr := postgres.CollectRows[vitaminEntity](batchResults)
-		mineralEntities, entityErr := postgres.CollectRows[mineralEntity](batchResults)
-
-		if entityErr != nil {
-			if !errors.Is(entityErr, pgx.ErrNoRows) {
-				continue
-			}
-		}
-
-		product := v1.Product{
-			Ean:  productE.Ean,
-			Name: productE.Name,
-			Packaging: v1.Quantity{
-				Value: packagingE.Value,
-				Unit:  packagingE.Unit,
-			},
-			Nutrition: v1.Nutrition{
-				Per: v1.Quantity{
-					Value: nutritionQuantityE.Value,
-					Unit:  nutritionQuantityE.Unit,
-				},
-				Kcal: nutritionE.Kcal,
-				Nutrients: array.MapArray(nutrientEntities, func(entity nutrientEntity) v1.Nutrient {
-					return v1.Nutrient{
-						T: entity.Type,
-						Quantity: v1.Quantity{
-							Value: entity.Value,
-							Unit:  entity.Unit,
-						},
-					}
-				}),
-				Vitamins: array.MapArray(vitaminEntities, func(entity vitaminEntity) v1.Vitamin {
-					return v1.Vitamin{
-						T: entity.Type,
-						Quantity: v1.Quantity{
-							Value: entity.Value,
-							Unit:  entity.Unit,
-						},
-					}
-				}),
-				Minerals: array.MapArray(mineralEntities, func(entity mineralEntity) v1.Mineral {
-					return v1.Mineral{
-						T: entity.Type,
-						Quantity: v1.Quantity{
-							Value: entity.Value,
-							Unit:  entity.Unit,
-						},
-					}
-				}),
-			},
-		}
-		products = append(products, product)
-	}
-
-	err = batchResults.Close()
-	if err != nil {
-		return nil, err
-	}
-
-	return products, nil
+	return array.MapArray(documents, toProduct), nil
 }
 
 func (s PostgresStore) CreateProduct(ctx context.Context, product v1.Product) error {
-	batch := pgx.Batch{}
-
-	productQuery := `
-		INSERT INTO product(ean, name)
-		VALUES ($1, $2);
-	`
-	batch.Queue(productQuery, product.Ean, product.Name)
-
-	packagingQuery := `
-		INSERT INTO packaging(ean, value, unit_id)
-		VALUES ($1, $2, (SELECT id FROM unit WHERE value = $3));
-	`
-	batch.Queue(packagingQuery, product.Ean, product.Packaging.Value, product.Packaging.Unit)
-
-	nutritionQuery := `
-		INSERT INTO nutrition(ean, kcal)
-		VALUES ($1, $2);
-	`
-	batch.Queue(nutritionQuery, product.Ean, product.Nutrition.Kcal)
-
-	nutritionQuantityQuery := `
-		INSERT INTO nutrition_quantity(ean, value, unit_id)
-		VALUES ($1, $2, (SELECT id FROM unit WHERE value = $3));
-	`
-	batch.Queue(nutritionQuantityQuery, product.Ean, product.Nutrition.Per.Value, product.Nutrition.Per.Unit)
-
-	nutrientQuery := `
-		INSERT INTO nutrient(ean, type_id, value, unit_id)
-		VALUES ($1, (SELECT id FROM nutrient_type WHERE type = $2), $3, (SELECT id FROM unit WHERE value = $4));
-	`
-	for _, nutrient := range product.Nutrition.Nutrients {
-		batch.Queue(nutrientQuery, product.Ean, nutrient.T, nutrient.Quantity.Value, nutrient.Quantity.Unit)
-	}
-
-	vitaminQuery := `
-		INSERT INTO vitamin(ean, type_id, value, unit_id)
-		VALUES ($1, (SELECT id FROM vitamin_type WHERE type = $2), $3, (SELECT id FROM unit WHERE value = $4));
-	`
-	for _, vitamin := range product.Nutrition.Vitamins {
-		batch.Queue(vitaminQuery, product.Ean, vitamin.T, vitamin.Quantity.Value, vitamin.Quantity.Unit)
-	}
-
-	mineralQuery := `
-		INSERT INTO mineral(ean, type_id, value, unit_id)
-		VALUES ($1, (SELECT id FROM mineral_type WHERE type = $2), $3, (SELECT id FROM unit WHERE value = $4));
-	`
-	for _, mineral := range product.Nutrition.Minerals {
-		batch.Queue(mineralQuery, product.Ean, mineral.T, mineral.Quantity.Value, mineral.Quantity.Unit)
-	}
-
-	err := s.pool.SendBatch(ctx, &batch).Close()
-	if err != nil {
-		var pgErr *pgconn.PgError
-		if errors.As(err, &pgErr) {
-			return v1.ErrorInvalidData
-		}
-		return err
-	}
-
-	return err
+	return s.unitOfWork.Run(ctx, func(tx pgx.Tx) error {
+		batch := pgx.Batch{}
+
+		productQuery := `
+			INSERT INTO product(ean, name)
+			VALUES ($1, $2);
+		`
+		postgres.ExpectExec(batch.Queue(productQuery, product.Ean, product.Name), insertProductResult)
+
+		addPackagingQueries(&batch, product)
+		addNutritionQueries(&batch, product)
+		addComponentQueries(&batch, product)
+		addServingQueries(&batch, product)
+
+		return tx.SendBatch(ctx, &batch).Close()
+	})
 }
 
 func (s PostgresStore) UpdateProduct(ctx context.Context, product v1.Product) error {
-	batch := pgx.Batch{}
-
-	productQuery := `
-		UPDATE product
-		SET name = $2
-		WHERE ean = $1
-	`
-	batch.Queue(productQuery, product.Ean, product.Name)
-
-	packagingQuery := `
-		UPDATE packaging
-		SET value = $2, unit_id = (SELECT id FROM unit WHERE value = $3)
-		WHERE ean = $1
-	`
-	batch.Queue(packagingQuery, product.Ean, product.Packaging.Value, product.Packaging.Unit)
-
-	nutritionQuery := `
-		UPDATE nutrition
-		SET kcal = $2
-		WHERE ean = $1
-	`
-	batch.Queue(nutritionQuery, product.Ean, product.Nutrition.Kcal)
-
-	nutritionQuantityQuery := `
-		UPDATE nutrition_quantity
-		SET value = $2, unit_id = (SELECT id FROM unit WHERE value = $3)
-		WHERE ean = $1
-	`
-	batch.Queue(nutritionQuantityQuery, product.Ean, product.Nutrition.Per.Value, product.Nutrition.Per.Unit)
-
-	deleteNutrientsQuery := `DELETE FROM nutrient WHERE ean = $1`
-	batch.Queue(deleteNutrientsQuery, product.Ean)
-
-	nutrientQuery := `
-		INSERT INTO nutrient(ean, type_id, value, unit_id)
-		VALUES ($1, (SELECT id FROM nutrient_type WHERE type = $2), $3, (SELECT id FROM unit WHERE value = $4));
-	`
-	for _, nutrient := range product.Nutrition.Nutrients {
-		batch.Queue(nutrientQuery, product.Ean, nutrient.T, nutrient.Quantity.Value, nutrient.Quantity.Unit)
-	}
+	return s.unitOfWork.Run(ctx, func(tx pgx.Tx) error {
+		batch := pgx.Batch{}
+
+		productQuery := `
+			UPDATE product
+			SET name = $2
+			WHERE ean = $1
+		`
+		postgres.ExpectExec(batch.Queue(productQuery, product.Ean, product.Name), updateProductResult)
+
+		addDeleteChildQueries(&batch, product.Ean)
+		addPackagingQueries(&batch, product)
+		addNutritionQueries(&batch, product)
+		addComponentQueries(&batch, product)
+		addServingQueries(&batch, product)
+
+		return tx.SendBatch(ctx, &batch).Close()
+	})
+}
 
-	deleteVitaminsQuery := `DELETE FROM vitamin WHERE ean = $1`
-	batch.Queue(deleteVitaminsQuery, product.Ean)
+// UpsertProduct creates the product or replaces the stored one, created
+// reports which of the two happened.
+func (s PostgresStore) UpsertProduct(ctx context.Context, product v1.Product) (bool, error) {
+	var created bool
+	err := s.unitOfWork.Run(ctx, func(tx pgx.Tx) error {
+		batch := pgx.Batch{}
+		addUpsertProductQueries(&batch, product, &created)
+		return tx.SendBatch(ctx, &batch).Close()
+	})
+	return created, err
+}
 
-	vitaminQuery := `
-		INSERT INTO vitamin(ean, type_id, value, unit_id)
-		VALUES ($1, (SELECT id FROM vitamin_type WHERE type = $2), $3, (SELECT id FROM unit WHERE value = $4));
-	`
-	for _, vitamin := range product.Nutrition.Vitamins {
-		batch.Queue(vitaminQuery, product.Ean, vitamin.T, vitamin.Quantity.Value, vitamin.Quantity.Unit)
-	}
+func (s PostgresStore) UpsertProducts(ctx context.Context, products []v1.Product) error {
+	return s.unitOfWork.Run(ctx, func(tx pgx.Tx) error {
+		batch := pgx.Batch{}
+		for _, product := range products {
+			addUpsertProductQueries(&batch, product, nil)
+		}
+		return tx.SendBatch(ctx, &batch).Close()
+	})
+}
 
-	deleteMineralsQuery := `DELETE FROM mineral WHERE ean = $1`
-	batch.Queue(deleteMineralsQuery, product.Ean)
+// CountDriftedDocuments returns how many products have a document that
+// differs from what their rows build, or a document they should not have.
+func (s PostgresStore) CountDriftedDocuments(ctx context.Context) (int64, error) {
+	var drifted int64
+	err := s.pool.QueryRow(ctx, countDriftedDocumentsQuery).Scan(&drifted)
+	return drifted, err
+}
 
-	mineralQuery := `
-		INSERT INTO mineral(ean, type_id, value, unit_id)
-		VALUES ($1, (SELECT id FROM mineral_type WHERE type = $2), $3, (SELECT id FROM unit WHERE value = $4));
-	`
-	for _, mineral := range product.Nutrition.Minerals {
-		batch.Queue(mineralQuery, product.Ean, mineral.T, mineral.Quantity.Value, mineral.Quantity.Unit)
-	}
+// RebuildDocuments replaces every document of product_document with one built
+// from the rows of the product and returns how many were written.
+func (s PostgresStore) RebuildDocuments(ctx context.Context) (int64, error) {
+	var rebuilt int64
+	err := s.unitOfWork.Run(ctx, func(tx pgx.Tx) error {
+		if _, err := tx.Exec(ctx, `DELETE FROM product_document`); err != nil {
+			return err
+		}
 
-	batchResults := s.pool.SendBatch(ctx, &batch)
+		tag, err := tx.Exec(ctx, rebuildDocumentsQuery)
+		if err != nil {
+			return err
+		}
+		rebuilt = tag.RowsAffected()
+		return nil
+	})
+	return rebuilt, err
+}
 
-	tag, err := batchResults.Exec()
-	if err != nil {
-		return err
-	}
+func (s PostgresStore) DeleteProduct(ctx context.Context, ean string) error {
+	return s.unitOfWork.Run(ctx, func(tx pgx.Tx) error {
+		query := `DELETE FROM product WHERE ean = $1`
 
-	if tag.RowsAffected() == 0 {
-		return v1.ErrorProductDoesNotExist
-	}
+		tag, err := tx.Exec(ctx, query, ean)
+		if postgres.HasCode(err, postgres.ForeignKeyViolation) {
+			return v1.ErrorProductInUse
+		}
+		if err != nil {
+			return err
+		}
 
-	err = batchResults.Close()
-	if err != nil {
-		var pgErr *pgconn.PgError
-		if errors.As(err, &pgErr) {
-			return v1.ErrorInvalidData
+		if tag.RowsAffected() == 0 {
+			return v1.ErrorProductDoesNotExist
 		}
-		return err
-	}
 
-	return nil
+		return nil
+	})
 }
 
-func (s PostgresStore) DeleteProduct(ctx context.Context, ean string) error {
-	query := `DELETE FROM product WHERE ean = $1`
-
-	tag, execErr := s.pool.Exec(ctx, query, ean)
-	if execErr != nil {
-		return execErr
+func toProduct(document productDocumentEntity) v1.Product {
+	toQuantity := func(document quantityDocument) v1.Quantity {
+		return v1.Quantity{Value: document.Value, Unit: document.Unit}
 	}
 
-	if tag.RowsAffected() == 0 {
-		return v1.ErrorProductDoesNotExist
+	return v1.Product{
+		Ean:       document.Ean,
+		Name:      document.Name,
+		Packaging: toQuantity(document.Packaging),
+		Nutrition: v1.Nutrition{
+			Per:  toQuantity(document.Nutrition.Per),
+			Kcal: document.Nutrition.Kcal,
+			Nutrients: array.MapArray(document.Nutrition.Nutrients, func(document typedQuantityDocument) v1.Nutrient {
+				return v1.Nutrient{T: document.Type, Quantity: v1.Quantity{Value: document.Value, Unit: document.Unit}}
+			}),
+			Vitamins: array.MapArray(document.Nutrition.Vitamins, func(document typedQuantityDocument) v1.Vitamin {
+				return v1.Vitamin{T: document.Type, Quantity: v1.Quantity{Value: document.Value, Unit: document.Unit}}
+			}),
+			Minerals: array.MapArray(document.Nutrition.Minerals, func(document typedQuantityDocument) v1.Mineral {
+				return v1.Mineral{T: document.Type, Quantity: v1.Quantity{Value: document.Value, Unit: document.Unit}}
+			}),
+		},
+		Components: array.MapArray(document.Components, func(document componentDocument) v1.Component {
+			return v1.Component{Ean: document.Ean, Count: document.Count}
+		}),
+		Servings: array.MapArray(document.Servings, func(document servingDocument) v1.Serving {
+			return v1.Serving{Name: document.Name, Quantity: v1.Quantity{Value: document.Value, Unit: document.Unit}}
+		}),
 	}
-
-	return nil
 }
@@ -0,0 +1,492 @@
+package database
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/Kobietka/product-service/internal/database/setup"
+	"github.com/Kobietka/product-service/internal/database/storetest"
+	v1 "github.com/Kobietka/product-service/pkg/api/v1"
+	"github.com/Kobietka/product-service/pkg/array"
+	"github.com/Kobietka/product-service/pkg/postgres"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+// TestPostgresStoreNotifiesWrites runs when DATABASE_URL points at a local
+// Postgres instance.
+func TestPostgresStoreNotifiesWrites(t *testing.T) {
+	pool := storetest.PostgresPool(t)
+	store := NewPostgresStore(pool)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	connected := make(chan struct{}, 1)
+	payloads := make(chan string, 10)
+	go postgres.NewListener(pool, "product_changed").Listen(ctx, func() {
+		connected <- struct{}{}
+	}, func(payload string) {
+		payloads <- payload
+	})
+	<-connected
+
+	renamed := oatBar
+	renamed.Name = "Oat Bar Classic"
+	assert.NoError(t, store.CreateProduct(ctx, oatBar))
+	assert.NoError(t, store.UpdateProduct(ctx, renamed))
+	_, err := store.UpsertProduct(ctx, oatBar)
+	assert.NoError(t, err)
+	assert.NoError(t, store.DeleteProduct(ctx, oatBar.Ean))
+
+	// Other tests may write to the same database, their products are ignored.
+	for received := 0; received < 4; {
+		select {
+		case payload := <-payloads:
+			if payload == oatBar.Ean {
+				received++
+			}
+		case <-ctx.Done():
+			t.Fatalf("received %d of 4 notifications", received)
+		}
+	}
+}
+
+func TestToProduct(t *testing.T) {
+	tests := []struct {
+		Name     string
+		Document string
+		Expected v1.Product
+	}{
+		{
+			Name: "product without nutrition",
+			Document: `{"ean": "12345670", "name": "Sticker", "packaging": {"value": 1, "unit": "pcs"},
+				"nutrition": {"kcal": null, "per": null, "nutrients": [], "vitamins": [], "minerals": []},
+				"components": [], "servings": []}`,
+			Expected: v1.Product{
+				Ean:        "12345670",
+				Name:       "Sticker",
+				Packaging:  v1.Quantity{Value: 1, Unit: "pcs"},
+				Nutrition:  v1.Nutrition{Nutrients: []v1.Nutrient{}, Vitamins: []v1.Vitamin{}, Minerals: []v1.Mineral{}},
+				Components: []v1.Component{},
+				Servings:   []v1.Serving{},
+			},
+		},
+		{
+			Name: "full product",
+			Document: `{"ean": "4006381333931", "name": "Oat Bar", "packaging": {"value": 40, "unit": "g"},
+				"nutrition": {"kcal": 420, "per": {"value": 100, "unit": "g"},
+					"nutrients": [{"type": "FAT", "value": 15.5, "unit": "g"}],
+					"vitamins": [{"type": "VITAMIN_E", "value": 1.2, "unit": "mg"}],
+					"minerals": [{"type": "IRON", "value": 3.5, "unit": "mg"}]},
+				"components": [{"ean": "12345670", "count": 2}],
+				"servings": [{"name": "half", "value": 20, "unit": "g"}]}`,
+			Expected: v1.Product{
+				Ean:       oatBar.Ean,
+				Name:      oatBar.Name,
+				Packaging: oatBar.Packaging,
+				Nutrition: v1.Nutrition{
+					Per:       v1.Quantity{Value: 100, Unit: "g"},
+					Kcal:      420,
+					Nutrients: []v1.Nutrient{{T: "FAT", Quantity: v1.Quantity{Value: 15.5, Unit: "g"}}},
+					Vitamins:  []v1.Vitamin{{T: "VITAMIN_E", Quantity: v1.Quantity{Value: 1.2, Unit: "mg"}}},
+					Minerals:  []v1.Mineral{{T: "IRON", Quantity: v1.Quantity{Value: 3.5, Unit: "mg"}}},
+				},
+				Components: []v1.Component{{Ean: "12345670", Count: 2}},
+				Servings:   []v1.Serving{{Name: "half", Quantity: v1.Quantity{Value: 20, Unit: "g"}}},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			var document productDocumentEntity
+			assert.NoError(t, json.Unmarshal([]byte(test.Document), &document))
+			assert.Equal(t, test.Expected, toProduct(document))
+		})
+	}
+}
+
+// TestPostgresStoreRefreshesDocumentsOnCommit runs when DATABASE_URL points
+// at a local Postgres instance.
+func TestPostgresStoreRefreshesDocumentsOnCommit(t *testing.T) {
+	pool := storetest.PostgresPool(t)
+	store := NewPostgresStore(pool)
+	ctx := context.Background()
+	assert.NoError(t, store.CreateProduct(ctx, oatBar))
+
+	tx, err := pool.Begin(ctx)
+	assert.NoError(t, err)
+	_, err = tx.Exec(ctx, `UPDATE product SET name = 'Oat Bar Classic' WHERE ean = $1`, oatBar.Ean)
+	assert.NoError(t, err)
+	_, err = tx.Exec(ctx, `UPDATE nutrient SET value = value + 1 WHERE ean = $1`, oatBar.Ean)
+	assert.NoError(t, err)
+
+	var name string
+	assert.NoError(t, tx.QueryRow(ctx, `SELECT document ->> 'name' FROM product_document WHERE ean = $1`, oatBar.Ean).Scan(&name))
+	assert.Equal(t, oatBar.Name, name, "the document is refreshed when the transaction commits")
+	var pending int
+	assert.NoError(t, tx.QueryRow(ctx, `SELECT count(*) FROM product_document_pending WHERE ean = $1`, oatBar.Ean).Scan(&pending))
+	assert.Equal(t, 1, pending, "the EAN is queued once")
+	assert.NoError(t, tx.Commit(ctx))
+
+	product, err := store.GetProduct(ctx, oatBar.Ean)
+	assert.NoError(t, err)
+	assert.Equal(t, "Oat Bar Classic", product.Name)
+	assert.NoError(t, pool.QueryRow(ctx, `SELECT count(*) FROM product_document_pending`).Scan(&pending))
+	assert.Equal(t, 0, pending)
+	drifted, err := store.CountDriftedDocuments(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), drifted)
+}
+
+// TestPostgresStoreRebuildsDocuments runs when DATABASE_URL points at a local
+// Postgres instance.
+func TestPostgresStoreRebuildsDocuments(t *testing.T) {
+	pool := storetest.PostgresPool(t)
+	store := NewPostgresStore(pool)
+	ctx := context.Background()
+	eans := createBenchmarkProducts(t, store, 3)
+	product, err := store.GetProduct(ctx, eans[0])
+	assert.NoError(t, err)
+
+	drifted, err := store.CountDriftedDocuments(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), drifted)
+
+	_, err = pool.Exec(ctx, `UPDATE product_document SET document = document || '{"name": "Drifted"}' WHERE ean = $1`, eans[0])
+	assert.NoError(t, err)
+	_, err = pool.Exec(ctx, `DELETE FROM product_document WHERE ean = $1`, eans[1])
+	assert.NoError(t, err)
+	drifted, err = store.CountDriftedDocuments(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), drifted)
+
+	rebuilt, err := store.RebuildDocuments(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(len(eans)+1), rebuilt)
+	drifted, err = store.CountDriftedDocuments(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), drifted)
+
+	rebuiltProduct, err := store.GetProduct(ctx, eans[0])
+	assert.NoError(t, err)
+	assert.Equal(t, product, rebuiltProduct)
+}
+
+// TestPostgresSchemaRemovesDuplicateRows runs when DATABASE_URL points at a
+// local Postgres instance.
+func TestPostgresSchemaRemovesDuplicateRows(t *testing.T) {
+	pool := storetest.PostgresPool(t)
+	store := NewPostgresStore(pool)
+	ctx := context.Background()
+	assert.NoError(t, store.CreateProduct(ctx, oatBar))
+
+	for _, table := range []string{"packaging", "nutrition", "nutrition_quantity"} {
+		_, err := pool.Exec(ctx, `DROP INDEX `+table+`_ean`)
+		assert.NoError(t, err)
+		_, err = pool.Exec(ctx, `INSERT INTO `+table+` SELECT * FROM `+table+` WHERE ean = $1`, oatBar.Ean)
+		assert.NoError(t, err)
+	}
+
+	assert.NoError(t, setup.NewSeeder(pool).CreateSchema(ctx))
+	for _, table := range []string{"packaging", "nutrition", "nutrition_quantity"} {
+		var rows int
+		assert.NoError(t, pool.QueryRow(ctx, `SELECT count(*) FROM `+table+` WHERE ean = $1`, oatBar.Ean).Scan(&rows))
+		assert.Equal(t, 1, rows, table)
+	}
+	_, err := store.UpsertProduct(ctx, oatBar)
+	assert.NoError(t, err)
+}
+
+// TestPostgresStoreReadsLikeBatch runs when DATABASE_URL points at a local
+// Postgres instance.
+func TestPostgresStoreReadsLikeBatch(t *testing.T) {
+	pool := storetest.PostgresPool(t)
+	store := NewPostgresStore(pool)
+	eans := createBenchmarkProducts(t, store, 10)
+
+	for _, ean := range eans {
+		product, err := store.GetProduct(context.Background(), ean)
+		assert.NoError(t, err)
+		batchProduct, err := batchGetProduct(context.Background(), pool, ean)
+		assert.NoError(t, err)
+		assert.Equal(t, batchProduct, product)
+	}
+
+	products, err := store.SearchProducts(context.Background(), "benchmark", 100)
+	assert.NoError(t, err)
+	batchProducts, err := batchSearchProducts(context.Background(), pool, "benchmark", 100)
+	assert.NoError(t, err)
+	assert.Equal(t, batchProducts, products)
+}
+
+func BenchmarkPostgresStoreGetProduct(b *testing.B) {
+	pool := storetest.PostgresPool(b)
+	store := NewPostgresStore(pool)
+	eans := createBenchmarkProducts(b, store, 100)
+	ctx := context.Background()
+
+	b.Run("document", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			if _, err := store.GetProduct(ctx, eans[i%len(eans)]); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("batch", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			if _, err := batchGetProduct(ctx, pool, eans[i%len(eans)]); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func BenchmarkPostgresStoreSearchProducts(b *testing.B) {
+	pool := storetest.PostgresPool(b)
+	store := NewPostgresStore(pool)
+	createBenchmarkProducts(b, store, 100)
+	ctx := context.Background()
+
+	for _, limit := range []int8{1, 15, 100} {
+		b.Run(fmt.Sprintf("document %d", limit), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				if _, err := store.SearchProducts(ctx, "benchmark", limit); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+		b.Run(fmt.Sprintf("batch %d", limit), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				if _, err := batchSearchProducts(ctx, pool, "benchmark", limit); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// createBenchmarkProducts creates count products made of oat bars, with every
+// list of a product filled.
+func createBenchmarkProducts(t testing.TB, store PostgresStore, count int) []string {
+	t.Helper()
+
+	bar := oatBar
+	bar.Nutrition.Vitamins = []v1.Vitamin{{T: "VITAMIN_E", Quantity: v1.Quantity{Value: 1.5, Unit: "mg"}}}
+	bar.Nutrition.Minerals = []v1.Mineral{{T: "IRON", Quantity: v1.Quantity{Value: 3.5, Unit: "mg"}}}
+	bar.Servings = []v1.Serving{{Name: "half", Quantity: v1.Quantity{Value: 20, Unit: "g"}}}
+	if err := store.CreateProduct(context.Background(), bar); err != nil {
+		t.Fatal(err)
+	}
+
+	eans := make([]string, 0, count)
+	for i := 0; i < count; i++ {
+		product := bar
+		product.Ean = fmt.Sprintf("2%011d", i)
+		product.Name = fmt.Sprintf("Benchmark bar %d", i)
+		product.Components = []v1.Component{{Ean: bar.Ean, Count: int32(i%5 + 1)}}
+		product.Servings = []v1.Serving{
+			{Name: "two bars", Quantity: v1.Quantity{Value: 80, Unit: "g"}},
+			{Name: "one bar", Quantity: v1.Quantity{Value: 40, Unit: "g"}},
+		}
+		if err := store.CreateProduct(context.Background(), product); err != nil {
+			t.Fatal(err)
+		}
+		eans = append(eans, product.Ean)
+	}
+	return eans
+}
+
+// The queries reading a product from the normalised tables, their only
+// parameter is the EAN.
+const (
+	selectProductQuery = `
+		SELECT ean, name
+		FROM product
+		WHERE ean = $1
+	`
+	selectPackagingQuery = `
+		SELECT
+		packaging.ean,
+		packaging.value,
+		unit.value
+		FROM packaging
+		JOIN unit ON unit.id = unit_id
+		WHERE packaging.ean = $1
+	`
+	selectNutritionQuery = `
+		SELECT
+		ean,
+		kcal
+		FROM nutrition
+		WHERE ean = $1
+	`
+	selectNutritionQuantityQuery = `
+		SELECT
+		nutrition_quantity.ean,
+		nutrition_quantity.value,
+		unit.value
+		FROM nutrition_quantity
+		JOIN unit ON unit.id = unit_id
+		WHERE nutrition_quantity.ean = $1
+	`
+	selectNutrientsQuery = `
+		SELECT
+		nutrient.ean,
+		nutrient_type.type,
+		nutrient.value,
+		unit.value
+		FROM nutrient
+		JOIN unit ON unit.id = unit_id
+		JOIN nutrient_type ON nutrient.type_id = nutrient_type.id
+		WHERE nutrient.ean = $1
+		ORDER BY nutrient_type.type
+	`
+	selectVitaminsQuery = `
+		SELECT
+		vitamin.ean,
+		vitamin_type.type,
+		vitamin.value,
+		unit.value
+		FROM vitamin
+		JOIN unit ON unit.id = unit_id
+		JOIN vitamin_type ON vitamin.type_id = vitamin_type.id
+		WHERE vitamin.ean = $1
+		ORDER BY vitamin_type.type
+	`
+	selectMineralsQuery = `
+		SELECT
+		mineral.ean,
+		mineral_type.type,
+		mineral.value,
+		unit.value
+		FROM mineral
+		JOIN unit ON unit.id = unit_id
+		JOIN mineral_type ON mineral.type_id = mineral_type.id
+		WHERE mineral.ean = $1
+		ORDER BY mineral_type.type
+	`
+	selectComponentsQuery = `
+		SELECT
+		ean,
+		component_ean,
+		count
+		FROM product_component
+		WHERE ean = $1
+		ORDER BY component_ean
+	`
+	selectServingsQuery = `
+		SELECT
+		product_serving.ean,
+		product_serving.name,
+		product_serving.value,
+		unit.value
+		FROM product_serving
+		JOIN unit ON unit.id = unit_id
+		WHERE product_serving.ean = $1
+		ORDER BY product_serving.value, product_serving.name
+	`
+)
+
+// batchGetProduct and batchSearchProducts read products from the normalised
+// tables with a query per table, the way PostgresStore did before
+// product_document. They are the
+// baseline of the benchmarks.
+func batchGetProduct(ctx context.Context, pool *pgxpool.Pool, ean string) (v1.Product, error) {
+	batch := pgx.Batch{}
+	addProductQueries(&batch, ean)
+	results := pool.SendBatch(ctx, &batch)
+	product, err := collectBatchProduct(results)
+	return product, errors.Join(err, results.Close())
+}
+
+func batchSearchProducts(ctx context.Context, pool *pgxpool.Pool, query string, limit int8) ([]v1.Product, error) {
+	rows, err := pool.Query(ctx, `SELECT ean, name FROM product WHERE LOWER(name) LIKE LOWER($1) ORDER BY ean LIMIT $2`, "%"+query+"%", limit)
+	if err != nil {
+		return nil, err
+	}
+	productEntities, err := pgx.CollectRows(rows, pgx.RowToStructByPos[productEntity])
+	if err != nil {
+		return nil, err
+	}
+
+	batch := pgx.Batch{}
+	for _, entity := range productEntities {
+		addProductQueries(&batch, entity.Ean)
+	}
+	results := pool.SendBatch(ctx, &batch)
+	products := make([]v1.Product, 0, len(productEntities))
+	for range productEntities {
+		product, err := collectBatchProduct(results)
+		if err != nil {
+			return nil, errors.Join(err, results.Close())
+		}
+		products = append(products, product)
+	}
+	return products, results.Close()
+}
+
+func addProductQueries(batch *pgx.Batch, ean string) {
+	batch.Queue(selectProductQuery, ean)
+	batch.Queue(selectPackagingQuery, ean)
+	batch.Queue(selectNutritionQuery, ean)
+	batch.Queue(selectNutritionQuantityQuery, ean)
+	batch.Queue(selectNutrientsQuery, ean)
+	batch.Queue(selectVitaminsQuery, ean)
+	batch.Queue(selectMineralsQuery, ean)
+	batch.Queue(selectComponentsQuery, ean)
+	batch.Queue(selectServingsQuery, ean)
+}
+
+func collectBatchProduct(results pgx.BatchResults) (v1.Product, error) {
+	optional := func(err error) error {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil
+		}
+		return err
+	}
+
+	productE, productErr := postgres.CollectOneRow[productEntity](results)
+	packagingE, packagingErr := postgres.CollectOneRow[packagingEntity](results)
+	nutritionE, nutritionErr := postgres.CollectOneRow[nutritionEntity](results)
+	nutritionQuantityE, nutritionQuantityErr := postgres.CollectOneRow[nutritionQuantityEntity](results)
+	nutrientEntities, nutrientsErr := postgres.CollectRows[nutrientEntity](results)
+	vitaminEntities, vitaminsErr := postgres.CollectRows[vitaminEntity](results)
+	mineralEntities, mineralsErr := postgres.CollectRows[mineralEntity](results)
+	componentEntities, componentsErr := postgres.CollectRows[componentEntity](results)
+	servingEntities, servingsErr := postgres.CollectRows[servingEntity](results)
+
+	if errors.Is(productErr, pgx.ErrNoRows) || errors.Is(packagingErr, pgx.ErrNoRows) {
+		return v1.Product{}, v1.ErrorDataNotFound
+	}
+	err := errors.Join(productErr, packagingErr, optional(nutritionErr), optional(nutritionQuantityErr),
+		nutrientsErr, vitaminsErr, mineralsErr, componentsErr, servingsErr)
+	if err != nil {
+		return v1.Product{}, err
+	}
+
+	return v1.Product{
+		Ean:       productE.Ean,
+		Name:      productE.Name,
+		Packaging: v1.Quantity{Value: packagingE.Value, Unit: packagingE.Unit},
+		Nutrition: v1.Nutrition{
+			Per:  v1.Quantity{Value: nutritionQuantityE.Value, Unit: nutritionQuantityE.Unit},
+			Kcal: nutritionE.Kcal,
+			Nutrients: array.MapArray(nutrientEntities, func(entity nutrientEntity) v1.Nutrient {
+				return v1.Nutrient{T: entity.Type, Quantity: v1.Quantity{Value: entity.Value, Unit: entity.Unit}}
+			}),
+			Vitamins: array.MapArray(vitaminEntities, func(entity vitaminEntity) v1.Vitamin {
+				return v1.Vitamin{T: entity.Type, Quantity: v1.Quantity{Value: entity.Value, Unit: entity.Unit}}
+			}),
+			Minerals: array.MapArray(mineralEntities, func(entity mineralEntity) v1.Mineral {
+				return v1.Mineral{T: entity.Type, Quantity: v1.Quantity{Value: entity.Value, Unit: entity.Unit}}
+			}),
+		},
+		Components: array.MapArray(componentEntities, toComponent),
+		Servings:   array.MapArray(servingEntities, toServing),
+	}, nil
+}
@@ -1,82 +1,258 @@
 package database
 
-import "github.com/jackc/pgx/v5"
+import (
+	v1 "github.com/Kobietka/product-service/pkg/api/v1"
+	"github.com/Kobietka/product-service/pkg/array"
+	"github.com/Kobietka/product-service/pkg/postgres"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
 
-func addProductQueries(batch *pgx.Batch, ean string) {
-	productQuery := `
-		SELECT ean, name 
-		FROM product
+// The documents of product_document are built by build_product_document of
+// schema.sql, a product without one is not found. Search matches the names of
+// the narrow product table and only reads the documents of the matches.
+const (
+	selectDocumentQuery = `
+		SELECT document
+		FROM product_document
 		WHERE ean = $1
 	`
-	batch.Queue(productQuery, ean)
+	selectDocumentsQuery = `
+		SELECT document
+		FROM product_document
+		WHERE ean = ANY($1)
+	`
+	searchDocumentsQuery = `
+		SELECT product_document.document
+		FROM product
+		JOIN product_document ON product_document.ean = product.ean
+		WHERE LOWER(product.name) LIKE LOWER($1)
+		ORDER BY product.ean
+		LIMIT $2
+	`
+	countDriftedDocumentsQuery = `
+		SELECT count(*)
+		FROM (SELECT ean, build_product_document(ean) AS document FROM product) built
+		FULL JOIN product_document USING (ean)
+		WHERE built.document IS DISTINCT FROM product_document.document
+	`
+	rebuildDocumentsQuery = `
+		INSERT INTO product_document (ean, document)
+		SELECT ean, document
+		FROM (SELECT ean, build_product_document(ean) AS document FROM product) built
+		WHERE document IS NOT NULL
+	`
+)
 
+func addPackagingQueries(batch *pgx.Batch, product v1.Product) {
 	packagingQuery := `
-		SELECT
-		packaging.ean,
-		packaging.value,
-		unit.value
-		FROM packaging
-		JOIN unit ON unit.id = unit_id
-		WHERE packaging.ean = $1
+		INSERT INTO packaging(ean, value, unit_id)
+		VALUES ($1, $2, (SELECT id FROM unit WHERE value = $3));
 	`
-	batch.Queue(packagingQuery, ean)
+	postgres.ExpectExec(batch.Queue(packagingQuery, product.Ean, product.Packaging.Value, product.Packaging.Unit), childRowResult)
+}
+
+// childTables hold every row of a product but the product itself.
+var childTables = []string{
+	"packaging",
+	"nutrition",
+	"nutrition_quantity",
+	"nutrient",
+	"vitamin",
+	"mineral",
+	"product_component",
+	"product_serving",
+}
+
+// addDeleteChildQueries removes the child rows of the product, so that an
+// update can insert them again.
+func addDeleteChildQueries(batch *pgx.Batch, ean string) {
+	for _, table := range childTables {
+		batch.Queue(`DELETE FROM `+table+` WHERE ean = $1`, ean)
+	}
+}
+
+func addNutritionQueries(batch *pgx.Batch, product v1.Product) {
+	if product.Nutrition.IsEmpty() {
+		return
+	}
 
 	nutritionQuery := `
-		SELECT
-		ean,
-		kcal
-		FROM nutrition
-		WHERE ean = $1
-    `
-	batch.Queue(nutritionQuery, ean)
+		INSERT INTO nutrition(ean, kcal)
+		VALUES ($1, $2);
+	`
+	postgres.ExpectExec(batch.Queue(nutritionQuery, product.Ean, product.Nutrition.Kcal), childRowResult)
+
+	nutritionQuantityQuery := `
+		INSERT INTO nutrition_quantity(ean, value, unit_id)
+		VALUES ($1, $2, (SELECT id FROM unit WHERE value = $3));
+	`
+	postgres.ExpectExec(batch.Queue(nutritionQuantityQuery, product.Ean, product.Nutrition.Per.Value, product.Nutrition.Per.Unit), childRowResult)
+
+	nutrientQuery := `
+		INSERT INTO nutrient(ean, type_id, value, unit_id)
+		VALUES ($1, (SELECT id FROM nutrient_type WHERE type = $2), $3, (SELECT id FROM unit WHERE value = $4));
+	`
+	for _, nutrient := range product.Nutrition.Nutrients {
+		postgres.ExpectExec(batch.Queue(nutrientQuery, product.Ean, nutrient.T, nutrient.Quantity.Value, nutrient.Quantity.Unit), childRowResult)
+	}
+
+	vitaminQuery := `
+		INSERT INTO vitamin(ean, type_id, value, unit_id)
+		VALUES ($1, (SELECT id FROM vitamin_type WHERE type = $2), $3, (SELECT id FROM unit WHERE value = $4));
+	`
+	for _, vitamin := range product.Nutrition.Vitamins {
+		postgres.ExpectExec(batch.Queue(vitaminQuery, product.Ean, vitamin.T, vitamin.Quantity.Value, vitamin.Quantity.Unit), childRowResult)
+	}
+
+	mineralQuery := `
+		INSERT INTO mineral(ean, type_id, value, unit_id)
+		VALUES ($1, (SELECT id FROM mineral_type WHERE type = $2), $3, (SELECT id FROM unit WHERE value = $4));
+	`
+	for _, mineral := range product.Nutrition.Minerals {
+		postgres.ExpectExec(batch.Queue(mineralQuery, product.Ean, mineral.T, mineral.Quantity.Value, mineral.Quantity.Unit), childRowResult)
+	}
+}
+
+func addComponentQueries(batch *pgx.Batch, product v1.Product) {
+	componentQuery := `
+		INSERT INTO product_component(ean, component_ean, count)
+		VALUES ($1, $2, $3);
+	`
+	for _, component := range product.Components {
+		postgres.ExpectExec(batch.Queue(componentQuery, product.Ean, component.Ean, component.Count), childRowResult)
+	}
+}
+
+func addServingQueries(batch *pgx.Batch, product v1.Product) {
+	servingQuery := `
+		INSERT INTO product_serving(ean, name, value, unit_id)
+		VALUES ($1, $2, $3, (SELECT id FROM unit WHERE value = $4));
+	`
+	for _, serving := range product.Servings {
+		postgres.ExpectExec(batch.Queue(servingQuery, product.Ean, serving.Name, serving.Quantity.Value, serving.Quantity.Unit), childRowResult)
+	}
+}
+
+// addUpsertProductQueries creates the product row or renames the stored one,
+// then writes the child rows. When created is not nil it is set to whether
+// the product row was inserted.
+func addUpsertProductQueries(batch *pgx.Batch, product v1.Product, created *bool) {
+	productQuery := `
+		INSERT INTO product(ean, name)
+		VALUES ($1, $2)
+		ON CONFLICT (ean) DO UPDATE SET name = EXCLUDED.name
+	`
+	if created == nil {
+		postgres.ExpectExec(batch.Queue(productQuery, product.Ean, product.Name), childRowResult)
+	} else {
+		batch.Queue(productQuery+` RETURNING (xmax = 0) AS created`, product.Ean, product.Name).QueryRow(func(row pgx.Row) error {
+			return childRowResult(pgconn.CommandTag{}, row.Scan(created))
+		})
+	}
+
+	addUpsertChildQueries(batch, product)
+}
+
+// addUpsertChildQueries writes the rows of the product over the stored ones.
+// Rows are upserted on their keys and rows the product no longer has are
+// removed, so an unchanged product is left as it is.
+func addUpsertChildQueries(batch *pgx.Batch, product v1.Product) {
+	packagingQuery := `
+		INSERT INTO packaging(ean, value, unit_id)
+		VALUES ($1, $2, (SELECT id FROM unit WHERE value = $3))
+		ON CONFLICT (ean) DO UPDATE SET value = EXCLUDED.value, unit_id = EXCLUDED.unit_id;
+	`
+	postgres.ExpectExec(batch.Queue(packagingQuery, product.Ean, product.Packaging.Value, product.Packaging.Unit), childRowResult)
+
+	addUpsertNutritionQueries(batch, product)
+
+	components := array.MapArray(product.Components, func(component v1.Component) string {
+		return component.Ean
+	})
+	batch.Queue(`DELETE FROM product_component WHERE ean = $1 AND component_ean <> ALL($2)`, product.Ean, components)
+	componentQuery := `
+		INSERT INTO product_component(ean, component_ean, count)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (ean, component_ean) DO UPDATE SET count = EXCLUDED.count;
+	`
+	for _, component := range product.Components {
+		postgres.ExpectExec(batch.Queue(componentQuery, product.Ean, component.Ean, component.Count), childRowResult)
+	}
+
+	servings := array.MapArray(product.Servings, func(serving v1.Serving) string {
+		return serving.Name
+	})
+	batch.Queue(`DELETE FROM product_serving WHERE ean = $1 AND name <> ALL($2)`, product.Ean, servings)
+	servingQuery := `
+		INSERT INTO product_serving(ean, name, value, unit_id)
+		VALUES ($1, $2, $3, (SELECT id FROM unit WHERE value = $4))
+		ON CONFLICT (ean, name) DO UPDATE SET value = EXCLUDED.value, unit_id = EXCLUDED.unit_id;
+	`
+	for _, serving := range product.Servings {
+		postgres.ExpectExec(batch.Queue(servingQuery, product.Ean, serving.Name, serving.Quantity.Value, serving.Quantity.Unit), childRowResult)
+	}
+}
+
+func addUpsertNutritionQueries(batch *pgx.Batch, product v1.Product) {
+	if product.Nutrition.IsEmpty() {
+		for _, table := range []string{"nutrition", "nutrition_quantity", "nutrient", "vitamin", "mineral"} {
+			batch.Queue(`DELETE FROM `+table+` WHERE ean = $1`, product.Ean)
+		}
+		return
+	}
+
+	nutritionQuery := `
+		INSERT INTO nutrition(ean, kcal)
+		VALUES ($1, $2)
+		ON CONFLICT (ean) DO UPDATE SET kcal = EXCLUDED.kcal;
+	`
+	postgres.ExpectExec(batch.Queue(nutritionQuery, product.Ean, product.Nutrition.Kcal), childRowResult)
 
 	nutritionQuantityQuery := `
-		SELECT
-		nutrition_quantity.ean,
-		nutrition_quantity.value,
-		unit.value
-		FROM nutrition_quantity
-		JOIN unit ON unit.id = unit_id
-		WHERE nutrition_quantity.ean = $1
-	`
-	batch.Queue(nutritionQuantityQuery, ean)
-
-	nutrientsQuery := `
-		SELECT
-		nutrient.ean,
-		nutrient_type.type,
-		nutrient.value,
-		unit.value
-		FROM nutrient
-		JOIN unit ON unit.id = unit_id
-		JOIN nutrient_type ON nutrient.type_id = nutrient_type.id
-		WHERE nutrient.ean = $1
-    `
-	batch.Queue(nutrientsQuery, ean)
-
-	vitaminsQuery := `
-		SELECT
-		vitamin.ean,
-		vitamin_type.type,
-		vitamin.value,
-		unit.value
-		FROM vitamin
-		JOIN unit ON unit.id = unit_id
-		JOIN vitamin_type ON vitamin.type_id = vitamin_type.id
-		WHERE vitamin.ean = $1
-	`
-	batch.Queue(vitaminsQuery, ean)
-
-	mineralsQuery := `
-		SELECT
-		mineral.ean,
-		mineral_type.type,
-		mineral.value,
-		unit.value
-		FROM mineral
-		JOIN unit ON unit.id = unit_id
-		JOIN mineral_type ON mineral.type_id = mineral_type.id
-		WHERE mineral.ean = $1
-	`
-	batch.Queue(mineralsQuery, ean)
+		INSERT INTO nutrition_quantity(ean, value, unit_id)
+		VALUES ($1, $2, (SELECT id FROM unit WHERE value = $3))
+		ON CONFLICT (ean) DO UPDATE SET value = EXCLUDED.value, unit_id = EXCLUDED.unit_id;
+	`
+	postgres.ExpectExec(batch.Queue(nutritionQuantityQuery, product.Ean, product.Nutrition.Per.Value, product.Nutrition.Per.Unit), childRowResult)
+
+	nutrients := array.MapArray(product.Nutrition.Nutrients, func(nutrient v1.Nutrient) string {
+		return nutrient.T
+	})
+	addUpsertTypedQueries(batch, "nutrient", product.Ean, nutrients, array.MapArray(product.Nutrition.Nutrients, func(nutrient v1.Nutrient) v1.Quantity {
+		return nutrient.Quantity
+	}))
+
+	vitamins := array.MapArray(product.Nutrition.Vitamins, func(vitamin v1.Vitamin) string {
+		return vitamin.T
+	})
+	addUpsertTypedQueries(batch, "vitamin", product.Ean, vitamins, array.MapArray(product.Nutrition.Vitamins, func(vitamin v1.Vitamin) v1.Quantity {
+		return vitamin.Quantity
+	}))
+
+	minerals := array.MapArray(product.Nutrition.Minerals, func(mineral v1.Mineral) string {
+		return mineral.T
+	})
+	addUpsertTypedQueries(batch, "mineral", product.Ean, minerals, array.MapArray(product.Nutrition.Minerals, func(mineral v1.Mineral) v1.Quantity {
+		return mineral.Quantity
+	}))
+}
+
+// addUpsertTypedQueries upserts the nutrient, vitamin or mineral rows given by
+// table, types and quantities are matched by index.
+func addUpsertTypedQueries(batch *pgx.Batch, table string, ean string, types []string, quantities []v1.Quantity) {
+	deleteQuery := `
+		DELETE FROM ` + table + `
+		WHERE ean = $1 AND type_id NOT IN (SELECT id FROM ` + table + `_type WHERE type = ANY($2))
+	`
+	batch.Queue(deleteQuery, ean, types)
+
+	upsertQuery := `
+		INSERT INTO ` + table + `(ean, type_id, value, unit_id)
+		VALUES ($1, (SELECT id FROM ` + table + `_type WHERE type = $2), $3, (SELECT id FROM unit WHERE value = $4))
+		ON CONFLICT (ean, type_id) DO UPDATE SET value = EXCLUDED.value, unit_id = EXCLUDED.unit_id;
+	`
+	for index, t := range types {
+		postgres.ExpectExec(batch.Queue(upsertQuery, ean, t, quantities[index].Value, quantities[index].Unit), childRowResult)
+	}
 }
@@ -0,0 +1,532 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	v1 "github.com/Kobietka/product-service/pkg/api/v1"
+	"github.com/Kobietka/product-service/pkg/array"
+	"github.com/Kobietka/product-service/pkg/sqlite"
+	"math"
+)
+
+// SqliteStore keeps products in a SQLite database opened with sqlite.Open,
+// its schema mirrors the Postgres one so the stores fail the same writes.
+type SqliteStore struct {
+	db *sql.DB
+}
+
+func NewSqliteStore(db *sql.DB) SqliteStore {
+	return SqliteStore{db}
+}
+
+func (s SqliteStore) GetProduct(ctx context.Context, ean string) (v1.Product, error) {
+	products, err := s.GetProducts(ctx, []string{ean})
+	if err != nil {
+		return v1.Product{}, err
+	}
+	product, ok := products[ean]
+	if !ok {
+		return v1.Product{}, v1.ErrorDataNotFound
+	}
+	return product, nil
+}
+
+// GetProducts reads the products in one transaction, so a concurrent write is
+// seen whole or not at all.
+func (s SqliteStore) GetProducts(ctx context.Context, eans []string) (map[string]v1.Product, error) {
+	var products map[string]v1.Product
+	err := s.inTransaction(ctx, func(tx *sql.Tx) error {
+		var err error
+		products, err = readSqliteProducts(ctx, tx, eans)
+		return err
+	})
+	return products, err
+}
+
+// SearchProducts matches names like PostgresStore does. LIKE has no default
+// escape character in SQLite, the backslash of Postgres is set explicitly.
+func (s SqliteStore) SearchProducts(ctx context.Context, query string, limit int8) ([]v1.Product, error) {
+	productsSearchQuery := `
+		SELECT ean
+		FROM product
+		WHERE LOWER(name) LIKE LOWER($1) ESCAPE '\'
+		ORDER BY ean
+		LIMIT $2
+	`
+	var products []v1.Product
+	err := s.inTransaction(ctx, func(tx *sql.Tx) error {
+		eans, err := sqlite.CollectRows[struct{ Ean string }](tx.QueryContext(ctx, productsSearchQuery, "%"+query+"%", limit))
+		if err != nil {
+			return err
+		}
+
+		found, err := readSqliteProducts(ctx, tx, array.MapArray(eans, func(row struct{ Ean string }) string {
+			return row.Ean
+		}))
+		if err != nil {
+			return err
+		}
+
+		products = make([]v1.Product, 0, len(found))
+		for _, row := range eans {
+			if product, ok := found[row.Ean]; ok {
+				products = append(products, product)
+			}
+		}
+		return nil
+	})
+	return products, err
+}
+
+func (s SqliteStore) CreateProduct(ctx context.Context, product v1.Product) error {
+	return s.inTransaction(ctx, func(tx *sql.Tx) error {
+		productQuery := `
+			INSERT INTO product(ean, name)
+			VALUES ($1, $2);
+		`
+		_, err := tx.ExecContext(ctx, productQuery, product.Ean, product.Name)
+		if sqlite.IsDuplicate(err) {
+			return v1.ErrorProductAlreadyExists
+		}
+		if err != nil {
+			return sqliteRowError(err)
+		}
+
+		return insertSqliteChildRows(ctx, tx, product)
+	})
+}
+
+func (s SqliteStore) UpdateProduct(ctx context.Context, product v1.Product) error {
+	return s.inTransaction(ctx, func(tx *sql.Tx) error {
+		productQuery := `
+			UPDATE product
+			SET name = $2
+			WHERE ean = $1
+		`
+		result, err := tx.ExecContext(ctx, productQuery, product.Ean, product.Name)
+		if err != nil {
+			return sqliteRowError(err)
+		}
+		affected, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if affected == 0 {
+			return v1.ErrorProductDoesNotExist
+		}
+
+		if err := deleteSqliteChildRows(ctx, tx, product.Ean); err != nil {
+			return err
+		}
+		return insertSqliteChildRows(ctx, tx, product)
+	})
+}
+
+// UpsertProduct replaces the child rows of an existing product in the same
+// transaction, the write is atomic like the ON CONFLICT upsert of
+// PostgresStore.
+func (s SqliteStore) UpsertProduct(ctx context.Context, product v1.Product) (bool, error) {
+	var created bool
+	err := s.inTransaction(ctx, func(tx *sql.Tx) error {
+		var err error
+		created, err = upsertSqliteProduct(ctx, tx, product)
+		return err
+	})
+	return created, err
+}
+
+func (s SqliteStore) UpsertProducts(ctx context.Context, products []v1.Product) error {
+	return s.inTransaction(ctx, func(tx *sql.Tx) error {
+		for _, product := range products {
+			if _, err := upsertSqliteProduct(ctx, tx, product); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (s SqliteStore) DeleteProduct(ctx context.Context, ean string) error {
+	return s.inTransaction(ctx, func(tx *sql.Tx) error {
+		// ON DELETE RESTRICT fails as a trigger constraint in SQLite, not as a
+		// foreign key one. Components are the only rows restricting a delete.
+		result, err := tx.ExecContext(ctx, `DELETE FROM product WHERE ean = $1`, ean)
+		if sqlite.IsConstraintViolation(err) {
+			return v1.ErrorProductInUse
+		}
+		if err != nil {
+			return err
+		}
+
+		affected, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if affected == 0 {
+			return v1.ErrorProductDoesNotExist
+		}
+		return nil
+	})
+}
+
+func (s SqliteStore) inTransaction(ctx context.Context, work func(tx *sql.Tx) error) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	if err := work(tx); err != nil {
+		return errors.Join(err, tx.Rollback())
+	}
+	return tx.Commit()
+}
+
+func upsertSqliteProduct(ctx context.Context, tx *sql.Tx, product v1.Product) (bool, error) {
+	var exists bool
+	if err := tx.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM product WHERE ean = $1)`, product.Ean).Scan(&exists); err != nil {
+		return false, err
+	}
+
+	productQuery := `
+		INSERT INTO product(ean, name)
+		VALUES ($1, $2)
+		ON CONFLICT (ean) DO UPDATE SET name = excluded.name;
+	`
+	if _, err := tx.ExecContext(ctx, productQuery, product.Ean, product.Name); err != nil {
+		return false, sqliteRowError(err)
+	}
+
+	if err := deleteSqliteChildRows(ctx, tx, product.Ean); err != nil {
+		return false, err
+	}
+	return !exists, insertSqliteChildRows(ctx, tx, product)
+}
+
+func deleteSqliteChildRows(ctx context.Context, tx *sql.Tx, ean string) error {
+	for _, table := range childTables {
+		if _, err := tx.ExecContext(ctx, `DELETE FROM `+table+` WHERE ean = $1`, ean); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// insertSqliteChildRows writes the rows PostgresStore queues in
+// addPackagingQueries and the functions after it. The nutrition quantity is
+// rounded to even before it is stored, like a Postgres cast to integer does.
+func insertSqliteChildRows(ctx context.Context, tx *sql.Tx, product v1.Product) error {
+	exec := func(query string, args ...any) error {
+		_, err := tx.ExecContext(ctx, query, args...)
+		return sqliteRowError(err)
+	}
+
+	packagingQuery := `
+		INSERT INTO packaging(ean, value, unit_id)
+		VALUES ($1, $2, (SELECT id FROM unit WHERE value = $3));
+	`
+	if err := exec(packagingQuery, product.Ean, product.Packaging.Value, product.Packaging.Unit); err != nil {
+		return err
+	}
+
+	if !product.Nutrition.IsEmpty() {
+		nutritionQuery := `
+			INSERT INTO nutrition(ean, kcal)
+			VALUES ($1, $2);
+		`
+		if err := exec(nutritionQuery, product.Ean, product.Nutrition.Kcal); err != nil {
+			return err
+		}
+
+		nutritionQuantityQuery := `
+			INSERT INTO nutrition_quantity(ean, value, unit_id)
+			VALUES ($1, $2, (SELECT id FROM unit WHERE value = $3));
+		`
+		per := int64(math.RoundToEven(float64(product.Nutrition.Per.Value)))
+		if err := exec(nutritionQuantityQuery, product.Ean, per, product.Nutrition.Per.Unit); err != nil {
+			return err
+		}
+
+		nutrientQuery := `
+			INSERT INTO nutrient(ean, type_id, value, unit_id)
+			VALUES ($1, (SELECT id FROM nutrient_type WHERE type = $2), $3, (SELECT id FROM unit WHERE value = $4));
+		`
+		for _, nutrient := range product.Nutrition.Nutrients {
+			if err := exec(nutrientQuery, product.Ean, nutrient.T, nutrient.Quantity.Value, nutrient.Quantity.Unit); err != nil {
+				return err
+			}
+		}
+
+		vitaminQuery := `
+			INSERT INTO vitamin(ean, type_id, value, unit_id)
+			VALUES ($1, (SELECT id FROM vitamin_type WHERE type = $2), $3, (SELECT id FROM unit WHERE value = $4));
+		`
+		for _, vitamin := range product.Nutrition.Vitamins {
+			if err := exec(vitaminQuery, product.Ean, vitamin.T, vitamin.Quantity.Value, vitamin.Quantity.Unit); err != nil {
+				return err
+			}
+		}
+
+		mineralQuery := `
+			INSERT INTO mineral(ean, type_id, value, unit_id)
+			VALUES ($1, (SELECT id FROM mineral_type WHERE type = $2), $3, (SELECT id FROM unit WHERE value = $4));
+		`
+		for _, mineral := range product.Nutrition.Minerals {
+			if err := exec(mineralQuery, product.Ean, mineral.T, mineral.Quantity.Value, mineral.Quantity.Unit); err != nil {
+				return err
+			}
+		}
+	}
+
+	componentQuery := `
+		INSERT INTO product_component(ean, component_ean, count)
+		VALUES ($1, $2, $3);
+	`
+	for _, component := range product.Components {
+		if err := exec(componentQuery, product.Ean, component.Ean, component.Count); err != nil {
+			return err
+		}
+	}
+
+	servingQuery := `
+		INSERT INTO product_serving(ean, name, value, unit_id)
+		VALUES ($1, $2, $3, (SELECT id FROM unit WHERE value = $4));
+	`
+	for _, serving := range product.Servings {
+		if err := exec(servingQuery, product.Ean, serving.Name, serving.Quantity.Value, serving.Quantity.Unit); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// sqliteRowError reports constraint violations as invalid data, like
+// childRowResult does for PostgresStore.
+func sqliteRowError(err error) error {
+	if sqlite.IsConstraintViolation(err) {
+		return v1.ErrorInvalidData
+	}
+	return err
+}
+
+// The queries reading products take the EANs as a JSON array, every table is
+// read once however many products are read.
+const (
+	selectSqliteProductsQuery = `
+		SELECT ean, name
+		FROM product
+		WHERE ean IN (SELECT value FROM json_each($1))
+	`
+	selectSqlitePackagingQuery = `
+		SELECT
+		packaging.ean,
+		packaging.value,
+		unit.value
+		FROM packaging
+		JOIN unit ON unit.id = unit_id
+		WHERE packaging.ean IN (SELECT value FROM json_each($1))
+	`
+	selectSqliteNutritionQuery = `
+		SELECT
+		ean,
+		kcal
+		FROM nutrition
+		WHERE ean IN (SELECT value FROM json_each($1))
+	`
+	selectSqliteNutritionQuantityQuery = `
+		SELECT
+		nutrition_quantity.ean,
+		nutrition_quantity.value,
+		unit.value
+		FROM nutrition_quantity
+		JOIN unit ON unit.id = unit_id
+		WHERE nutrition_quantity.ean IN (SELECT value FROM json_each($1))
+	`
+	selectSqliteNutrientsQuery = `
+		SELECT
+		nutrient.ean,
+		nutrient_type.type,
+		nutrient.value,
+		unit.value
+		FROM nutrient
+		JOIN unit ON unit.id = unit_id
+		JOIN nutrient_type ON nutrient.type_id = nutrient_type.id
+		WHERE nutrient.ean IN (SELECT value FROM json_each($1))
+		ORDER BY nutrient.ean, nutrient_type.type
+	`
+	selectSqliteVitaminsQuery = `
+		SELECT
+		vitamin.ean,
+		vitamin_type.type,
+		vitamin.value,
+		unit.value
+		FROM vitamin
+		JOIN unit ON unit.id = unit_id
+		JOIN vitamin_type ON vitamin.type_id = vitamin_type.id
+		WHERE vitamin.ean IN (SELECT value FROM json_each($1))
+		ORDER BY vitamin.ean, vitamin_type.type
+	`
+	selectSqliteMineralsQuery = `
+		SELECT
+		mineral.ean,
+		mineral_type.type,
+		mineral.value,
+		unit.value
+		FROM mineral
+		JOIN unit ON unit.id = unit_id
+		JOIN mineral_type ON mineral.type_id = mineral_type.id
+		WHERE mineral.ean IN (SELECT value FROM json_each($1))
+		ORDER BY mineral.ean, mineral_type.type
+	`
+	selectSqliteComponentsQuery = `
+		SELECT
+		ean,
+		component_ean,
+		count
+		FROM product_component
+		WHERE ean IN (SELECT value FROM json_each($1))
+		ORDER BY ean, component_ean
+	`
+	selectSqliteServingsQuery = `
+		SELECT
+		product_serving.ean,
+		product_serving.name,
+		product_serving.value,
+		unit.value
+		FROM product_serving
+		JOIN unit ON unit.id = unit_id
+		WHERE product_serving.ean IN (SELECT value FROM json_each($1))
+		ORDER BY product_serving.ean, product_serving.value, product_serving.name
+	`
+)
+
+// readSqliteProducts reads the products with a query per table, a product
+// without packaging is not found.
+func readSqliteProducts(ctx context.Context, tx *sql.Tx, eans []string) (map[string]v1.Product, error) {
+	eansJson, err := json.Marshal(eans)
+	if err != nil {
+		return nil, err
+	}
+	query := func(query string) (*sql.Rows, error) {
+		return tx.QueryContext(ctx, query, string(eansJson))
+	}
+
+	productEntities, err := sqlite.CollectRows[productEntity](query(selectSqliteProductsQuery))
+	if err != nil {
+		return nil, err
+	}
+	packagingEntities, err := sqlite.CollectRows[packagingEntity](query(selectSqlitePackagingQuery))
+	if err != nil {
+		return nil, err
+	}
+	nutritionEntities, err := sqlite.CollectRows[nutritionEntity](query(selectSqliteNutritionQuery))
+	if err != nil {
+		return nil, err
+	}
+	nutritionQuantityEntities, err := sqlite.CollectRows[nutritionQuantityEntity](query(selectSqliteNutritionQuantityQuery))
+	if err != nil {
+		return nil, err
+	}
+	nutrientEntities, err := sqlite.CollectRows[nutrientEntity](query(selectSqliteNutrientsQuery))
+	if err != nil {
+		return nil, err
+	}
+	vitaminEntities, err := sqlite.CollectRows[vitaminEntity](query(selectSqliteVitaminsQuery))
+	if err != nil {
+		return nil, err
+	}
+	mineralEntities, err := sqlite.CollectRows[mineralEntity](query(selectSqliteMineralsQuery))
+	if err != nil {
+		return nil, err
+	}
+	componentEntities, err := sqlite.CollectRows[componentEntity](query(selectSqliteComponentsQuery))
+	if err != nil {
+		return nil, err
+	}
+	servingEntities, err := sqlite.CollectRows[servingEntity](query(selectSqliteServingsQuery))
+	if err != nil {
+		return nil, err
+	}
+
+	packagings := byEan(packagingEntities, func(entity packagingEntity) string { return entity.Ean })
+	nutritions := byEan(nutritionEntities, func(entity nutritionEntity) string { return entity.Ean })
+	nutritionQuantities := byEan(nutritionQuantityEntities, func(entity nutritionQuantityEntity) string { return entity.Ean })
+	nutrients := byEan(nutrientEntities, func(entity nutrientEntity) string { return entity.Ean })
+	vitamins := byEan(vitaminEntities, func(entity vitaminEntity) string { return entity.Ean })
+	minerals := byEan(mineralEntities, func(entity mineralEntity) string { return entity.Ean })
+	components := byEan(componentEntities, func(entity componentEntity) string { return entity.Ean })
+	servings := byEan(servingEntities, func(entity servingEntity) string { return entity.Ean })
+
+	products := make(map[string]v1.Product, len(productEntities))
+	for _, productE := range productEntities {
+		packagingE, ok := packagings[productE.Ean]
+		if !ok {
+			continue
+		}
+		nutritionE := first(nutritions[productE.Ean])
+		nutritionQuantityE := first(nutritionQuantities[productE.Ean])
+
+		products[productE.Ean] = v1.Product{
+			Ean:  productE.Ean,
+			Name: productE.Name,
+			Packaging: v1.Quantity{
+				Value: packagingE[0].Value,
+				Unit:  packagingE[0].Unit,
+			},
+			Nutrition: v1.Nutrition{
+				Per: v1.Quantity{
+					Value: nutritionQuantityE.Value,
+					Unit:  nutritionQuantityE.Unit,
+				},
+				Kcal: nutritionE.Kcal,
+				Nutrients: array.MapArray(nutrients[productE.Ean], func(entity nutrientEntity) v1.Nutrient {
+					return v1.Nutrient{T: entity.Type, Quantity: v1.Quantity{Value: entity.Value, Unit: entity.Unit}}
+				}),
+				Vitamins: array.MapArray(vitamins[productE.Ean], func(entity vitaminEntity) v1.Vitamin {
+					return v1.Vitamin{T: entity.Type, Quantity: v1.Quantity{Value: entity.Value, Unit: entity.Unit}}
+				}),
+				Minerals: array.MapArray(minerals[productE.Ean], func(entity mineralEntity) v1.Mineral {
+					return v1.Mineral{T: entity.Type, Quantity: v1.Quantity{Value: entity.Value, Unit: entity.Unit}}
+				}),
+			},
+			Components: array.MapArray(components[productE.Ean], toComponent),
+			Servings:   array.MapArray(servings[productE.Ean], toServing),
+		}
+	}
+	return products, nil
+}
+
+// byEan groups the rows by the EAN of their product, keeping their order.
+func byEan[T any](entities []T, ean func(T) string) map[string][]T {
+	grouped := make(map[string][]T)
+	for _, entity := range entities {
+		grouped[ean(entity)] = append(grouped[ean(entity)], entity)
+	}
+	return grouped
+}
+
+func first[T any](entities []T) T {
+	if len(entities) == 0 {
+		return *new(T)
+	}
+	return entities[0]
+}
+
+func toComponent(entity componentEntity) v1.Component {
+	return v1.Component{
+		Ean:   entity.ComponentEan,
+		Count: entity.Count,
+	}
+}
+
+func toServing(entity servingEntity) v1.Serving {
+	return v1.Serving{
+		Name: entity.Name,
+		Quantity: v1.Quantity{
+			Value: entity.Value,
+			Unit:  entity.Unit,
+		},
+	}
+}
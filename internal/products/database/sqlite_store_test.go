@@ -0,0 +1,183 @@
+package database
+
+import (
+	"context"
+	"github.com/Kobietka/product-service/internal/database/setup"
+	v1 "github.com/Kobietka/product-service/pkg/api/v1"
+	"github.com/Kobietka/product-service/pkg/sqlite"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func newTestSqliteStore(t *testing.T, products ...v1.Product) SqliteStore {
+	db, err := sqlite.Open("sqlite://:memory:")
+	assert.NoError(t, err)
+	t.Cleanup(func() {
+		db.Close()
+	})
+
+	seeder := setup.NewSqliteSeeder(db)
+	assert.NoError(t, seeder.CreateSchema(context.Background()))
+	assert.NoError(t, seeder.Seed(context.Background()))
+
+	store := NewSqliteStore(db)
+	for _, product := range products {
+		assert.NoError(t, store.CreateProduct(context.Background(), product))
+	}
+	return store
+}
+
+func TestSqliteStoreGetProduct(t *testing.T) {
+	product := oatBar
+	product.Nutrition.Per.Value = 100.5
+	product.Nutrition.Vitamins = []v1.Vitamin{{T: "VITAMIN_E", Quantity: v1.Quantity{Value: 1.5, Unit: "mg"}}}
+	product.Servings = []v1.Serving{
+		{Name: "2 bars", Quantity: v1.Quantity{Value: 80, Unit: "g"}},
+		{Name: "1 bar", Quantity: v1.Quantity{Value: 40, Unit: "g"}},
+	}
+	sticker := v1.Product{Ean: "12345670", Name: "Sticker", Packaging: v1.Quantity{Value: 1, Unit: "pcs"}}
+	multipack := v1.Product{
+		Ean:        "4006381333948",
+		Name:       "Oat Bar Multipack",
+		Packaging:  v1.Quantity{Value: 240, Unit: "g"},
+		Components: []v1.Component{{Ean: oatBar.Ean, Count: 6}, {Ean: sticker.Ean, Count: 1}},
+	}
+	store := newTestSqliteStore(t, product, sticker, multipack)
+
+	stored, err := store.GetProduct(context.Background(), oatBar.Ean)
+	assert.NoError(t, err)
+	assert.Equal(t, float32(100), stored.Nutrition.Per.Value)
+	assert.Equal(t, []v1.Nutrient{product.Nutrition.Nutrients[1], product.Nutrition.Nutrients[0], product.Nutrition.Nutrients[2]}, stored.Nutrition.Nutrients, "nutrients are ordered by type")
+	assert.Equal(t, product.Nutrition.Vitamins, stored.Nutrition.Vitamins)
+	assert.Equal(t, []v1.Mineral{}, stored.Nutrition.Minerals)
+	assert.Equal(t, []v1.Serving{product.Servings[1], product.Servings[0]}, stored.Servings)
+
+	stored, err = store.GetProduct(context.Background(), multipack.Ean)
+	assert.NoError(t, err)
+	assert.Equal(t, v1.Product{
+		Ean:        multipack.Ean,
+		Name:       multipack.Name,
+		Packaging:  multipack.Packaging,
+		Nutrition:  v1.Nutrition{Nutrients: []v1.Nutrient{}, Vitamins: []v1.Vitamin{}, Minerals: []v1.Mineral{}},
+		Components: []v1.Component{{Ean: sticker.Ean, Count: 1}, {Ean: oatBar.Ean, Count: 6}},
+		Servings:   []v1.Serving{},
+	}, stored)
+
+	_, err = store.GetProduct(context.Background(), "87654321")
+	assert.ErrorIs(t, err, v1.ErrorDataNotFound)
+}
+
+func TestSqliteStoreCreateProductErrors(t *testing.T) {
+	tests := []struct {
+		Name        string
+		Product     v1.Product
+		ExpectedErr error
+	}{
+		{Name: "product already exists", Product: oatBar, ExpectedErr: v1.ErrorProductAlreadyExists},
+		{Name: "unknown unit", Product: v1.Product{Ean: "12345678", Packaging: v1.Quantity{Value: 1, Unit: "oz"}}, ExpectedErr: v1.ErrorInvalidData},
+		{
+			Name: "unknown nutrient type",
+			Product: v1.Product{Ean: "12345678", Packaging: v1.Quantity{Value: 1, Unit: "g"}, Nutrition: v1.Nutrition{
+				Per:       v1.Quantity{Value: 100, Unit: "g"},
+				Nutrients: []v1.Nutrient{{T: "CAFFEINE", Quantity: v1.Quantity{Value: 1, Unit: "g"}}},
+			}},
+			ExpectedErr: v1.ErrorInvalidData,
+		},
+		{
+			Name:        "missing component",
+			Product:     v1.Product{Ean: "12345678", Packaging: v1.Quantity{Value: 1, Unit: "g"}, Components: []v1.Component{{Ean: "87654321", Count: 1}}},
+			ExpectedErr: v1.ErrorInvalidData,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			store := newTestSqliteStore(t, oatBar)
+
+			err := store.CreateProduct(context.Background(), test.Product)
+			assert.ErrorIs(t, err, test.ExpectedErr)
+			if test.Product.Ean != oatBar.Ean {
+				_, err = store.GetProduct(context.Background(), test.Product.Ean)
+				assert.ErrorIs(t, err, v1.ErrorDataNotFound)
+			}
+		})
+	}
+}
+
+func TestSqliteStoreSearchProducts(t *testing.T) {
+	store := newTestSqliteStore(t,
+		v1.Product{Ean: "44444444", Name: "Barley", Packaging: v1.Quantity{Value: 1, Unit: "kg"}},
+		v1.Product{Ean: "11111111", Name: "Oat Bar", Packaging: v1.Quantity{Value: 40, Unit: "g"}},
+		v1.Product{Ean: "22222222", Name: "Żubr", Packaging: v1.Quantity{Value: 500, Unit: "ml"}},
+		v1.Product{Ean: "33333333", Name: "Milk 100%", Packaging: v1.Quantity{Value: 1, Unit: "l"}},
+	)
+
+	tests := []struct {
+		Name         string
+		Query        string
+		Limit        int8
+		ExpectedEans []string
+	}{
+		{Name: "case-insensitive by ean", Query: "BAR", Limit: 15, ExpectedEans: []string{"11111111", "44444444"}},
+		{Name: "non-ascii case-insensitive", Query: "żUBR", Limit: 15, ExpectedEans: []string{"22222222"}},
+		{Name: "limited", Query: "bar", Limit: 1, ExpectedEans: []string{"11111111"}},
+		{Name: "escaped wildcard", Query: `0\%`, Limit: 15, ExpectedEans: []string{"33333333"}},
+		{Name: "no match", Query: "bread", Limit: 15, ExpectedEans: []string{}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			products, err := store.SearchProducts(context.Background(), test.Query, test.Limit)
+			assert.NoError(t, err)
+			eans := make([]string, 0)
+			for _, product := range products {
+				eans = append(eans, product.Ean)
+			}
+			assert.Equal(t, test.ExpectedEans, eans)
+		})
+	}
+}
+
+func TestSqliteStoreWrites(t *testing.T) {
+	ctx := context.Background()
+	multipack := v1.Product{
+		Ean:        "4006381333948",
+		Name:       "Oat Bar Multipack",
+		Packaging:  v1.Quantity{Value: 240, Unit: "g"},
+		Components: []v1.Component{{Ean: oatBar.Ean, Count: 6}},
+	}
+	store := newTestSqliteStore(t, oatBar)
+
+	assert.ErrorIs(t, store.UpdateProduct(ctx, multipack), v1.ErrorProductDoesNotExist)
+
+	created, err := store.UpsertProduct(ctx, multipack)
+	assert.NoError(t, err)
+	assert.True(t, created)
+
+	multipack.Components[0].Count = 12
+	created, err = store.UpsertProduct(ctx, multipack)
+	assert.NoError(t, err)
+	assert.False(t, created)
+	stored, err := store.GetProduct(ctx, multipack.Ean)
+	assert.NoError(t, err)
+	assert.Equal(t, multipack.Components, stored.Components)
+
+	withoutNutrition := oatBar
+	withoutNutrition.Nutrition = v1.Nutrition{}
+	assert.NoError(t, store.UpdateProduct(ctx, withoutNutrition))
+	stored, err = store.GetProduct(ctx, oatBar.Ean)
+	assert.NoError(t, err)
+	assert.True(t, stored.Nutrition.IsEmpty())
+
+	invalid := withoutNutrition
+	invalid.Packaging.Unit = "oz"
+	assert.ErrorIs(t, store.UpdateProduct(ctx, invalid), v1.ErrorInvalidData)
+	stored, err = store.GetProduct(ctx, oatBar.Ean)
+	assert.NoError(t, err)
+	assert.Equal(t, oatBar.Packaging, stored.Packaging)
+
+	assert.ErrorIs(t, store.DeleteProduct(ctx, oatBar.Ean), v1.ErrorProductInUse)
+	assert.NoError(t, store.DeleteProduct(ctx, multipack.Ean))
+	assert.NoError(t, store.DeleteProduct(ctx, oatBar.Ean))
+	assert.ErrorIs(t, store.DeleteProduct(ctx, oatBar.Ean), v1.ErrorProductDoesNotExist)
+}
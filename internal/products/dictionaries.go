@@ -0,0 +1,66 @@
+package products
+
+import (
+	"errors"
+	"github.com/Kobietka/product-service/internal/types"
+	v1 "github.com/Kobietka/product-service/pkg/api/v1"
+	"github.com/Kobietka/product-service/pkg/text"
+)
+
+var (
+	ErrorQuantityUnitUnknown = errors.New("QUANTITY_UNIT_UNKNOWN")
+	ErrorNutrientTypeUnknown = errors.New("NUTRIENT_TYPE_UNKNOWN")
+	ErrorVitaminTypeUnknown  = errors.New("VITAMIN_TYPE_UNKNOWN")
+	ErrorMineralTypeUnknown  = errors.New("MINERAL_TYPE_UNKNOWN")
+)
+
+// validateDictionaries checks units and nutrient, vitamin and mineral types
+// against the dictionaries the database references. Blank values are left to
+// the other rules.
+func validateDictionaries(product v1.Product, dictionaries types.Dictionaries) Violations {
+	var violations Violations
+	violations.merge(validateUnit("/packaging", product.Packaging, dictionaries))
+	for i, serving := range product.Servings {
+		violations.merge(validateUnit(pointer("/servings", i, "quantity"), serving.Quantity, dictionaries))
+	}
+
+	if len(product.Components) > 0 && product.Nutrition.IsEmpty() {
+		return violations
+	}
+
+	nutrition := product.Nutrition
+	violations.merge(validateUnit("/nutrition/per", nutrition.Per, dictionaries))
+	for i, nutrient := range nutrition.Nutrients {
+		path := pointer("/nutrition/nutrients", i)
+		violations.merge(validateType(path, nutrient.T, dictionaries.NutrientTypes, ErrorNutrientTypeUnknown))
+		violations.merge(validateUnit(pointer(path, "quantity"), nutrient.Quantity, dictionaries))
+	}
+	for i, vitamin := range nutrition.Vitamins {
+		path := pointer("/nutrition/vitamins", i)
+		violations.merge(validateType(path, vitamin.T, dictionaries.VitaminTypes, ErrorVitaminTypeUnknown))
+		violations.merge(validateUnit(pointer(path, "quantity"), vitamin.Quantity, dictionaries))
+	}
+	for i, mineral := range nutrition.Minerals {
+		path := pointer("/nutrition/minerals", i)
+		violations.merge(validateType(path, mineral.T, dictionaries.MineralTypes, ErrorMineralTypeUnknown))
+		violations.merge(validateUnit(pointer(path, "quantity"), mineral.Quantity, dictionaries))
+	}
+
+	return violations
+}
+
+func validateUnit(path string, quantity v1.Quantity, dictionaries types.Dictionaries) Violations {
+	var violations Violations
+	if _, ok := dictionaries.Units[quantity.Unit]; !ok && !text.IsBlankString(quantity.Unit) {
+		violations.add(pointer(path, "unit"), ErrorQuantityUnitUnknown, "unit", quantity.Unit)
+	}
+	return violations
+}
+
+func validateType(path string, t string, dictionary map[string]bool, err error) Violations {
+	var violations Violations
+	if !text.IsBlankString(t) && !dictionary[t] {
+		violations.add(pointer(path, "type"), err, "type", t)
+	}
+	return violations
+}
@@ -0,0 +1,210 @@
+package products
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/Kobietka/product-service/internal/types"
+	v1 "github.com/Kobietka/product-service/pkg/api/v1"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+var testDictionaries = types.Dictionaries{
+	Units: map[string]types.Dimension{
+		"kg":   types.DimensionMass,
+		"g":    types.DimensionMass,
+		"mg":   types.DimensionMass,
+		"µg":   types.DimensionMass,
+		"l":    types.DimensionVolume,
+		"ml":   types.DimensionVolume,
+		"kcal": types.DimensionEnergy,
+		"pcs":  types.DimensionCount,
+	},
+	NutrientTypes: map[string]bool{"FAT": true, "CARBOHYDRATES": true, "PROTEIN": true},
+	VitaminTypes:  map[string]bool{"VITAMIN_C": true},
+	MineralTypes:  map[string]bool{"IRON": true},
+}
+
+func TestValidateDictionaries(t *testing.T) {
+	correctNutrition := v1.Nutrition{
+		Per: v1.Quantity{Value: 100, Unit: "g"},
+		Nutrients: []v1.Nutrient{
+			{T: "FAT", Quantity: v1.Quantity{Value: 1, Unit: "g"}},
+		},
+		Vitamins: []v1.Vitamin{
+			{T: "VITAMIN_C", Quantity: v1.Quantity{Value: 1, Unit: "mg"}},
+		},
+		Minerals: []v1.Mineral{
+			{T: "IRON", Quantity: v1.Quantity{Value: 1, Unit: "mg"}},
+		},
+	}
+
+	tests := []struct {
+		Name               string
+		Product            v1.Product
+		ExpectedViolations Violations
+	}{
+		{
+			Name: "known units and types",
+			Product: v1.Product{
+				Packaging: v1.Quantity{Value: 1, Unit: "kg"},
+				Servings:  []v1.Serving{{Name: "slice", Quantity: v1.Quantity{Value: 20, Unit: "g"}}},
+				Nutrition: correctNutrition,
+			},
+			ExpectedViolations: nil,
+		},
+		{
+			Name: "blank values are left to product validation",
+			Product: v1.Product{
+				Nutrition: v1.Nutrition{
+					Nutrients: []v1.Nutrient{{T: " ", Quantity: v1.Quantity{Value: 1}}},
+				},
+			},
+			ExpectedViolations: nil,
+		},
+		{
+			Name: "multipack without nutrition",
+			Product: v1.Product{
+				Packaging:  v1.Quantity{Value: 1, Unit: "kg"},
+				Components: []v1.Component{{Ean: "12345678", Count: 2}},
+			},
+			ExpectedViolations: nil,
+		},
+		{
+			Name: "unknown units and types",
+			Product: v1.Product{
+				Packaging: v1.Quantity{Value: 1, Unit: "kilo"},
+				Servings:  []v1.Serving{{Name: "slice", Quantity: v1.Quantity{Value: 1, Unit: "slice"}}},
+				Nutrition: v1.Nutrition{
+					Per: v1.Quantity{Value: 100, Unit: "g"},
+					Nutrients: []v1.Nutrient{
+						{T: "FAT", Quantity: v1.Quantity{Value: 1, Unit: "g"}},
+						{T: "STARCH", Quantity: v1.Quantity{Value: 1, Unit: "gram"}},
+					},
+					Vitamins: []v1.Vitamin{
+						{T: "VITAMIN_X", Quantity: v1.Quantity{Value: 1, Unit: "mg"}},
+					},
+					Minerals: []v1.Mineral{
+						{T: "GOLD", Quantity: v1.Quantity{Value: 1, Unit: "mg"}},
+					},
+				},
+			},
+			ExpectedViolations: Violations{
+				{Pointer: "/packaging/unit", Err: ErrorQuantityUnitUnknown, Params: map[string]any{"unit": "kilo"}},
+				{Pointer: "/servings/0/quantity/unit", Err: ErrorQuantityUnitUnknown, Params: map[string]any{"unit": "slice"}},
+				{Pointer: "/nutrition/nutrients/1/type", Err: ErrorNutrientTypeUnknown, Params: map[string]any{"type": "STARCH"}},
+				{Pointer: "/nutrition/nutrients/1/quantity/unit", Err: ErrorQuantityUnitUnknown, Params: map[string]any{"unit": "gram"}},
+				{Pointer: "/nutrition/vitamins/0/type", Err: ErrorVitaminTypeUnknown, Params: map[string]any{"type": "VITAMIN_X"}},
+				{Pointer: "/nutrition/minerals/0/type", Err: ErrorMineralTypeUnknown, Params: map[string]any{"type": "GOLD"}},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			violations := validateDictionaries(test.Product, testDictionaries)
+			assert.Equal(t, test.ExpectedViolations, violations)
+		})
+	}
+}
+
+func TestHandlePostProductDictionaries(t *testing.T) {
+	product := v1.Product{
+		Ean:       "12345678",
+		Name:      "Product name",
+		Packaging: v1.Quantity{Value: 12, Unit: "kilo"},
+		Nutrition: v1.Nutrition{
+			Per:  v1.Quantity{Value: 100, Unit: "g"},
+			Kcal: 17,
+			Nutrients: []v1.Nutrient{
+				{T: "PROTEIN", Quantity: v1.Quantity{Value: 1, Unit: "g"}},
+				{T: "CARBOHYDRATES", Quantity: v1.Quantity{Value: 1, Unit: "g"}},
+				{T: "FAT", Quantity: v1.Quantity{Value: 1, Unit: "g"}},
+			},
+		},
+	}
+
+	tests := []struct {
+		Name         string
+		TypesErr     error
+		ExpectedCode int
+		ExpectedBody *v1.ErrorResponse
+	}{
+		{
+			Name:         "unknown unit",
+			ExpectedCode: http.StatusBadRequest,
+			ExpectedBody: &v1.ErrorResponse{Code: ErrorQuantityUnitUnknown.Error(), Violations: []v1.Violation{
+				{Pointer: "/packaging/unit", Code: ErrorQuantityUnitUnknown.Error(), Params: map[string]any{"unit": "kilo"}},
+			}},
+		},
+		{
+			Name:         "types store returns an error",
+			TypesErr:     errors.New("error"),
+			ExpectedCode: http.StatusInternalServerError,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			store := new(MockStore)
+			server := NewServer(store).WithTypes(fakeTypesStore{dictionaries: testDictionaries, err: test.TypesErr})
+
+			jsonBytes, err := json.Marshal(product)
+			assert.NoError(t, err)
+			request := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(jsonBytes))
+			request.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			response := httptest.NewRecorder()
+			c := echo.New().NewContext(request, response)
+
+			err = server.handlePostProduct(c)
+			assert.NoError(t, err)
+			assert.Equal(t, test.ExpectedCode, response.Code)
+			store.AssertNotCalled(t, "CreateProduct", mock.Anything, mock.Anything)
+			if test.ExpectedBody != nil {
+				var obj v1.ErrorResponse
+				err = json.NewDecoder(response.Body).Decode(&obj)
+				assert.NoError(t, err)
+				assert.Equal(t, *test.ExpectedBody, obj)
+			}
+		})
+	}
+}
+
+type fakeTypesStore struct {
+	dictionaries types.Dictionaries
+	err          error
+}
+
+func (s fakeTypesStore) GetUnits(context.Context) ([]string, error) {
+	return keys(s.dictionaries.Units), s.err
+}
+
+func (s fakeTypesStore) GetUnitDimensions(context.Context) (map[string]types.Dimension, error) {
+	return s.dictionaries.Units, s.err
+}
+
+func (s fakeTypesStore) GetNutrientTypes(context.Context) ([]string, error) {
+	return keys(s.dictionaries.NutrientTypes), s.err
+}
+
+func (s fakeTypesStore) GetVitaminTypes(context.Context) ([]string, error) {
+	return keys(s.dictionaries.VitaminTypes), s.err
+}
+
+func (s fakeTypesStore) GetMineralTypes(context.Context) ([]string, error) {
+	return keys(s.dictionaries.MineralTypes), s.err
+}
+
+func keys[V any](set map[string]V) []string {
+	values := make([]string, 0, len(set))
+	for value := range set {
+		values = append(values, value)
+	}
+	return values
+}
@@ -0,0 +1,75 @@
+package products
+
+import (
+	"errors"
+	v1 "github.com/Kobietka/product-service/pkg/api/v1"
+	"math"
+)
+
+// minEnergyDeviation is the deviation in kcal per 100 g or ml that is always
+// accepted, so that rounding on labels of low energy products passes.
+const minEnergyDeviation = 10
+
+var (
+	ErrorNutritionEnergyMismatch = errors.New("NUTRITION_ENERGY_MISMATCH")
+)
+
+// Energy conversion factors in kcal per gram as used for nutrition labelling.
+var energyFactors = map[string]float64{
+	FatType:           9,
+	CarbohydratesType: 4,
+	ProteinType:       4,
+	AlcoholType:       7,
+	PolyolsType:       2.4,
+	FiberType:         2,
+}
+
+// DefaultEnergyTolerance is the accepted relative deviation of the declared
+// energy from the one estimated from macronutrients.
+const DefaultEnergyTolerance = 0.2
+
+// validateEnergy skips the check when tolerance is zero.
+func validateEnergy(path string, nutrition v1.Nutrition, tolerance float64) Violations {
+	if tolerance <= 0 {
+		return nil
+	}
+
+	estimated := estimateKcal(nutrition.Nutrients)
+	declared := float64(nutrition.Kcal)
+
+	minDeviation := float64(minEnergyDeviation)
+	if per, _, ok := toBase(nutrition.Per); ok {
+		minDeviation = minEnergyDeviation * per / 100
+	}
+
+	var violations Violations
+	if math.Abs(declared-estimated) > max(tolerance*estimated, minDeviation) {
+		violations.add(
+			pointer(path, "kcal"), ErrorNutritionEnergyMismatch,
+			"kcal", nutrition.Kcal, "estimated", math.Round(estimated), "tolerance", tolerance,
+		)
+	}
+	return violations
+}
+
+// estimateKcal sums the energy of macronutrients. Polyols are labelled as
+// part of carbohydrates, so they are taken out before applying the
+// carbohydrate factor.
+func estimateKcal(nutrients []v1.Nutrient) float64 {
+	grams := make(map[string]float64)
+	for _, nutrient := range nutrients {
+		if _, ok := energyFactors[nutrient.T]; !ok {
+			continue
+		}
+		if value, ok := toGrams(nutrient.Quantity); ok {
+			grams[nutrient.T] += value
+		}
+	}
+	grams[CarbohydratesType] = max(0, grams[CarbohydratesType]-grams[PolyolsType])
+
+	kcal := 0.0
+	for t, value := range grams {
+		kcal += value * energyFactors[t]
+	}
+	return kcal
+}
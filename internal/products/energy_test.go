@@ -0,0 +1,127 @@
+package products
+
+import (
+	v1 "github.com/Kobietka/product-service/pkg/api/v1"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestEstimateKcal(t *testing.T) {
+	tests := []struct {
+		Name         string
+		Nutrients    []v1.Nutrient
+		ExpectedKcal float64
+	}{
+		{
+			Name:         "no nutrients",
+			Nutrients:    []v1.Nutrient{},
+			ExpectedKcal: 0,
+		},
+		{
+			Name: "macronutrients",
+			Nutrients: []v1.Nutrient{
+				{T: FatType, Quantity: v1.Quantity{Value: 10, Unit: "g"}},
+				{T: CarbohydratesType, Quantity: v1.Quantity{Value: 20, Unit: "g"}},
+				{T: ProteinType, Quantity: v1.Quantity{Value: 5000, Unit: "mg"}},
+				{T: "SUGAR", Quantity: v1.Quantity{Value: 15, Unit: "g"}},
+				{T: "SALT", Quantity: v1.Quantity{Value: 1, Unit: "g"}},
+			},
+			ExpectedKcal: 190,
+		},
+		{
+			Name: "polyols are part of carbohydrates",
+			Nutrients: []v1.Nutrient{
+				{T: CarbohydratesType, Quantity: v1.Quantity{Value: 60, Unit: "g"}},
+				{T: PolyolsType, Quantity: v1.Quantity{Value: 50, Unit: "g"}},
+			},
+			ExpectedKcal: 160,
+		},
+		{
+			Name: "alcohol and fibre",
+			Nutrients: []v1.Nutrient{
+				{T: AlcoholType, Quantity: v1.Quantity{Value: 10, Unit: "g"}},
+				{T: FiberType, Quantity: v1.Quantity{Value: 5, Unit: "g"}},
+			},
+			ExpectedKcal: 80,
+		},
+		{
+			Name: "unknown unit is ignored",
+			Nutrients: []v1.Nutrient{
+				{T: FatType, Quantity: v1.Quantity{Value: 10, Unit: "ml"}},
+			},
+			ExpectedKcal: 0,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			assert.InDelta(t, test.ExpectedKcal, estimateKcal(test.Nutrients), 0.001)
+		})
+	}
+}
+
+func TestValidateEnergy(t *testing.T) {
+	nutrients := []v1.Nutrient{
+		{T: FatType, Quantity: v1.Quantity{Value: 10, Unit: "g"}},
+		{T: CarbohydratesType, Quantity: v1.Quantity{Value: 50, Unit: "g"}},
+		{T: ProteinType, Quantity: v1.Quantity{Value: 10, Unit: "g"}},
+	}
+
+	tests := []struct {
+		Name        string
+		Kcal        int32
+		Per         v1.Quantity
+		Tolerance   float64
+		ExpectedErr error
+	}{
+		{
+			Name:        "matching energy",
+			Kcal:        330,
+			Per:         v1.Quantity{Value: 100, Unit: "g"},
+			Tolerance:   DefaultEnergyTolerance,
+			ExpectedErr: nil,
+		},
+		{
+			Name:        "within tolerance",
+			Kcal:        390,
+			Per:         v1.Quantity{Value: 100, Unit: "g"},
+			Tolerance:   DefaultEnergyTolerance,
+			ExpectedErr: nil,
+		},
+		{
+			Name:        "beyond tolerance",
+			Kcal:        200,
+			Per:         v1.Quantity{Value: 100, Unit: "g"},
+			Tolerance:   DefaultEnergyTolerance,
+			ExpectedErr: ErrorNutritionEnergyMismatch,
+		},
+		{
+			Name:        "custom tolerance",
+			Kcal:        390,
+			Per:         v1.Quantity{Value: 100, Unit: "g"},
+			Tolerance:   0.1,
+			ExpectedErr: ErrorNutritionEnergyMismatch,
+		},
+		{
+			Name:        "disabled check",
+			Kcal:        0,
+			Per:         v1.Quantity{Value: 100, Unit: "g"},
+			Tolerance:   0,
+			ExpectedErr: nil,
+		},
+		{
+			Name:        "minimum deviation scales with basis",
+			Kcal:        20,
+			Per:         v1.Quantity{Value: 1, Unit: "kg"},
+			Tolerance:   0.01,
+			ExpectedErr: ErrorNutritionEnergyMismatch,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			nutrition := v1.Nutrition{Per: test.Per, Kcal: test.Kcal, Nutrients: nutrients}
+			assertValidationError(t, test.ExpectedErr, validateEnergy("/nutrition", nutrition, test.Tolerance).err())
+		})
+	}
+}
@@ -1,10 +1,22 @@
 package products
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
 	"errors"
+	"github.com/Kobietka/product-service/internal/ean"
+	"github.com/Kobietka/product-service/internal/types"
 	v1 "github.com/Kobietka/product-service/pkg/api/v1"
+	"github.com/Kobietka/product-service/pkg/array"
 	"github.com/Kobietka/product-service/pkg/text"
+	"github.com/charmbracelet/log"
 	"github.com/labstack/echo/v4"
+	_ "image/jpeg"
+	_ "image/png"
+	"io"
+	"maps"
+	"math"
 	"net/http"
 )
 
@@ -12,10 +24,42 @@ var (
 	searchLimit int8 = 15
 )
 
+var (
+	ErrorProductEanDuplicate = errors.New("PRODUCT_EAN_DUPLICATE")
+	ErrorProductEanMismatch  = errors.New("PRODUCT_EAN_MISMATCH")
+)
+
+const (
+	maxImageBytes     = 10 << 20
+	imageFormField    = "image"
+	defaultModuleSize = 2
+	maxModuleSize     = 20
+	minDpi            = 72
+	maxDpi            = 2400
+	moduleMillimetres = 0.33
+	maxValidateBytes  = 10 << 20
+	maxValidateBatch  = 1000
+	maxLookupEans     = 100
+)
+
 type productBinding struct {
 	Ean string `param:"ean"`
 }
 
+type barcodeBinding struct {
+	Code string `param:"code"`
+}
+
+type barcodeImageBinding struct {
+	Ean  string `param:"ean"`
+	Size int    `query:"size"`
+	Dpi  int    `query:"dpi"`
+}
+
+type decodeBinding struct {
+	Product bool `query:"product"`
+}
+
 type searchBinding struct {
 	Query string `query:"query"`
 	Limit int8   `query:"limit"`
@@ -27,7 +71,24 @@ func (s Server) handleGetProduct(c echo.Context) error {
 		return c.NoContent(http.StatusBadRequest)
 	}
 
-	product, err := s.store.GetProduct(c.Request().Context(), binding.Ean)
+	product, err := s.getProduct(c.Request().Context(), binding.Ean)
+	if err != nil {
+		if errors.Is(err, v1.ErrorDataNotFound) {
+			return s.handleGetVariableMeasureProduct(c, binding.Ean)
+		}
+		return c.NoContent(http.StatusInternalServerError)
+	}
+
+	return c.JSON(http.StatusOK, withServings(withBarcode(product)))
+}
+
+func (s Server) handleGetVariableMeasureProduct(c echo.Context, code string) error {
+	variableMeasure, err := ean.DecodeVariableMeasure(code, s.measureTemplates)
+	if err != nil || variableMeasure.BaseCode == code {
+		return c.NoContent(http.StatusNotFound)
+	}
+
+	product, err := s.getProduct(c.Request().Context(), variableMeasure.BaseCode)
 	if err != nil {
 		if errors.Is(err, v1.ErrorDataNotFound) {
 			return c.NoContent(http.StatusNotFound)
@@ -35,7 +96,86 @@ func (s Server) handleGetProduct(c echo.Context) error {
 		return c.NoContent(http.StatusInternalServerError)
 	}
 
-	return c.JSON(http.StatusOK, product)
+	return c.JSON(http.StatusOK, withMeasure(withServings(withBarcode(product)), variableMeasure))
+}
+
+// handleLookupProducts answers every EAN of the request like GET
+// /products/:ean would, with the products read in one call to the store.
+// Variable measure codes are read by their base code in a second call and
+// the multipack components of both in one call per level of composition. A
+// product whose nutrition cannot be resolved is answered with the error.
+func (s Server) handleLookupProducts(c echo.Context) error {
+	var request v1.LookupRequest
+	if err := c.Bind(&request); err != nil {
+		return c.NoContent(http.StatusBadRequest)
+	}
+
+	if len(request.Eans) == 0 || len(request.Eans) > maxLookupEans {
+		return c.NoContent(http.StatusBadRequest)
+	}
+
+	ctx := c.Request().Context()
+	products, err := s.store.GetProducts(ctx, request.Eans)
+	if err != nil {
+		return c.NoContent(http.StatusInternalServerError)
+	}
+
+	variableMeasures := make(map[string]ean.VariableMeasure)
+	baseCodes := make([]string, 0)
+	for _, code := range request.Eans {
+		if _, ok := products[code]; ok {
+			continue
+		}
+		variableMeasure, err := ean.DecodeVariableMeasure(code, s.measureTemplates)
+		if err != nil || variableMeasure.BaseCode == code {
+			continue
+		}
+		variableMeasures[code] = variableMeasure
+		baseCodes = append(baseCodes, variableMeasure.BaseCode)
+	}
+
+	baseProducts := make(map[string]v1.Product)
+	if len(baseCodes) > 0 {
+		baseProducts, err = s.store.GetProducts(ctx, baseCodes)
+		if err != nil {
+			return c.NoContent(http.StatusInternalServerError)
+		}
+	}
+
+	found := make(map[string]v1.Product, len(products)+len(baseProducts))
+	maps.Copy(found, products)
+	maps.Copy(found, baseProducts)
+	resolved, failed, err := s.resolveNutritions(ctx, found)
+	if err != nil {
+		return c.NoContent(http.StatusInternalServerError)
+	}
+
+	results := make(map[string]v1.LookupResult, len(request.Eans))
+	for _, code := range request.Eans {
+		key := code
+		variableMeasure, isVariableMeasure := variableMeasures[code]
+		if _, ok := products[code]; !ok && isVariableMeasure {
+			key = variableMeasure.BaseCode
+		}
+
+		if err, ok := failed[key]; ok {
+			results[code] = v1.LookupResult{Code: err.Error()}
+			continue
+		}
+		product, ok := resolved[key]
+		if !ok {
+			results[code] = v1.LookupResult{Code: v1.ErrorDataNotFound.Error()}
+			continue
+		}
+
+		product = withServings(withBarcode(product))
+		if key != code {
+			product = withMeasure(product, variableMeasure)
+		}
+		results[code] = v1.LookupResult{Product: &product}
+	}
+
+	return c.JSON(http.StatusOK, results)
 }
 
 func (s Server) handleSearchProduct(c echo.Context) error {
@@ -54,12 +194,24 @@ func (s Server) handleSearchProduct(c echo.Context) error {
 
 	limit := min(binding.Limit, searchLimit)
 
-	products, err := s.store.SearchProducts(c.Request().Context(), binding.Query, limit)
+	ctx := c.Request().Context()
+	products, err := s.store.SearchProducts(ctx, binding.Query, limit)
 	if err != nil {
 		return c.NoContent(http.StatusInternalServerError)
 	}
 
-	return c.JSON(http.StatusOK, products)
+	byEan := make(map[string]v1.Product, len(products))
+	for _, product := range products {
+		byEan[product.Ean] = product
+	}
+	resolved, failed, err := s.resolveNutritions(ctx, byEan)
+	if err != nil || len(failed) > 0 {
+		return c.NoContent(http.StatusInternalServerError)
+	}
+
+	return c.JSON(http.StatusOK, array.MapArray(products, func(product v1.Product) v1.Product {
+		return withServings(withBarcode(resolved[product.Ean]))
+	}))
 }
 
 func (s Server) handlePostProduct(c echo.Context) error {
@@ -68,11 +220,19 @@ func (s Server) handlePostProduct(c echo.Context) error {
 		return c.NoContent(http.StatusBadRequest)
 	}
 
-	if err := validateProduct(product); err != nil {
-		return c.JSON(http.StatusBadRequest, v1.ErrorResponse{Code: err.Error()})
+	if err := s.validateProduct(c.Request().Context(), product); err != nil {
+		if errors.As(err, &Violations{}) {
+			return c.JSON(http.StatusBadRequest, toErrorResponse(err))
+		}
+		return c.NoContent(http.StatusInternalServerError)
 	}
 
 	if err := s.store.CreateProduct(c.Request().Context(), product); err != nil {
+		if errors.Is(err, v1.ErrorProductAlreadyExists) {
+			var violations Violations
+			violations.add("/ean", err, "ean", product.Ean)
+			return c.JSON(http.StatusBadRequest, toErrorResponse(violations))
+		}
 		if errors.Is(err, v1.ErrorInvalidData) {
 			return c.NoContent(http.StatusBadRequest)
 		}
@@ -88,8 +248,11 @@ func (s Server) handlePutProduct(c echo.Context) error {
 		return c.NoContent(http.StatusBadRequest)
 	}
 
-	if err := validateProduct(product); err != nil {
-		return c.JSON(http.StatusBadRequest, v1.ErrorResponse{Code: err.Error()})
+	if err := s.validateProduct(c.Request().Context(), product); err != nil {
+		if errors.As(err, &Violations{}) {
+			return c.JSON(http.StatusBadRequest, toErrorResponse(err))
+		}
+		return c.NoContent(http.StatusInternalServerError)
 	}
 
 	if err := s.store.UpdateProduct(c.Request().Context(), product); err != nil {
@@ -105,6 +268,170 @@ func (s Server) handlePutProduct(c echo.Context) error {
 	return c.NoContent(http.StatusOK)
 }
 
+// handleUpsertProduct creates the product at the path EAN or replaces it. A
+// body without an EAN takes the one of the path.
+func (s Server) handleUpsertProduct(c echo.Context) error {
+	var product v1.Product
+	if err := c.Bind(&product); err != nil {
+		return c.NoContent(http.StatusBadRequest)
+	}
+
+	pathEan := c.Param("ean")
+	if product.Ean == "" {
+		product.Ean = pathEan
+	}
+	if product.Ean != pathEan {
+		var violations Violations
+		violations.add("/ean", ErrorProductEanMismatch, "ean", pathEan)
+		return c.JSON(http.StatusBadRequest, toErrorResponse(violations))
+	}
+
+	if err := s.validateProduct(c.Request().Context(), product); err != nil {
+		if errors.As(err, &Violations{}) {
+			return c.JSON(http.StatusBadRequest, toErrorResponse(err))
+		}
+		return c.NoContent(http.StatusInternalServerError)
+	}
+
+	created, err := s.store.UpsertProduct(c.Request().Context(), product)
+	if err != nil {
+		if errors.Is(err, v1.ErrorInvalidData) {
+			return c.NoContent(http.StatusBadRequest)
+		}
+		return c.NoContent(http.StatusInternalServerError)
+	}
+
+	if created {
+		return c.NoContent(http.StatusCreated)
+	}
+	return c.NoContent(http.StatusOK)
+}
+
+// validateProduct accepts products that only violate warning rules, the
+// warnings are logged.
+func (s Server) validateProduct(ctx context.Context, product v1.Product) error {
+	violations, err := s.collectViolations(ctx, product)
+	if err != nil {
+		return err
+	}
+
+	if IsWarning(violations.err()) {
+		log.Warn("product accepted with warnings", "ean", product.Ean, "warnings", violations.Error())
+		return nil
+	}
+	return violations.err()
+}
+
+// collectViolations gives the rules the dictionaries when a type store is
+// configured and the catalogue. The error is only set when a store fails.
+func (s Server) collectViolations(ctx context.Context, product v1.Product) (Violations, error) {
+	env := Env{Store: s.store}
+	if s.types != nil {
+		dictionaries, err := types.LoadDictionaries(ctx, s.types)
+		if err != nil {
+			return nil, err
+		}
+		env.Dictionaries = &dictionaries
+	}
+
+	return s.rules.Check(ctx, product, env)
+}
+
+// handleValidateProducts runs the checks of POST /products without writing.
+// A single product is answered like POST /products would, an array of
+// products with a result per product in the same order.
+func (s Server) handleValidateProducts(c echo.Context) error {
+	request := c.Request()
+	body, err := io.ReadAll(http.MaxBytesReader(c.Response(), request.Body, maxValidateBytes))
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		return c.NoContent(http.StatusRequestEntityTooLarge)
+	} else if err != nil {
+		return c.NoContent(http.StatusBadRequest)
+	}
+
+	batch := bytes.HasPrefix(bytes.TrimSpace(body), []byte("["))
+	var products []v1.Product
+	if batch {
+		err = json.Unmarshal(body, &products)
+	} else {
+		products = make([]v1.Product, 1)
+		err = json.Unmarshal(body, &products[0])
+	}
+	if err != nil || len(products) > maxValidateBatch {
+		return c.NoContent(http.StatusBadRequest)
+	}
+
+	ctx := request.Context()
+	violations := make([]Violations, len(products))
+	eans := make([]string, 0, len(products))
+	for i, product := range products {
+		violations[i], err = s.collectViolations(ctx, product)
+		if err != nil {
+			return c.NoContent(http.StatusInternalServerError)
+		}
+		if !violations[i].under("/ean") {
+			eans = append(eans, product.Ean)
+		}
+	}
+
+	existing := map[string]v1.Product{}
+	if len(eans) > 0 {
+		existing, err = s.store.GetProducts(ctx, eans)
+		if err != nil {
+			return c.NoContent(http.StatusInternalServerError)
+		}
+	}
+
+	results := make([]v1.ValidationResult, len(products))
+	seen := make(map[string]int)
+	for i, product := range products {
+		violations[i].merge(validateUniqueEan(product, violations[i], seen, existing))
+		if _, ok := seen[product.Ean]; !ok {
+			seen[product.Ean] = i
+		}
+
+		results[i] = toValidationResult(product.Ean, violations[i])
+	}
+
+	if batch {
+		return c.JSON(http.StatusOK, results)
+	}
+	if !results[0].Valid {
+		return c.JSON(http.StatusBadRequest, results[0])
+	}
+	return c.JSON(http.StatusOK, results[0])
+}
+
+// validateUniqueEan stands in for the primary key of the product table, both
+// against the existing products of the catalogue and against the products
+// seen earlier in a batch.
+func validateUniqueEan(product v1.Product, violations Violations, seen map[string]int, existing map[string]v1.Product) Violations {
+	var duplicateViolations Violations
+	if violations.under("/ean") {
+		return duplicateViolations
+	}
+
+	if index, ok := seen[product.Ean]; ok {
+		duplicateViolations.add("/ean", ErrorProductEanDuplicate, "ean", product.Ean, "index", index)
+	} else if _, ok := existing[product.Ean]; ok {
+		duplicateViolations.add("/ean", v1.ErrorProductAlreadyExists, "ean", product.Ean)
+	}
+	return duplicateViolations
+}
+
+func toValidationResult(ean string, violations Violations) v1.ValidationResult {
+	result := v1.ValidationResult{Ean: ean, Valid: len(violations) == 0 || IsWarning(violations)}
+	if len(violations) > 0 {
+		response := toErrorResponse(violations)
+		result.Violations = response.Violations
+		if !result.Valid {
+			result.Code = response.Code
+		}
+	}
+	return result
+}
+
 func (s Server) handleDeleteProduct(c echo.Context) error {
 	var binding productBinding
 	if err := c.Bind(&binding); err != nil {
@@ -115,8 +442,159 @@ func (s Server) handleDeleteProduct(c echo.Context) error {
 		if errors.Is(err, v1.ErrorProductDoesNotExist) {
 			return c.NoContent(http.StatusNotFound)
 		}
+		if errors.Is(err, v1.ErrorProductInUse) {
+			return c.JSON(http.StatusConflict, v1.ErrorResponse{Code: err.Error()})
+		}
 		return c.NoContent(http.StatusInternalServerError)
 	}
 
 	return c.NoContent(http.StatusNoContent)
 }
+
+func (s Server) handleGetBarcode(c echo.Context) error {
+	var binding barcodeBinding
+	if err := c.Bind(&binding); err != nil {
+		return c.NoContent(http.StatusBadRequest)
+	}
+
+	barcode := describeBarcode(binding.Code)
+	if barcode == nil {
+		return c.JSON(http.StatusBadRequest, v1.ErrorResponse{Code: ean.ErrorCodeInvalid.Error()})
+	}
+
+	return c.JSON(http.StatusOK, barcode)
+}
+
+var (
+	ErrorGtinMissing = errors.New("GS1_GTIN_MISSING")
+)
+
+func (s Server) handleResolveBarcode(c echo.Context) error {
+	var request v1.ResolveRequest
+	if err := c.Bind(&request); err != nil {
+		return c.NoContent(http.StatusBadRequest)
+	}
+
+	elements, err := ean.ParseElementString(request.Data)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, v1.ErrorResponse{Code: err.Error()})
+	}
+
+	resolution := v1.Resolution{
+		Elements: array.MapArray(elements, toApplicationIdentifier),
+	}
+	for _, element := range elements {
+		if element.Ai == ean.AiGtin {
+			resolution.Gtin = element.Value
+			break
+		}
+	}
+	if resolution.Gtin == "" {
+		return c.JSON(http.StatusBadRequest, v1.ErrorResponse{Code: ErrorGtinMissing.Error()})
+	}
+
+	product, err := s.findByGtin(c.Request().Context(), resolution.Gtin)
+	if err != nil {
+		return c.NoContent(http.StatusInternalServerError)
+	}
+	resolution.Product = product
+
+	return c.JSON(http.StatusOK, resolution)
+}
+
+func (s Server) handleGetBarcodeSvg(c echo.Context) error {
+	return s.handleGetBarcodeImage(c, "image/svg+xml", func(symbol ean.Symbol, size int) ([]byte, error) {
+		return symbol.Svg(size), nil
+	})
+}
+
+func (s Server) handleGetBarcodePng(c echo.Context) error {
+	return s.handleGetBarcodeImage(c, "image/png", ean.Symbol.Png)
+}
+
+func (s Server) handleGetBarcodeImage(
+	c echo.Context,
+	contentType string,
+	render func(symbol ean.Symbol, size int) ([]byte, error),
+) error {
+	var binding barcodeImageBinding
+	if err := c.Bind(&binding); err != nil {
+		return c.NoContent(http.StatusBadRequest)
+	}
+
+	size := defaultModuleSize
+	if binding.Size != 0 {
+		size = binding.Size
+	}
+	if binding.Dpi != 0 {
+		if binding.Dpi < minDpi || binding.Dpi > maxDpi {
+			return c.NoContent(http.StatusBadRequest)
+		}
+		size = max(1, int(math.Round(moduleMillimetres*float64(binding.Dpi)/25.4)))
+	}
+	if size < 1 || size > maxModuleSize {
+		return c.NoContent(http.StatusBadRequest)
+	}
+
+	product, err := s.store.GetProduct(c.Request().Context(), binding.Ean)
+	if err != nil {
+		if errors.Is(err, v1.ErrorDataNotFound) {
+			return c.NoContent(http.StatusNotFound)
+		}
+		return c.NoContent(http.StatusInternalServerError)
+	}
+
+	symbol, err := ean.Encode(product.Ean)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, v1.ErrorResponse{Code: err.Error()})
+	}
+
+	image, err := render(symbol, size)
+	if err != nil {
+		return c.NoContent(http.StatusInternalServerError)
+	}
+
+	return c.Blob(http.StatusOK, contentType, image)
+}
+
+func (s Server) handleDecodeBarcode(c echo.Context) error {
+	var binding decodeBinding
+	if err := (&echo.DefaultBinder{}).BindQueryParams(c, &binding); err != nil {
+		return c.NoContent(http.StatusBadRequest)
+	}
+
+	body, err := readImage(c)
+	if err != nil {
+		return c.NoContent(http.StatusBadRequest)
+	}
+	defer body.Close()
+
+	img, err := ean.ReadImage(body)
+	if errors.Is(err, ean.ErrorImageTooLarge) {
+		return c.JSON(http.StatusRequestEntityTooLarge, v1.ErrorResponse{Code: err.Error()})
+	} else if err != nil {
+		return c.NoContent(http.StatusBadRequest)
+	}
+
+	code, err := ean.DecodeImage(img)
+	if err != nil {
+		return c.JSON(http.StatusUnprocessableEntity, v1.ErrorResponse{Code: err.Error()})
+	}
+
+	decoded := v1.DecodedBarcode{
+		Code:    code,
+		Barcode: describeBarcode(code),
+	}
+
+	if binding.Product {
+		product, err := s.getProduct(c.Request().Context(), code)
+		if err == nil {
+			product = withServings(withBarcode(product))
+			decoded.Product = &product
+		} else if !errors.Is(err, v1.ErrorDataNotFound) {
+			return c.NoContent(http.StatusInternalServerError)
+		}
+	}
+
+	return c.JSON(http.StatusOK, decoded)
+}
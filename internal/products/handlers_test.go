@@ -3,19 +3,34 @@ package products
 import (
 	"bytes"
 	"context"
+	"encoding/binary"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"github.com/Kobietka/product-service/internal/ean"
 	v1 "github.com/Kobietka/product-service/pkg/api/v1"
 	"github.com/labstack/echo/v4"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"hash/crc32"
+	"image"
+	"image/png"
+	"mime/multipart"
 	"net/http"
 	"net/http/httptest"
 	"strconv"
+	"strings"
 	"testing"
 )
 
+var testBarcode = v1.Barcode{
+	Code:    "12345678",
+	Format:  "EAN_8",
+	Prefix:  "123",
+	Country: "United States",
+	Range:   "GTIN",
+}
+
 func TestHandleGetProduct(t *testing.T) {
 	tests := []struct {
 		Name         string
@@ -29,7 +44,7 @@ func TestHandleGetProduct(t *testing.T) {
 			MockValue:    v1.Product{Ean: "12345678", Name: "Product name"},
 			MockError:    nil,
 			ExpectedCode: http.StatusOK,
-			ExpectedBody: &v1.Product{Ean: "12345678", Name: "Product name"},
+			ExpectedBody: &v1.Product{Ean: "12345678", Name: "Product name", Barcode: &testBarcode},
 		},
 		{
 			Name:         "store returns not found error",
@@ -74,6 +89,273 @@ func TestHandleGetProduct(t *testing.T) {
 	}
 }
 
+func TestHandleLookupProducts(t *testing.T) {
+	price := float32(3.49)
+	product := v1.Product{Ean: "12345678", Name: "Product name"}
+	ham := v1.Product{Ean: "2512345000006", Name: "Ham"}
+	tooMany := make([]string, maxLookupEans+1)
+	for i := range tooMany {
+		tooMany[i] = "12345678"
+	}
+
+	tests := []struct {
+		Name            string
+		Body            string
+		MockProducts    map[string]v1.Product
+		MockError       error
+		MockBaseCodes   []string
+		MockBaseValues  map[string]v1.Product
+		ExpectedCode    int
+		ExpectedResults map[string]v1.LookupResult
+	}{
+		{
+			Name:         "maps every ean to a product or not found",
+			Body:         `{"eans": ["12345678", "87654321"]}`,
+			MockProducts: map[string]v1.Product{"12345678": product},
+			ExpectedCode: http.StatusOK,
+			ExpectedResults: map[string]v1.LookupResult{
+				"12345678": {Product: &v1.Product{Ean: "12345678", Name: "Product name", Barcode: &testBarcode}},
+				"87654321": {Code: "DATA_NOT_FOUND"},
+			},
+		},
+		{
+			Name:           "variable measure codes are read by their base code",
+			Body:           `{"eans": ["2512345003496", "2012345012509"]}`,
+			MockProducts:   map[string]v1.Product{},
+			MockBaseCodes:  []string{"2512345000006", "2012345000001"},
+			MockBaseValues: map[string]v1.Product{"2512345000006": ham},
+			ExpectedCode:   http.StatusOK,
+			ExpectedResults: map[string]v1.LookupResult{
+				"2512345003496": {Product: &v1.Product{
+					Ean:     "2512345000006",
+					Name:    "Ham",
+					Barcode: &v1.Barcode{Code: "2512345000006", Format: "EAN_13", Prefix: "251", Range: "IN_STORE"},
+					Measure: &v1.Measure{Code: "2512345003496", Article: "12345", Price: &price},
+				}},
+				"2012345012509": {Code: "DATA_NOT_FOUND"},
+			},
+		},
+		{
+			Name:         "store returns unknown error",
+			Body:         `{"eans": ["12345678"]}`,
+			MockProducts: map[string]v1.Product(nil),
+			MockError:    errors.New("error"),
+			ExpectedCode: http.StatusInternalServerError,
+		},
+		{
+			Name:         "no eans",
+			Body:         `{"eans": []}`,
+			ExpectedCode: http.StatusBadRequest,
+		},
+		{
+			Name:         "too many eans",
+			Body:         fmt.Sprintf(`{"eans": ["%s"]}`, strings.Join(tooMany, `", "`)),
+			ExpectedCode: http.StatusBadRequest,
+		},
+		{
+			Name:         "invalid body",
+			Body:         `{"eans": "12345678"}`,
+			ExpectedCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			store := new(MockStore)
+			server := NewServer(store)
+
+			store.On("GetProducts", mock.Anything, mock.Anything).Return(test.MockProducts, test.MockError).Once()
+			if test.MockBaseCodes != nil {
+				store.On("GetProducts", mock.Anything, test.MockBaseCodes).Return(test.MockBaseValues, nil).Once()
+			}
+
+			request := httptest.NewRequest(http.MethodPost, "/products/lookup", strings.NewReader(test.Body))
+			request.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			response := httptest.NewRecorder()
+			c := echo.New().NewContext(request, response)
+
+			err := server.handleLookupProducts(c)
+			assert.NoError(t, err)
+			assert.Equal(t, test.ExpectedCode, response.Code)
+			if test.ExpectedResults != nil {
+				var results map[string]v1.LookupResult
+				assert.NoError(t, json.NewDecoder(response.Body).Decode(&results))
+				assert.Equal(t, test.ExpectedResults, results)
+			}
+		})
+	}
+}
+
+func TestHandleLookupProductsResolvesMultipacks(t *testing.T) {
+	sixPack := v1.Product{
+		Ean:        "5901234000017",
+		Packaging:  v1.Quantity{Value: 9, Unit: "l"},
+		Components: []v1.Component{{Ean: water.Ean, Count: 6}},
+	}
+	box := v1.Product{
+		Ean:        "5901234000024",
+		Packaging:  v1.Quantity{Value: 18, Unit: "l"},
+		Components: []v1.Component{{Ean: sixPack.Ean, Count: 2}},
+	}
+	juicePack := v1.Product{
+		Ean:        "5901234000031",
+		Packaging:  v1.Quantity{Value: 1.5, Unit: "l"},
+		Components: []v1.Component{{Ean: juice.Ean, Count: 3}},
+	}
+	broken := v1.Product{
+		Ean:        "5901234000048",
+		Packaging:  v1.Quantity{Value: 1, Unit: "l"},
+		Components: []v1.Component{{Ean: "87654321", Count: 1}},
+	}
+	waterMeasure := v1.Product{
+		Ean:        "2512345000006",
+		Packaging:  v1.Quantity{Value: 1.5, Unit: "l"},
+		Components: []v1.Component{{Ean: water.Ean, Count: 1}},
+	}
+	const variableMeasure = "2512345003496"
+
+	store := new(MockStore)
+	server := NewServer(store)
+	store.On("GetProducts", mock.Anything, []string{box.Ean, juicePack.Ean, variableMeasure, broken.Ean}).
+		Return(map[string]v1.Product{box.Ean: box, juicePack.Ean: juicePack, broken.Ean: broken}, nil).Once()
+	store.On("GetProducts", mock.Anything, []string{waterMeasure.Ean}).
+		Return(map[string]v1.Product{waterMeasure.Ean: waterMeasure}, nil).Once()
+	store.On("GetProducts", mock.Anything, []string{juice.Ean, sixPack.Ean, water.Ean, "87654321"}).
+		Return(map[string]v1.Product{juice.Ean: juice, sixPack.Ean: sixPack, water.Ean: water}, nil).Once()
+
+	body := fmt.Sprintf(`{"eans": ["%s", "%s", "%s", "%s"]}`, box.Ean, juicePack.Ean, variableMeasure, broken.Ean)
+	request := httptest.NewRequest(http.MethodPost, "/products/lookup", strings.NewReader(body))
+	request.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	response := httptest.NewRecorder()
+	c := echo.New().NewContext(request, response)
+
+	err := server.handleLookupProducts(c)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, response.Code)
+	var results map[string]v1.LookupResult
+	assert.NoError(t, json.NewDecoder(response.Body).Decode(&results))
+	assert.Equal(t, water.Nutrition, results[box.Ean].Product.Nutrition)
+	assert.Equal(t, juice.Nutrition, results[juicePack.Ean].Product.Nutrition)
+	assert.Equal(t, water.Nutrition, results[variableMeasure].Product.Nutrition)
+	assert.NotNil(t, results[variableMeasure].Product.Measure)
+	assert.Equal(t, v1.LookupResult{Code: ErrorComponentNotFound.Error()}, results[broken.Ean])
+	store.AssertExpectations(t)
+	store.AssertNotCalled(t, "GetProduct", mock.Anything, mock.Anything)
+}
+
+func TestHandleGetVariableMeasureProduct(t *testing.T) {
+	product := v1.Product{
+		Ean:  "2012345000001",
+		Name: "Ham",
+		Nutrition: v1.Nutrition{
+			Per:  v1.Quantity{Value: 100, Unit: "g"},
+			Kcal: 120,
+			Nutrients: []v1.Nutrient{
+				{T: "PROTEIN", Quantity: v1.Quantity{Value: 20, Unit: "g"}},
+			},
+			Vitamins: []v1.Vitamin{
+				{T: "VITAMIN_B12", Quantity: v1.Quantity{Value: 1, Unit: "µg"}},
+			},
+			Minerals: []v1.Mineral{},
+		},
+	}
+	price := float32(3.49)
+
+	tests := []struct {
+		Name            string
+		Code            string
+		BaseCode        string
+		MockBaseValue   v1.Product
+		MockBaseError   error
+		ExpectedCode    int
+		ExpectedMeasure *v1.Measure
+	}{
+		{
+			Name:          "weight scales nutrition",
+			Code:          "2012345012509",
+			BaseCode:      "2012345000001",
+			MockBaseValue: product,
+			ExpectedCode:  http.StatusOK,
+			ExpectedMeasure: &v1.Measure{
+				Code:    "2012345012509",
+				Article: "12345",
+				Weight:  &v1.Quantity{Value: 1.25, Unit: "kg"},
+				Nutrition: &v1.Nutrition{
+					Per:  v1.Quantity{Value: 1.25, Unit: "kg"},
+					Kcal: 1500,
+					Nutrients: []v1.Nutrient{
+						{T: "PROTEIN", Quantity: v1.Quantity{Value: 250, Unit: "g"}},
+					},
+					Vitamins: []v1.Vitamin{
+						{T: "VITAMIN_B12", Quantity: v1.Quantity{Value: 12.5, Unit: "µg"}},
+					},
+					Minerals: []v1.Mineral{},
+				},
+			},
+		},
+		{
+			Name:          "price",
+			Code:          "2512345003496",
+			BaseCode:      "2512345000006",
+			MockBaseValue: product,
+			ExpectedCode:  http.StatusOK,
+			ExpectedMeasure: &v1.Measure{
+				Code:    "2512345003496",
+				Article: "12345",
+				Price:   &price,
+			},
+		},
+		{
+			Name:          "article not in catalogue",
+			Code:          "2012345012509",
+			BaseCode:      "2012345000001",
+			MockBaseError: v1.ErrorDataNotFound,
+			ExpectedCode:  http.StatusNotFound,
+		},
+		{
+			Name:          "store returns unknown error",
+			Code:          "2012345012509",
+			BaseCode:      "2012345000001",
+			MockBaseError: errors.New("error"),
+			ExpectedCode:  http.StatusInternalServerError,
+		},
+		{
+			Name:         "code outside variable measure ranges",
+			Code:         "5901234123457",
+			ExpectedCode: http.StatusNotFound,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			store := new(MockStore)
+			server := NewServer(store)
+
+			store.On("GetProduct", mock.Anything, test.Code).Return(v1.Product{}, v1.ErrorDataNotFound)
+			store.On("GetProduct", mock.Anything, test.BaseCode).Return(test.MockBaseValue, test.MockBaseError)
+
+			request := httptest.NewRequest(http.MethodGet, "/", nil)
+			response := httptest.NewRecorder()
+			c := echo.New().NewContext(request, response)
+			c.SetParamNames("ean")
+			c.SetParamValues(test.Code)
+
+			err := server.handleGetProduct(c)
+			assert.NoError(t, err)
+			assert.Equal(t, test.ExpectedCode, response.Code)
+			if test.ExpectedMeasure != nil {
+				var obj v1.Product
+				err = json.NewDecoder(response.Body).Decode(&obj)
+				assert.NoError(t, err)
+				assert.Equal(t, test.MockBaseValue.Ean, obj.Ean)
+				assert.Equal(t, test.ExpectedMeasure, obj.Measure)
+			} else {
+				assert.Equal(t, 0, len(response.Body.Bytes()))
+			}
+		})
+	}
+}
+
 func TestHandleSearchProduct(t *testing.T) {
 	tests := []struct {
 		Name         string
@@ -91,7 +373,7 @@ func TestHandleSearchProduct(t *testing.T) {
 			MockValue:    []v1.Product{{Ean: "12345678", Name: "Product name"}},
 			MockError:    nil,
 			ExpectedCode: http.StatusOK,
-			ExpectedBody: []v1.Product{{Ean: "12345678", Name: "Product name"}},
+			ExpectedBody: []v1.Product{{Ean: "12345678", Name: "Product name", Barcode: &testBarcode}},
 		},
 		{
 			Name:         "store returns an unknown error",
@@ -136,7 +418,7 @@ func TestHandleSearchProduct(t *testing.T) {
 			MockValue:    []v1.Product{{Ean: "12345678", Name: "Product name"}},
 			MockError:    nil,
 			ExpectedCode: http.StatusOK,
-			ExpectedBody: []v1.Product{{Ean: "12345678", Name: "Product name"}},
+			ExpectedBody: []v1.Product{{Ean: "12345678", Name: "Product name", Barcode: &testBarcode}},
 		},
 		{
 			Name:         "limit higher than int8 can store",
@@ -192,10 +474,10 @@ func TestHandlePostProduct(t *testing.T) {
 		},
 		Nutrition: v1.Nutrition{
 			Per: v1.Quantity{
-				Value: 1,
+				Value: 100,
 				Unit:  "g",
 			},
-			Kcal: 123,
+			Kcal: 17,
 			Nutrients: []v1.Nutrient{
 				{
 					T: "PROTEIN",
@@ -234,7 +516,15 @@ func TestHandlePostProduct(t *testing.T) {
 			RequestBody:  nil,
 			MockError:    nil,
 			ExpectedCode: http.StatusBadRequest,
-			ExpectedBody: &v1.ErrorResponse{Code: ErrorProductEanMissing.Error()},
+			ExpectedBody: &v1.ErrorResponse{Code: ErrorProductEanMissing.Error(), Violations: []v1.Violation{
+				{Pointer: "/ean", Code: ErrorProductEanMissing.Error()},
+				{Pointer: "/name", Code: ErrorProductNameMissing.Error()},
+				{Pointer: "/packaging/unit", Code: ErrorQuantityUnitMissing.Error()},
+				{Pointer: "/nutrition/per/unit", Code: ErrorQuantityUnitMissing.Error()},
+				{Pointer: "/nutrition/nutrients", Code: ErrorFatMissing.Error(), Params: map[string]any{"type": "FAT"}},
+				{Pointer: "/nutrition/nutrients", Code: ErrorCarbohydratesMissing.Error(), Params: map[string]any{"type": "CARBOHYDRATES"}},
+				{Pointer: "/nutrition/nutrients", Code: ErrorProteinMissing.Error(), Params: map[string]any{"type": "PROTEIN"}},
+			}},
 		},
 		{
 			Name:         "creates product",
@@ -250,7 +540,15 @@ func TestHandlePostProduct(t *testing.T) {
 			},
 			MockError:    nil,
 			ExpectedCode: http.StatusBadRequest,
-			ExpectedBody: &v1.ErrorResponse{Code: ErrorProductEanInvalid.Error()},
+			ExpectedBody: &v1.ErrorResponse{Code: ErrorProductEanInvalid.Error(), Violations: []v1.Violation{
+				{Pointer: "/ean", Code: ErrorProductEanInvalid.Error(), Params: map[string]any{"ean": "123"}},
+				{Pointer: "/name", Code: ErrorProductNameMissing.Error()},
+				{Pointer: "/packaging/unit", Code: ErrorQuantityUnitMissing.Error()},
+				{Pointer: "/nutrition/per/unit", Code: ErrorQuantityUnitMissing.Error()},
+				{Pointer: "/nutrition/nutrients", Code: ErrorFatMissing.Error(), Params: map[string]any{"type": "FAT"}},
+				{Pointer: "/nutrition/nutrients", Code: ErrorCarbohydratesMissing.Error(), Params: map[string]any{"type": "CARBOHYDRATES"}},
+				{Pointer: "/nutrition/nutrients", Code: ErrorProteinMissing.Error(), Params: map[string]any{"type": "PROTEIN"}},
+			}},
 		},
 		{
 			Name:         "store returns invalid data error",
@@ -259,6 +557,15 @@ func TestHandlePostProduct(t *testing.T) {
 			ExpectedCode: http.StatusBadRequest,
 			ExpectedBody: nil,
 		},
+		{
+			Name:         "product already exists",
+			RequestBody:  &correctProduct,
+			MockError:    v1.ErrorProductAlreadyExists,
+			ExpectedCode: http.StatusBadRequest,
+			ExpectedBody: &v1.ErrorResponse{Code: v1.ErrorProductAlreadyExists.Error(), Violations: []v1.Violation{
+				{Pointer: "/ean", Code: v1.ErrorProductAlreadyExists.Error(), Params: map[string]any{"ean": correctProduct.Ean}},
+			}},
+		},
 		{
 			Name:         "store returns an unknown error",
 			RequestBody:  &correctProduct,
@@ -304,6 +611,81 @@ func TestHandlePostProduct(t *testing.T) {
 	}
 }
 
+func TestHandlePostMultipack(t *testing.T) {
+	multipack := v1.Product{
+		Ean:        "5901234000000",
+		Name:       "Water 6 pack",
+		Packaging:  v1.Quantity{Value: 9, Unit: "l"},
+		Components: []v1.Component{{Ean: water.Ean, Count: 6}},
+	}
+
+	tests := []struct {
+		Name         string
+		Component    v1.Product
+		ComponentErr error
+		ExpectedCode int
+		ExpectedBody *v1.ErrorResponse
+	}{
+		{
+			Name:         "creates multipack",
+			Component:    water,
+			ExpectedCode: http.StatusCreated,
+		},
+		{
+			Name:         "component does not exist",
+			ComponentErr: v1.ErrorDataNotFound,
+			ExpectedCode: http.StatusBadRequest,
+			ExpectedBody: &v1.ErrorResponse{Code: ErrorComponentNotFound.Error(), Violations: []v1.Violation{
+				{Pointer: "/components/0/ean", Code: ErrorComponentNotFound.Error(), Params: map[string]any{"ean": water.Ean}},
+			}},
+		},
+		{
+			Name:         "packaging does not add up",
+			Component:    v1.Product{Ean: water.Ean, Packaging: v1.Quantity{Value: 1, Unit: "l"}},
+			ExpectedCode: http.StatusBadRequest,
+			ExpectedBody: &v1.ErrorResponse{Code: ErrorComponentPackagingMismatch.Error(), Violations: []v1.Violation{
+				{
+					Pointer: "/packaging/value",
+					Code:    ErrorComponentPackagingMismatch.Error(),
+					Params:  map[string]any{"total": float64(6000), "packaging": float64(9000)},
+				},
+			}},
+		},
+		{
+			Name:         "store returns an unknown error",
+			ComponentErr: errors.New("error"),
+			ExpectedCode: http.StatusInternalServerError,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			store := new(MockStore)
+			server := NewServer(store)
+
+			store.On("GetProduct", mock.Anything, water.Ean).Return(test.Component, test.ComponentErr)
+			store.On("CreateProduct", mock.Anything, mock.Anything).Return(nil)
+
+			jsonBytes, err := json.Marshal(multipack)
+			assert.NoError(t, err)
+			request := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(jsonBytes))
+			request.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			response := httptest.NewRecorder()
+			c := echo.New().NewContext(request, response)
+
+			err = server.handlePostProduct(c)
+			assert.NoError(t, err)
+			assert.Equal(t, test.ExpectedCode, response.Code)
+			if test.ExpectedBody != nil {
+				var obj v1.ErrorResponse
+				err = json.NewDecoder(response.Body).Decode(&obj)
+				assert.NoError(t, err)
+				assert.Equal(t, *test.ExpectedBody, obj)
+			}
+		})
+	}
+}
+
 func TestHandlePutProduct(t *testing.T) {
 	correctProduct := v1.Product{
 		Ean:  "12345678",
@@ -314,10 +696,10 @@ func TestHandlePutProduct(t *testing.T) {
 		},
 		Nutrition: v1.Nutrition{
 			Per: v1.Quantity{
-				Value: 1,
+				Value: 100,
 				Unit:  "g",
 			},
-			Kcal: 123,
+			Kcal: 17,
 			Nutrients: []v1.Nutrient{
 				{
 					T: "PROTEIN",
@@ -356,7 +738,15 @@ func TestHandlePutProduct(t *testing.T) {
 			RequestBody:  nil,
 			MockError:    nil,
 			ExpectedCode: http.StatusBadRequest,
-			ExpectedBody: &v1.ErrorResponse{Code: ErrorProductEanMissing.Error()},
+			ExpectedBody: &v1.ErrorResponse{Code: ErrorProductEanMissing.Error(), Violations: []v1.Violation{
+				{Pointer: "/ean", Code: ErrorProductEanMissing.Error()},
+				{Pointer: "/name", Code: ErrorProductNameMissing.Error()},
+				{Pointer: "/packaging/unit", Code: ErrorQuantityUnitMissing.Error()},
+				{Pointer: "/nutrition/per/unit", Code: ErrorQuantityUnitMissing.Error()},
+				{Pointer: "/nutrition/nutrients", Code: ErrorFatMissing.Error(), Params: map[string]any{"type": "FAT"}},
+				{Pointer: "/nutrition/nutrients", Code: ErrorCarbohydratesMissing.Error(), Params: map[string]any{"type": "CARBOHYDRATES"}},
+				{Pointer: "/nutrition/nutrients", Code: ErrorProteinMissing.Error(), Params: map[string]any{"type": "PROTEIN"}},
+			}},
 		},
 		{
 			Name:         "updates product",
@@ -372,7 +762,15 @@ func TestHandlePutProduct(t *testing.T) {
 			},
 			MockError:    nil,
 			ExpectedCode: http.StatusBadRequest,
-			ExpectedBody: &v1.ErrorResponse{Code: ErrorProductEanInvalid.Error()},
+			ExpectedBody: &v1.ErrorResponse{Code: ErrorProductEanInvalid.Error(), Violations: []v1.Violation{
+				{Pointer: "/ean", Code: ErrorProductEanInvalid.Error(), Params: map[string]any{"ean": "123"}},
+				{Pointer: "/name", Code: ErrorProductNameMissing.Error()},
+				{Pointer: "/packaging/unit", Code: ErrorQuantityUnitMissing.Error()},
+				{Pointer: "/nutrition/per/unit", Code: ErrorQuantityUnitMissing.Error()},
+				{Pointer: "/nutrition/nutrients", Code: ErrorFatMissing.Error(), Params: map[string]any{"type": "FAT"}},
+				{Pointer: "/nutrition/nutrients", Code: ErrorCarbohydratesMissing.Error(), Params: map[string]any{"type": "CARBOHYDRATES"}},
+				{Pointer: "/nutrition/nutrients", Code: ErrorProteinMissing.Error(), Params: map[string]any{"type": "PROTEIN"}},
+			}},
 		},
 		{
 			Name:         "store returns invalid data error",
@@ -433,6 +831,256 @@ func TestHandlePutProduct(t *testing.T) {
 	}
 }
 
+func TestHandleUpsertProduct(t *testing.T) {
+	product := v1.Product{
+		Ean:       "12345678",
+		Name:      "Product name",
+		Packaging: v1.Quantity{Value: 12, Unit: "g"},
+		Nutrition: v1.Nutrition{
+			Per:  v1.Quantity{Value: 100, Unit: "g"},
+			Kcal: 17,
+			Nutrients: []v1.Nutrient{
+				{T: "PROTEIN", Quantity: v1.Quantity{Value: 1, Unit: "g"}},
+				{T: "CARBOHYDRATES", Quantity: v1.Quantity{Value: 1, Unit: "g"}},
+				{T: "FAT", Quantity: v1.Quantity{Value: 1, Unit: "g"}},
+			},
+		},
+	}
+	withoutEan := product
+	withoutEan.Ean = ""
+	otherEan := product
+	otherEan.Ean = "87654321"
+
+	tests := []struct {
+		Name           string
+		RequestBody    v1.Product
+		MockCreated    bool
+		MockError      error
+		ExpectedCode   int
+		ExpectedBody   *v1.ErrorResponse
+		ExpectedUpsert bool
+	}{
+		{
+			Name:           "creates product",
+			RequestBody:    product,
+			MockCreated:    true,
+			ExpectedCode:   http.StatusCreated,
+			ExpectedUpsert: true,
+		},
+		{
+			Name:           "replaces product",
+			RequestBody:    product,
+			ExpectedCode:   http.StatusOK,
+			ExpectedUpsert: true,
+		},
+		{
+			Name:           "takes ean from path",
+			RequestBody:    withoutEan,
+			MockCreated:    true,
+			ExpectedCode:   http.StatusCreated,
+			ExpectedUpsert: true,
+		},
+		{
+			Name:         "body ean does not match path",
+			RequestBody:  otherEan,
+			ExpectedCode: http.StatusBadRequest,
+			ExpectedBody: &v1.ErrorResponse{Code: ErrorProductEanMismatch.Error(), Violations: []v1.Violation{
+				{Pointer: "/ean", Code: ErrorProductEanMismatch.Error(), Params: map[string]any{"ean": "12345678"}},
+			}},
+		},
+		{
+			Name:         "validation returns an error",
+			RequestBody:  v1.Product{Ean: "12345678", Packaging: product.Packaging, Nutrition: product.Nutrition},
+			ExpectedCode: http.StatusBadRequest,
+			ExpectedBody: &v1.ErrorResponse{Code: ErrorProductNameMissing.Error(), Violations: []v1.Violation{
+				{Pointer: "/name", Code: ErrorProductNameMissing.Error()},
+			}},
+		},
+		{
+			Name:           "store returns invalid data error",
+			RequestBody:    product,
+			MockError:      v1.ErrorInvalidData,
+			ExpectedCode:   http.StatusBadRequest,
+			ExpectedUpsert: true,
+		},
+		{
+			Name:           "store returns an unknown error",
+			RequestBody:    product,
+			MockError:      errors.New("error"),
+			ExpectedCode:   http.StatusInternalServerError,
+			ExpectedUpsert: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			store := new(MockStore)
+			server := NewServer(store)
+
+			store.On("UpsertProduct", mock.Anything, product).Return(test.MockCreated, test.MockError)
+
+			jsonBytes, err := json.Marshal(test.RequestBody)
+			assert.NoError(t, err)
+			request := httptest.NewRequest(http.MethodPut, "/", bytes.NewReader(jsonBytes))
+			request.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			response := httptest.NewRecorder()
+			c := echo.New().NewContext(request, response)
+			c.SetParamNames("ean")
+			c.SetParamValues("12345678")
+
+			err = server.handleUpsertProduct(c)
+			assert.NoError(t, err)
+			assert.Equal(t, test.ExpectedCode, response.Code)
+			if test.ExpectedUpsert {
+				store.AssertCalled(t, "UpsertProduct", mock.Anything, product)
+			} else {
+				store.AssertNotCalled(t, "UpsertProduct", mock.Anything, mock.Anything)
+			}
+			if test.ExpectedBody != nil {
+				var obj v1.ErrorResponse
+				err = json.NewDecoder(response.Body).Decode(&obj)
+				assert.NoError(t, err)
+				assert.Equal(t, *test.ExpectedBody, obj)
+			} else {
+				assert.Equal(t, 0, len(response.Body.Bytes()))
+			}
+		})
+	}
+}
+
+func TestHandleValidateProducts(t *testing.T) {
+	product := v1.Product{
+		Ean:       "12345678",
+		Name:      "Product name",
+		Packaging: v1.Quantity{Value: 12, Unit: "g"},
+		Nutrition: v1.Nutrition{
+			Per:  v1.Quantity{Value: 100, Unit: "g"},
+			Kcal: 17,
+			Nutrients: []v1.Nutrient{
+				{T: "PROTEIN", Quantity: v1.Quantity{Value: 1, Unit: "g"}},
+				{T: "CARBOHYDRATES", Quantity: v1.Quantity{Value: 1, Unit: "g"}},
+				{T: "FAT", Quantity: v1.Quantity{Value: 1, Unit: "g"}},
+			},
+		},
+	}
+	unnamed := product
+	unnamed.Ean = "87654321"
+	unnamed.Name = ""
+
+	tests := []struct {
+		Name         string
+		Body         string
+		Existing     []string
+		StoreErr     error
+		ExpectedCode int
+		ExpectedBody any
+	}{
+		{
+			Name:         "valid product",
+			Body:         toJson(t, product),
+			ExpectedCode: http.StatusOK,
+			ExpectedBody: v1.ValidationResult{Ean: product.Ean, Valid: true},
+		},
+		{
+			Name:         "invalid product",
+			Body:         toJson(t, unnamed),
+			ExpectedCode: http.StatusBadRequest,
+			ExpectedBody: v1.ValidationResult{
+				Ean:  unnamed.Ean,
+				Code: ErrorProductNameMissing.Error(),
+				Violations: []v1.Violation{
+					{Pointer: "/name", Code: ErrorProductNameMissing.Error()},
+				},
+			},
+		},
+		{
+			Name:         "product already exists",
+			Body:         toJson(t, product),
+			Existing:     []string{product.Ean},
+			ExpectedCode: http.StatusBadRequest,
+			ExpectedBody: v1.ValidationResult{
+				Ean:  product.Ean,
+				Code: v1.ErrorProductAlreadyExists.Error(),
+				Violations: []v1.Violation{
+					{Pointer: "/ean", Code: v1.ErrorProductAlreadyExists.Error(), Params: map[string]any{"ean": product.Ean}},
+				},
+			},
+		},
+		{
+			Name:         "batch",
+			Body:         toJson(t, []v1.Product{product, unnamed, product}),
+			ExpectedCode: http.StatusOK,
+			ExpectedBody: []v1.ValidationResult{
+				{Ean: product.Ean, Valid: true},
+				{
+					Ean:  unnamed.Ean,
+					Code: ErrorProductNameMissing.Error(),
+					Violations: []v1.Violation{
+						{Pointer: "/name", Code: ErrorProductNameMissing.Error()},
+					},
+				},
+				{
+					Ean:  product.Ean,
+					Code: ErrorProductEanDuplicate.Error(),
+					Violations: []v1.Violation{
+						{Pointer: "/ean", Code: ErrorProductEanDuplicate.Error(), Params: map[string]any{"ean": product.Ean, "index": float64(0)}},
+					},
+				},
+			},
+		},
+		{
+			Name:         "store returns an unknown error",
+			Body:         toJson(t, product),
+			StoreErr:     errors.New("error"),
+			ExpectedCode: http.StatusInternalServerError,
+		},
+		{
+			Name:         "malformed body",
+			Body:         "{",
+			ExpectedCode: http.StatusBadRequest,
+		},
+		{
+			Name:         "body over the size limit",
+			Body:         "[" + strings.Repeat(" ", maxValidateBytes) + "]",
+			ExpectedCode: http.StatusRequestEntityTooLarge,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			store := new(MockStore)
+			server := NewServer(store)
+
+			existing := make(map[string]v1.Product)
+			for _, ean := range test.Existing {
+				existing[ean] = v1.Product{Ean: ean}
+			}
+			store.On("GetProducts", mock.Anything, mock.Anything).Return(existing, test.StoreErr)
+
+			request := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(test.Body))
+			request.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			response := httptest.NewRecorder()
+			c := echo.New().NewContext(request, response)
+
+			err := server.handleValidateProducts(c)
+			assert.NoError(t, err)
+			assert.Equal(t, test.ExpectedCode, response.Code)
+			store.AssertNotCalled(t, "CreateProduct", mock.Anything, mock.Anything)
+			store.AssertNotCalled(t, "GetProduct", mock.Anything, mock.Anything)
+			assert.LessOrEqual(t, len(store.Calls), 1, "existing products are read in one call")
+			if test.ExpectedBody != nil {
+				assert.JSONEq(t, toJson(t, test.ExpectedBody), response.Body.String())
+			}
+		})
+	}
+}
+
+func toJson(t *testing.T, value any) string {
+	jsonBytes, err := json.Marshal(value)
+	assert.NoError(t, err)
+	return string(jsonBytes)
+}
+
 func TestHandleDeleteProduct(t *testing.T) {
 	tests := []struct {
 		Name         string
@@ -449,6 +1097,11 @@ func TestHandleDeleteProduct(t *testing.T) {
 			MockError:    v1.ErrorProductDoesNotExist,
 			ExpectedCode: http.StatusNotFound,
 		},
+		{
+			Name:         "product is a component of a multipack",
+			MockError:    v1.ErrorProductInUse,
+			ExpectedCode: http.StatusConflict,
+		},
 		{
 			Name:         "store returns unknown error",
 			MockError:    errors.New("error"),
@@ -474,6 +1127,350 @@ func TestHandleDeleteProduct(t *testing.T) {
 	}
 }
 
+func TestHandleGetBarcode(t *testing.T) {
+	tests := []struct {
+		Name         string
+		Code         string
+		ExpectedCode int
+		ExpectedBody any
+	}{
+		{
+			Name:         "describes polish ean-13",
+			Code:         "5901234123457",
+			ExpectedCode: http.StatusOK,
+			ExpectedBody: &v1.Barcode{
+				Code:    "5901234123457",
+				Format:  "EAN_13",
+				Prefix:  "590",
+				Country: "Poland",
+				Range:   "GTIN",
+			},
+		},
+		{
+			Name:         "describes code without product",
+			Code:         "12345678",
+			ExpectedCode: http.StatusOK,
+			ExpectedBody: &testBarcode,
+		},
+		{
+			Name:         "invalid code",
+			Code:         "123",
+			ExpectedCode: http.StatusBadRequest,
+			ExpectedBody: &v1.ErrorResponse{Code: "EAN_INVALID"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			store := new(MockStore)
+			server := NewServer(store)
+
+			request := httptest.NewRequest(http.MethodGet, "/", nil)
+			response := httptest.NewRecorder()
+			c := echo.New().NewContext(request, response)
+			c.SetParamNames("code")
+			c.SetParamValues(test.Code)
+
+			err := server.handleGetBarcode(c)
+			assert.NoError(t, err)
+			assert.Equal(t, test.ExpectedCode, response.Code)
+			expectedJson, err := json.Marshal(test.ExpectedBody)
+			assert.NoError(t, err)
+			assert.JSONEq(t, string(expectedJson), response.Body.String())
+			store.AssertNotCalled(t, "GetProduct", mock.Anything, mock.Anything)
+		})
+	}
+}
+
+func TestHandleResolveBarcode(t *testing.T) {
+	product := v1.Product{Ean: "5901234123457", Name: "Product name"}
+	productWithBarcode := product
+	productWithBarcode.Barcode = &v1.Barcode{
+		Code:    "5901234123457",
+		Format:  "EAN_13",
+		Prefix:  "590",
+		Country: "Poland",
+		Range:   "GTIN",
+	}
+
+	tests := []struct {
+		Name         string
+		Data         string
+		MockValue    v1.Product
+		MockError    error
+		ExpectedCode int
+		ExpectedBody any
+	}{
+		{
+			Name:         "resolves product",
+			Data:         "(01)05901234123457(10)ABC",
+			MockValue:    product,
+			ExpectedCode: http.StatusOK,
+			ExpectedBody: v1.Resolution{
+				Gtin: "05901234123457",
+				Elements: []v1.ApplicationIdentifier{
+					{Ai: "01", Title: "GTIN", Value: "05901234123457"},
+					{Ai: "10", Title: "BATCH/LOT", Value: "ABC"},
+				},
+				Product: &productWithBarcode,
+			},
+		},
+		{
+			Name:         "product not found",
+			Data:         "(01)05901234123457(15)251231",
+			MockError:    v1.ErrorDataNotFound,
+			ExpectedCode: http.StatusOK,
+			ExpectedBody: v1.Resolution{
+				Gtin: "05901234123457",
+				Elements: []v1.ApplicationIdentifier{
+					{Ai: "01", Title: "GTIN", Value: "05901234123457"},
+					{Ai: "15", Title: "BEST BEFORE", Value: "251231", Date: "2025-12-31"},
+				},
+			},
+		},
+		{
+			Name:         "store returns unknown error",
+			Data:         "(01)05901234123457",
+			MockError:    errors.New("error"),
+			ExpectedCode: http.StatusInternalServerError,
+		},
+		{
+			Name:         "gtin missing",
+			Data:         "(10)ABC",
+			ExpectedCode: http.StatusBadRequest,
+			ExpectedBody: v1.ErrorResponse{Code: ErrorGtinMissing.Error()},
+		},
+		{
+			Name:         "data invalid",
+			Data:         "(99)ABC",
+			ExpectedCode: http.StatusBadRequest,
+			ExpectedBody: v1.ErrorResponse{Code: "GS1_AI_UNKNOWN"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			store := new(MockStore)
+			server := NewServer(store)
+
+			store.On("GetProduct", mock.Anything, "5901234123457").Return(test.MockValue, test.MockError)
+
+			jsonBytes, err := json.Marshal(v1.ResolveRequest{Data: test.Data})
+			assert.NoError(t, err)
+			request := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(jsonBytes))
+			request.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			response := httptest.NewRecorder()
+			c := echo.New().NewContext(request, response)
+
+			err = server.handleResolveBarcode(c)
+			assert.NoError(t, err)
+			assert.Equal(t, test.ExpectedCode, response.Code)
+			if test.ExpectedBody != nil {
+				expectedJson, err := json.Marshal(test.ExpectedBody)
+				assert.NoError(t, err)
+				assert.JSONEq(t, string(expectedJson), response.Body.String())
+			} else {
+				assert.Equal(t, 0, len(response.Body.Bytes()))
+			}
+		})
+	}
+}
+
+func TestHandleGetBarcodeImage(t *testing.T) {
+	tests := []struct {
+		Name                string
+		Png                 bool
+		Query               string
+		MockValue           v1.Product
+		MockError           error
+		ExpectedCode        int
+		ExpectedContentType string
+	}{
+		{
+			Name:                "renders svg",
+			MockValue:           v1.Product{Ean: "5901234123457"},
+			ExpectedCode:        http.StatusOK,
+			ExpectedContentType: "image/svg+xml",
+		},
+		{
+			Name:                "renders png with dpi",
+			Png:                 true,
+			Query:               "?dpi=300",
+			MockValue:           v1.Product{Ean: "96385074"},
+			ExpectedCode:        http.StatusOK,
+			ExpectedContentType: "image/png",
+		},
+		{
+			Name:         "size too large",
+			Query:        "?size=100",
+			MockValue:    v1.Product{Ean: "5901234123457"},
+			ExpectedCode: http.StatusBadRequest,
+		},
+		{
+			Name:         "dpi too small",
+			Png:          true,
+			Query:        "?dpi=10",
+			MockValue:    v1.Product{Ean: "5901234123457"},
+			ExpectedCode: http.StatusBadRequest,
+		},
+		{
+			Name:         "product has invalid check digit",
+			MockValue:    v1.Product{Ean: "12345678"},
+			ExpectedCode: http.StatusBadRequest,
+		},
+		{
+			Name:         "product not found",
+			MockError:    v1.ErrorDataNotFound,
+			ExpectedCode: http.StatusNotFound,
+		},
+		{
+			Name:         "store returns unknown error",
+			MockError:    errors.New("error"),
+			ExpectedCode: http.StatusInternalServerError,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			store := new(MockStore)
+			server := NewServer(store)
+
+			store.On("GetProduct", mock.Anything, mock.Anything).Return(test.MockValue, test.MockError)
+
+			request := httptest.NewRequest(http.MethodGet, "/"+test.Query, nil)
+			response := httptest.NewRecorder()
+			c := echo.New().NewContext(request, response)
+
+			var err error
+			if test.Png {
+				err = server.handleGetBarcodePng(c)
+			} else {
+				err = server.handleGetBarcodeSvg(c)
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, test.ExpectedCode, response.Code)
+			if test.ExpectedContentType != "" {
+				assert.Equal(t, test.ExpectedContentType, response.Header().Get(echo.HeaderContentType))
+				assert.NotEmpty(t, response.Body.Bytes())
+			}
+		})
+	}
+}
+
+func TestHandleDecodeBarcode(t *testing.T) {
+	symbol, err := ean.Encode("5901234123457")
+	assert.NoError(t, err)
+	barcodePng, err := symbol.Png(3)
+	assert.NoError(t, err)
+
+	var blank bytes.Buffer
+	assert.NoError(t, png.Encode(&blank, image.NewGray(image.Rect(0, 0, 50, 50))))
+
+	var oversized bytes.Buffer
+	assert.NoError(t, png.Encode(&oversized, image.NewGray(image.Rect(0, 0, 1, 1))))
+	binary.BigEndian.PutUint32(oversized.Bytes()[16:], 60000)
+	binary.BigEndian.PutUint32(oversized.Bytes()[20:], 60000)
+	binary.BigEndian.PutUint32(oversized.Bytes()[29:], crc32.ChecksumIEEE(oversized.Bytes()[12:29]))
+
+	tests := []struct {
+		Name            string
+		Query           string
+		Body            []byte
+		Multipart       bool
+		MockValue       v1.Product
+		MockError       error
+		ExpectedCode    int
+		ExpectedCodeVal string
+		ExpectedProduct bool
+	}{
+		{
+			Name:            "decodes raw body",
+			Body:            barcodePng,
+			ExpectedCode:    http.StatusOK,
+			ExpectedCodeVal: "5901234123457",
+		},
+		{
+			Name:            "decodes multipart upload with product",
+			Query:           "?product=true",
+			Body:            barcodePng,
+			Multipart:       true,
+			MockValue:       v1.Product{Ean: "5901234123457", Name: "Product name"},
+			ExpectedCode:    http.StatusOK,
+			ExpectedCodeVal: "5901234123457",
+			ExpectedProduct: true,
+		},
+		{
+			Name:            "product not found",
+			Query:           "?product=true",
+			Body:            barcodePng,
+			MockError:       v1.ErrorDataNotFound,
+			ExpectedCode:    http.StatusOK,
+			ExpectedCodeVal: "5901234123457",
+		},
+		{
+			Name:         "store returns unknown error",
+			Query:        "?product=true",
+			Body:         barcodePng,
+			MockError:    errors.New("error"),
+			ExpectedCode: http.StatusInternalServerError,
+		},
+		{
+			Name:         "no barcode in image",
+			Body:         blank.Bytes(),
+			ExpectedCode: http.StatusUnprocessableEntity,
+		},
+		{
+			Name:         "not an image",
+			Body:         []byte("text"),
+			ExpectedCode: http.StatusBadRequest,
+		},
+		{
+			Name:         "image declares too many pixels",
+			Body:         oversized.Bytes(),
+			ExpectedCode: http.StatusRequestEntityTooLarge,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			store := new(MockStore)
+			server := NewServer(store)
+
+			store.On("GetProduct", mock.Anything, "5901234123457").Return(test.MockValue, test.MockError)
+
+			var request *http.Request
+			if test.Multipart {
+				var body bytes.Buffer
+				writer := multipart.NewWriter(&body)
+				part, err := writer.CreateFormFile("image", "barcode.png")
+				assert.NoError(t, err)
+				_, err = part.Write(test.Body)
+				assert.NoError(t, err)
+				assert.NoError(t, writer.Close())
+				request = httptest.NewRequest(http.MethodPost, "/"+test.Query, &body)
+				request.Header.Set(echo.HeaderContentType, writer.FormDataContentType())
+			} else {
+				request = httptest.NewRequest(http.MethodPost, "/"+test.Query, bytes.NewReader(test.Body))
+				request.Header.Set(echo.HeaderContentType, "image/png")
+			}
+			response := httptest.NewRecorder()
+			c := echo.New().NewContext(request, response)
+
+			err := server.handleDecodeBarcode(c)
+			assert.NoError(t, err)
+			assert.Equal(t, test.ExpectedCode, response.Code)
+			if test.ExpectedCode == http.StatusOK {
+				var obj v1.DecodedBarcode
+				err = json.NewDecoder(response.Body).Decode(&obj)
+				assert.NoError(t, err)
+				assert.Equal(t, test.ExpectedCodeVal, obj.Code)
+				assert.NotNil(t, obj.Barcode)
+				assert.Equal(t, test.ExpectedProduct, obj.Product != nil)
+			}
+		})
+	}
+}
+
 type MockStore struct {
 	mock.Mock
 }
@@ -483,6 +1480,11 @@ func (s *MockStore) GetProduct(ctx context.Context, ean string) (v1.Product, err
 	return args.Get(0).(v1.Product), args.Error(1)
 }
 
+func (s *MockStore) GetProducts(ctx context.Context, eans []string) (map[string]v1.Product, error) {
+	args := s.Called(ctx, eans)
+	return args.Get(0).(map[string]v1.Product), args.Error(1)
+}
+
 func (s *MockStore) SearchProducts(ctx context.Context, query string, limit int8) ([]v1.Product, error) {
 	args := s.Called(ctx, query, limit)
 	return args.Get(0).([]v1.Product), args.Error(1)
@@ -498,6 +1500,16 @@ func (s *MockStore) UpdateProduct(ctx context.Context, product v1.Product) error
 	return args.Error(0)
 }
 
+func (s *MockStore) UpsertProduct(ctx context.Context, product v1.Product) (bool, error) {
+	args := s.Called(ctx, product)
+	return args.Bool(0), args.Error(1)
+}
+
+func (s *MockStore) UpsertProducts(ctx context.Context, products []v1.Product) error {
+	args := s.Called(ctx, products)
+	return args.Error(0)
+}
+
 func (s *MockStore) DeleteProduct(ctx context.Context, ean string) error {
 	args := s.Called(ctx, ean)
 	return args.Error(0)
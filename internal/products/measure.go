@@ -0,0 +1,88 @@
+package products
+
+import (
+	"github.com/Kobietka/product-service/internal/ean"
+	"github.com/Kobietka/product-service/internal/types"
+	v1 "github.com/Kobietka/product-service/pkg/api/v1"
+	"github.com/Kobietka/product-service/pkg/array"
+	"math"
+)
+
+var baseUnits = map[string]struct {
+	Dimension types.Dimension
+	Factor    float64
+}{
+	"kg": {Dimension: types.DimensionMass, Factor: 1000},
+	"g":  {Dimension: types.DimensionMass, Factor: 1},
+	"mg": {Dimension: types.DimensionMass, Factor: 0.001},
+	"µg": {Dimension: types.DimensionMass, Factor: 0.000001},
+	"l":  {Dimension: types.DimensionVolume, Factor: 1000},
+	"ml": {Dimension: types.DimensionVolume, Factor: 1},
+}
+
+// toBase converts a quantity to grams or millilitres.
+func toBase(quantity v1.Quantity) (float64, types.Dimension, bool) {
+	unit, ok := baseUnits[quantity.Unit]
+	if !ok {
+		return 0, "", false
+	}
+	return float64(quantity.Value) * unit.Factor, unit.Dimension, true
+}
+
+func toGrams(quantity v1.Quantity) (float64, bool) {
+	value, dimension, ok := toBase(quantity)
+	if !ok || dimension != types.DimensionMass {
+		return 0, false
+	}
+	return value, true
+}
+
+func withMeasure(product v1.Product, variableMeasure ean.VariableMeasure) v1.Product {
+	measure := v1.Measure{
+		Code:    variableMeasure.Code,
+		Article: variableMeasure.Article,
+	}
+
+	switch variableMeasure.Measure {
+	case ean.MeasureWeight:
+		weight := v1.Quantity{Value: float32(variableMeasure.Value), Unit: "kg"}
+		measure.Weight = &weight
+		measure.Nutrition = scaleNutrition(product.Nutrition, weight)
+	case ean.MeasurePrice:
+		price := float32(variableMeasure.Value)
+		measure.Price = &price
+	}
+
+	product.Measure = &measure
+	return product
+}
+
+func scaleNutrition(nutrition v1.Nutrition, to v1.Quantity) *v1.Nutrition {
+	per, perDimension, ok := toBase(nutrition.Per)
+	if !ok || per == 0 {
+		return nil
+	}
+	toValue, toDimension, ok := toBase(to)
+	if !ok || toDimension != perDimension {
+		return nil
+	}
+	factor := toValue / per
+
+	scale := func(quantity v1.Quantity) v1.Quantity {
+		return v1.Quantity{Value: float32(float64(quantity.Value) * factor), Unit: quantity.Unit}
+	}
+
+	return &v1.Nutrition{
+		Per:  to,
+		Kcal: int32(math.Round(float64(nutrition.Kcal) * factor)),
+		Nutrients: array.MapArray(nutrition.Nutrients, func(nutrient v1.Nutrient) v1.Nutrient {
+			return v1.Nutrient{T: nutrient.T, Quantity: scale(nutrient.Quantity)}
+		}),
+		Vitamins: array.MapArray(nutrition.Vitamins, func(vitamin v1.Vitamin) v1.Vitamin {
+			return v1.Vitamin{T: vitamin.T, Quantity: scale(vitamin.Quantity)}
+		}),
+		Minerals: array.MapArray(nutrition.Minerals, func(mineral v1.Mineral) v1.Mineral {
+			return v1.Mineral{T: mineral.T, Quantity: scale(mineral.Quantity)}
+		}),
+	}
+}
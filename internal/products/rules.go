@@ -0,0 +1,265 @@
+package products
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/Kobietka/product-service/internal/types"
+	v1 "github.com/Kobietka/product-service/pkg/api/v1"
+	"io"
+	"maps"
+	"slices"
+	"strconv"
+)
+
+type Severity string
+
+const (
+	SeverityError   Severity = "error"
+	SeverityWarning Severity = "warning"
+)
+
+var (
+	ErrorRuleUnknown         = errors.New("VALIDATION_RULE_UNKNOWN")
+	ErrorRuleSeverityInvalid = errors.New("VALIDATION_RULE_SEVERITY_INVALID")
+)
+
+// Rule is a named check of a product. Params holds the values configured for
+// the rule, a rule falls back to its defaults for missing ones.
+type Rule interface {
+	Name() string
+	Validate(product v1.Product, params Params) Violations
+}
+
+type ruleFunc struct {
+	name     string
+	validate func(product v1.Product, params Params) Violations
+}
+
+func (r ruleFunc) Name() string {
+	return r.name
+}
+
+func (r ruleFunc) Validate(product v1.Product, params Params) Violations {
+	return r.validate(product, params)
+}
+
+func NewRule(name string, validate func(product v1.Product, params Params) Violations) Rule {
+	return ruleFunc{name: name, validate: validate}
+}
+
+// Env holds what the rules checking a product against the service need, the
+// rules needing a field that is not set are skipped.
+type Env struct {
+	Store        Store
+	Dictionaries *types.Dictionaries
+}
+
+// EnvRule is a rule that needs the catalogue or the dictionaries. The error is
+// only set when the store fails.
+type EnvRule interface {
+	Rule
+	ValidateEnv(ctx context.Context, env Env, product v1.Product, params Params) (Violations, error)
+}
+
+type envRuleFunc struct {
+	name     string
+	validate func(ctx context.Context, env Env, product v1.Product, params Params) (Violations, error)
+}
+
+func (r envRuleFunc) Name() string {
+	return r.name
+}
+
+func (r envRuleFunc) Validate(product v1.Product, params Params) Violations {
+	violations, _ := r.validate(context.Background(), Env{}, product, params)
+	return violations
+}
+
+func (r envRuleFunc) ValidateEnv(ctx context.Context, env Env, product v1.Product, params Params) (Violations, error) {
+	return r.validate(ctx, env, product, params)
+}
+
+func NewEnvRule(name string, validate func(ctx context.Context, env Env, product v1.Product, params Params) (Violations, error)) Rule {
+	return envRuleFunc{name: name, validate: validate}
+}
+
+type Params map[string]any
+
+func (p Params) Float(key string, fallback float64) float64 {
+	switch value := p[key].(type) {
+	case float64:
+		return value
+	case int:
+		return float64(value)
+	case string:
+		if parsed, err := strconv.ParseFloat(value, 64); err == nil {
+			return parsed
+		}
+	}
+	return fallback
+}
+
+func (p Params) Strings(key string, fallback []string) []string {
+	switch value := p[key].(type) {
+	case []string:
+		return value
+	case []any:
+		values := make([]string, 0, len(value))
+		for _, item := range value {
+			if s, ok := item.(string); ok {
+				values = append(values, s)
+			}
+		}
+		return values
+	}
+	return fallback
+}
+
+// RuleConfig changes how a registered rule is applied. Unset fields keep the
+// current configuration, params are merged key by key.
+type RuleConfig struct {
+	Enabled  *bool    `json:"enabled,omitempty"`
+	Severity Severity `json:"severity,omitempty"`
+	Params   Params   `json:"params,omitempty"`
+}
+
+// ReadRuleConfigs decodes a JSON object of rule configs keyed by rule name.
+func ReadRuleConfigs(reader io.Reader) (map[string]RuleConfig, error) {
+	var configs map[string]RuleConfig
+	decoder := json.NewDecoder(reader)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&configs); err != nil {
+		return nil, err
+	}
+	if configs == nil {
+		configs = map[string]RuleConfig{}
+	}
+	return configs, nil
+}
+
+// Rules is a registry of rules applied in registration order. It is a value,
+// WithRule and Configure return modified copies.
+type Rules struct {
+	rules   []Rule
+	configs map[string]RuleConfig
+}
+
+func NewRules(rules ...Rule) Rules {
+	registry := Rules{configs: map[string]RuleConfig{}}
+	for _, rule := range rules {
+		registry = registry.WithRule(rule)
+	}
+	return registry
+}
+
+// WithRule registers a rule, replacing a registered one with the same name.
+func (r Rules) WithRule(rule Rule) Rules {
+	index := slices.IndexFunc(r.rules, func(registered Rule) bool {
+		return registered.Name() == rule.Name()
+	})
+	r.rules = slices.Clone(r.rules)
+	if index >= 0 {
+		r.rules[index] = rule
+	} else {
+		r.rules = append(r.rules, rule)
+	}
+	return r
+}
+
+func (r Rules) Configure(configs map[string]RuleConfig) (Rules, error) {
+	merged := maps.Clone(r.configs)
+	if merged == nil {
+		merged = map[string]RuleConfig{}
+	}
+
+	for name, config := range configs {
+		if !r.has(name) {
+			return r, fmt.Errorf("%w: %s", ErrorRuleUnknown, name)
+		}
+		if config.Severity != "" && config.Severity != SeverityError && config.Severity != SeverityWarning {
+			return r, fmt.Errorf("%w: %s", ErrorRuleSeverityInvalid, config.Severity)
+		}
+
+		current := merged[name]
+		if config.Enabled != nil {
+			current.Enabled = config.Enabled
+		}
+		if config.Severity != "" {
+			current.Severity = config.Severity
+		}
+		if len(config.Params) > 0 {
+			params := maps.Clone(current.Params)
+			if params == nil {
+				params = Params{}
+			}
+			maps.Copy(params, config.Params)
+			current.Params = params
+		}
+		merged[name] = current
+	}
+
+	r.configs = merged
+	return r, nil
+}
+
+// Validate collects the violations of every enabled rule that does not need
+// an Env. The returned error is either nil or Violations, violations of
+// warning rules are marked with SeverityWarning.
+func (r Rules) Validate(product v1.Product) error {
+	violations, _ := r.Check(context.Background(), product, Env{})
+	return violations.err()
+}
+
+// Check collects the violations of every enabled rule like Validate, rules
+// implementing EnvRule are given env. The error is only set when the store
+// fails.
+func (r Rules) Check(ctx context.Context, product v1.Product, env Env) (Violations, error) {
+	var violations Violations
+	for _, rule := range r.rules {
+		config := r.configs[rule.Name()]
+		if config.Enabled != nil && !*config.Enabled {
+			continue
+		}
+
+		var ruleViolations Violations
+		if envRule, ok := rule.(EnvRule); ok {
+			var err error
+			ruleViolations, err = envRule.ValidateEnv(ctx, env, product, config.Params)
+			if err != nil {
+				return nil, err
+			}
+		} else {
+			ruleViolations = rule.Validate(product, config.Params)
+		}
+		if config.Severity == SeverityWarning {
+			for i := range ruleViolations {
+				ruleViolations[i].Severity = SeverityWarning
+			}
+		}
+		violations.merge(ruleViolations)
+	}
+	return violations, nil
+}
+
+func (r Rules) has(name string) bool {
+	return slices.ContainsFunc(r.rules, func(rule Rule) bool {
+		return rule.Name() == name
+	})
+}
+
+// IsWarning reports whether err only holds violations of warning rules, the
+// product is accepted then.
+func IsWarning(err error) bool {
+	var violations Violations
+	if !errors.As(err, &violations) || len(violations) == 0 {
+		return false
+	}
+	for _, violation := range violations {
+		if violation.Severity != SeverityWarning {
+			return false
+		}
+	}
+	return true
+}
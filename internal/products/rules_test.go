@@ -0,0 +1,315 @@
+package products
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	v1 "github.com/Kobietka/product-service/pkg/api/v1"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+var supplement = v1.Product{
+	Ean:       "12345678",
+	Name:      "Vitamin C",
+	Packaging: v1.Quantity{Value: 100, Unit: "g"},
+	Nutrition: v1.Nutrition{
+		Per:  v1.Quantity{Value: 1, Unit: "g"},
+		Kcal: 4,
+		Nutrients: []v1.Nutrient{
+			{T: CarbohydratesType, Quantity: v1.Quantity{Value: 0.9, Unit: "g"}},
+		},
+		Vitamins: []v1.Vitamin{
+			{T: "VITAMIN_C", Quantity: v1.Quantity{Value: 100, Unit: "mg"}},
+		},
+	},
+}
+
+func TestRulesConfigure(t *testing.T) {
+	disabled := false
+
+	tests := []struct {
+		Name               string
+		Configs            map[string]RuleConfig
+		Product            v1.Product
+		ExpectedViolations Violations
+		ExpectedErr        error
+	}{
+		{
+			Name:    "default rules",
+			Product: supplement,
+			ExpectedViolations: Violations{
+				{Pointer: "/nutrition/nutrients", Err: ErrorFatMissing, Params: map[string]any{"type": FatType}},
+				{Pointer: "/nutrition/nutrients", Err: ErrorProteinMissing, Params: map[string]any{"type": ProteinType}},
+			},
+		},
+		{
+			Name:    "rule disabled",
+			Configs: map[string]RuleConfig{RuleRequiredNutrients: {Enabled: &disabled}},
+			Product: supplement,
+		},
+		{
+			Name:    "rule parameterised",
+			Configs: map[string]RuleConfig{RuleRequiredNutrients: {Params: Params{"types": []any{CarbohydratesType, SaltType}}}},
+			Product: supplement,
+			ExpectedViolations: Violations{
+				{Pointer: "/nutrition/nutrients", Err: ErrorNutrientMissing, Params: map[string]any{"type": SaltType}},
+			},
+		},
+		{
+			Name:    "rule downgraded to warning",
+			Configs: map[string]RuleConfig{RuleRequiredNutrients: {Severity: SeverityWarning}},
+			Product: supplement,
+			ExpectedViolations: Violations{
+				{Pointer: "/nutrition/nutrients", Err: ErrorFatMissing, Params: map[string]any{"type": FatType}, Severity: SeverityWarning},
+				{Pointer: "/nutrition/nutrients", Err: ErrorProteinMissing, Params: map[string]any{"type": ProteinType}, Severity: SeverityWarning},
+			},
+		},
+		{
+			Name:        "unknown rule",
+			Configs:     map[string]RuleConfig{"colour": {Enabled: &disabled}},
+			ExpectedErr: ErrorRuleUnknown,
+		},
+		{
+			Name:        "invalid severity",
+			Configs:     map[string]RuleConfig{RuleEnergy: {Severity: "fatal"}},
+			ExpectedErr: ErrorRuleSeverityInvalid,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			rules, err := DefaultRules.Configure(test.Configs)
+			assert.ErrorIs(t, err, test.ExpectedErr)
+			if test.ExpectedErr != nil {
+				return
+			}
+
+			err = rules.Validate(test.Product)
+			if test.ExpectedViolations == nil {
+				assert.NoError(t, err)
+				return
+			}
+			assert.Equal(t, test.ExpectedViolations, err)
+		})
+	}
+}
+
+func TestRulesCheck(t *testing.T) {
+	disabled := false
+	product := supplement
+	product.Packaging = v1.Quantity{Value: 1, Unit: "kcal"}
+	product.Nutrition.Nutrients = append(product.Nutrition.Nutrients,
+		v1.Nutrient{T: FatType, Quantity: v1.Quantity{Value: 0, Unit: "g"}},
+		v1.Nutrient{T: ProteinType, Quantity: v1.Quantity{Value: 0, Unit: "g"}},
+	)
+	product.Nutrition.Vitamins = []v1.Vitamin{{T: "VITAMIN_X", Quantity: v1.Quantity{Value: 100, Unit: "mg"}}}
+	product.Components = []v1.Component{{Ean: "12345670", Count: 1}}
+
+	dimension := Violation{Pointer: "/packaging/unit", Err: ErrorPackagingUnitInvalid, Params: map[string]any{"unit": "kcal", "dimension": "ENERGY"}}
+	dictionary := Violation{Pointer: "/nutrition/vitamins/0/type", Err: ErrorVitaminTypeUnknown, Params: map[string]any{"type": "VITAMIN_X"}}
+	composition := Violation{Pointer: "/components/0/ean", Err: ErrorComponentNotFound, Params: map[string]any{"ean": "12345670"}}
+	warning := func(violation Violation) Violation {
+		violation.Severity = SeverityWarning
+		return violation
+	}
+
+	tests := []struct {
+		Name               string
+		Configs            map[string]RuleConfig
+		Env                Env
+		StoreErr           error
+		ExpectedViolations Violations
+		ExpectedErr        error
+	}{
+		{
+			Name:               "every rule",
+			Env:                Env{Dictionaries: &testDictionaries},
+			ExpectedViolations: Violations{dimension, dictionary, composition},
+		},
+		{
+			Name:               "without env",
+			ExpectedViolations: nil,
+		},
+		{
+			Name: "rules disabled",
+			Configs: map[string]RuleConfig{
+				RuleUnitDimensions: {Enabled: &disabled},
+				RuleDictionaries:   {Enabled: &disabled},
+				RuleComposition:    {Enabled: &disabled},
+			},
+			Env: Env{Dictionaries: &testDictionaries},
+		},
+		{
+			Name: "rules downgraded to warnings",
+			Configs: map[string]RuleConfig{
+				RuleDictionaries: {Severity: SeverityWarning},
+				RuleComposition:  {Severity: SeverityWarning},
+			},
+			Env:                Env{Dictionaries: &testDictionaries},
+			ExpectedViolations: Violations{dimension, warning(dictionary), warning(composition)},
+		},
+		{
+			Name:        "store fails",
+			Env:         Env{Dictionaries: &testDictionaries},
+			StoreErr:    errors.New("err"),
+			ExpectedErr: errors.New("err"),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			store := new(MockStore)
+			storeErr := test.StoreErr
+			if storeErr == nil {
+				storeErr = v1.ErrorDataNotFound
+			}
+			store.On("GetProduct", mock.Anything, "12345670").Return(v1.Product{}, storeErr)
+			if test.Env.Dictionaries != nil {
+				test.Env.Store = store
+			}
+
+			rules, err := DefaultRules.Configure(test.Configs)
+			assert.NoError(t, err)
+
+			violations, err := rules.Check(context.Background(), product, test.Env)
+			assert.Equal(t, test.ExpectedErr, err)
+			assert.Equal(t, test.ExpectedViolations, violations)
+		})
+	}
+}
+
+func TestRulesConfigureMerges(t *testing.T) {
+	rules, err := DefaultRules.Configure(map[string]RuleConfig{RuleEnergy: {Severity: SeverityWarning}})
+	assert.NoError(t, err)
+	rules, err = rules.Configure(map[string]RuleConfig{RuleEnergy: {Params: Params{"tolerance": 0.5}}})
+	assert.NoError(t, err)
+
+	assert.Equal(t, RuleConfig{Severity: SeverityWarning, Params: Params{"tolerance": 0.5}}, rules.configs[RuleEnergy])
+	assert.Empty(t, DefaultRules.configs)
+}
+
+func TestRulesWithRule(t *testing.T) {
+	brand := NewRule("brand", func(product v1.Product, params Params) Violations {
+		var violations Violations
+		brand := params.Strings("brands", []string{"Acme"})[0]
+		if !strings.HasPrefix(product.Name, brand) {
+			violations.add("/name", errors.New("PRODUCT_BRAND_MISSING"), "brand", brand)
+		}
+		return violations
+	})
+	noEan := NewRule(RuleEan, func(v1.Product, Params) Violations {
+		return nil
+	})
+
+	rules := DefaultRules.WithRule(brand).WithRule(noEan)
+	rules, err := rules.Configure(map[string]RuleConfig{"brand": {Severity: SeverityWarning}})
+	assert.NoError(t, err)
+
+	product := supplement
+	product.Ean = "1234"
+	product.Nutrition.Nutrients = append(product.Nutrition.Nutrients,
+		v1.Nutrient{T: FatType, Quantity: v1.Quantity{Value: 0, Unit: "g"}},
+		v1.Nutrient{T: ProteinType, Quantity: v1.Quantity{Value: 0, Unit: "g"}},
+	)
+
+	err = rules.Validate(product)
+	assert.True(t, IsWarning(err))
+	assert.EqualError(t, err, "PRODUCT_BRAND_MISSING")
+	assert.Len(t, DefaultRules.rules, len(rules.rules)-1)
+}
+
+func TestReadRuleConfigs(t *testing.T) {
+	configs, err := ReadRuleConfigs(strings.NewReader(`{
+		"required-nutrients": {"enabled": false},
+		"energy": {"severity": "warning", "params": {"tolerance": 0.3}}
+	}`))
+	assert.NoError(t, err)
+	disabled := false
+	assert.Equal(t, map[string]RuleConfig{
+		RuleRequiredNutrients: {Enabled: &disabled},
+		RuleEnergy:            {Severity: SeverityWarning, Params: Params{"tolerance": 0.3}},
+	}, configs)
+
+	configs, err = ReadRuleConfigs(strings.NewReader(`null`))
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]RuleConfig{}, configs, "null reads as no configs")
+
+	_, err = ReadRuleConfigs(strings.NewReader(`{"energy": {"tolerance": 0.3}}`))
+	assert.Error(t, err)
+}
+
+func TestIsWarning(t *testing.T) {
+	warning := Violation{Pointer: "/nutrition/kcal", Err: ErrorNutritionEnergyMismatch, Severity: SeverityWarning}
+	failure := Violation{Pointer: "/name", Err: ErrorProductNameMissing}
+
+	assert.True(t, IsWarning(Violations{warning}))
+	assert.False(t, IsWarning(Violations{warning, failure}))
+	assert.False(t, IsWarning(Violations{}))
+	assert.False(t, IsWarning(ErrorNutritionEnergyMismatch))
+	assert.False(t, IsWarning(nil))
+}
+
+func TestHandlePostProductWarnings(t *testing.T) {
+	rules, err := DefaultRules.Configure(map[string]RuleConfig{RuleRequiredNutrients: {Severity: SeverityWarning}})
+	assert.NoError(t, err)
+
+	tests := []struct {
+		Name         string
+		Product      v1.Product
+		ExpectedCode int
+		ExpectedBody *v1.ErrorResponse
+	}{
+		{
+			Name:         "warnings only",
+			Product:      supplement,
+			ExpectedCode: http.StatusCreated,
+		},
+		{
+			Name: "warnings and errors",
+			Product: v1.Product{
+				Ean:       supplement.Ean,
+				Packaging: supplement.Packaging,
+				Nutrition: supplement.Nutrition,
+			},
+			ExpectedCode: http.StatusBadRequest,
+			ExpectedBody: &v1.ErrorResponse{Code: ErrorProductNameMissing.Error(), Violations: []v1.Violation{
+				{Pointer: "/name", Code: ErrorProductNameMissing.Error()},
+				{Pointer: "/nutrition/nutrients", Code: ErrorFatMissing.Error(), Params: map[string]any{"type": FatType}, Severity: "warning"},
+				{Pointer: "/nutrition/nutrients", Code: ErrorProteinMissing.Error(), Params: map[string]any{"type": ProteinType}, Severity: "warning"},
+			}},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			store := new(MockStore)
+			server := NewServer(store).WithRules(rules)
+
+			store.On("CreateProduct", mock.Anything, mock.Anything).Return(nil)
+
+			jsonBytes, err := json.Marshal(test.Product)
+			assert.NoError(t, err)
+			request := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(jsonBytes))
+			request.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			response := httptest.NewRecorder()
+			c := echo.New().NewContext(request, response)
+
+			err = server.handlePostProduct(c)
+			assert.NoError(t, err)
+			assert.Equal(t, test.ExpectedCode, response.Code)
+			if test.ExpectedBody != nil {
+				var obj v1.ErrorResponse
+				err = json.NewDecoder(response.Body).Decode(&obj)
+				assert.NoError(t, err)
+				assert.Equal(t, *test.ExpectedBody, obj)
+			}
+		})
+	}
+}
@@ -1,21 +1,51 @@
 package products
 
 import (
+	"github.com/Kobietka/product-service/internal/ean"
+	"github.com/Kobietka/product-service/internal/types"
 	"github.com/labstack/echo/v4"
 )
 
 type Server struct {
-	store Store
+	store            Store
+	measureTemplates []ean.Template
+	rules            Rules
+	types            types.Store
 }
 
 func NewServer(store Store) Server {
-	return Server{store: store}
+	return Server{store: store, measureTemplates: ean.DefaultTemplates, rules: DefaultRules}
+}
+
+func (s Server) WithMeasureTemplates(templates []ean.Template) Server {
+	s.measureTemplates = templates
+	return s
+}
+
+func (s Server) WithRules(rules Rules) Server {
+	s.rules = rules
+	return s
+}
+
+// WithTypes enables validation of units and types against the dictionaries,
+// the store is expected to be cached.
+func (s Server) WithTypes(store types.Store) Server {
+	s.types = store
+	return s
 }
 
 func (s Server) Routes(e *echo.Echo) {
 	e.GET("/products/:ean", s.handleGetProduct)
 	e.GET("/products", s.handleSearchProduct)
 	e.POST("/products", s.handlePostProduct)
+	e.POST("/products/validate", s.handleValidateProducts)
+	e.POST("/products/lookup", s.handleLookupProducts)
 	e.PUT("/products", s.handlePutProduct)
+	e.PUT("/products/:ean", s.handleUpsertProduct)
 	e.DELETE("/products/:ean", s.handleDeleteProduct)
+	e.GET("/products/:ean/barcode.svg", s.handleGetBarcodeSvg)
+	e.GET("/products/:ean/barcode.png", s.handleGetBarcodePng)
+	e.GET("/barcodes/:code", s.handleGetBarcode)
+	e.POST("/barcodes/resolve", s.handleResolveBarcode)
+	e.POST("/barcodes/decode", s.handleDecodeBarcode)
 }
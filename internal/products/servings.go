@@ -0,0 +1,31 @@
+package products
+
+import (
+	v1 "github.com/Kobietka/product-service/pkg/api/v1"
+	"math"
+)
+
+// withServings fills in how many of every serving fit into the package and
+// the nutrition of a single serving, scaled from the declared basis.
+func withServings(product v1.Product) v1.Product {
+	if len(product.Servings) == 0 {
+		return product
+	}
+
+	packaging, packagingDimension, packagingOk := toBase(product.Packaging)
+	servings := make([]v1.Serving, 0, len(product.Servings))
+	for _, serving := range product.Servings {
+		serving.PerPackage = nil
+		serving.Nutrition = scaleNutrition(product.Nutrition, serving.Quantity)
+
+		value, dimension, ok := toBase(serving.Quantity)
+		if ok && packagingOk && dimension == packagingDimension && value > 0 {
+			perPackage := float32(math.Round(packaging/value*10) / 10)
+			serving.PerPackage = &perPackage
+		}
+		servings = append(servings, serving)
+	}
+
+	product.Servings = servings
+	return product
+}
@@ -0,0 +1,133 @@
+package products
+
+import (
+	v1 "github.com/Kobietka/product-service/pkg/api/v1"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestWithServings(t *testing.T) {
+	bar := v1.Product{
+		Ean:       "5901234123457",
+		Name:      "Protein bar",
+		Packaging: v1.Quantity{Value: 0.18, Unit: "kg"},
+		Nutrition: v1.Nutrition{
+			Per:  v1.Quantity{Value: 100, Unit: "g"},
+			Kcal: 400,
+			Nutrients: []v1.Nutrient{
+				{T: "PROTEIN", Quantity: v1.Quantity{Value: 20, Unit: "g"}},
+			},
+			Vitamins: []v1.Vitamin{},
+			Minerals: []v1.Mineral{
+				{T: "IRON", Quantity: v1.Quantity{Value: 10, Unit: "mg"}},
+			},
+		},
+	}
+
+	tests := []struct {
+		Name             string
+		Product          v1.Product
+		Servings         []v1.Serving
+		ExpectedServings []v1.Serving
+	}{
+		{
+			Name:             "product without servings",
+			Product:          bar,
+			Servings:         nil,
+			ExpectedServings: nil,
+		},
+		{
+			Name:    "single bar",
+			Product: bar,
+			Servings: []v1.Serving{
+				{Name: "1 bar", Quantity: v1.Quantity{Value: 45, Unit: "g"}},
+			},
+			ExpectedServings: []v1.Serving{
+				{
+					Name:       "1 bar",
+					Quantity:   v1.Quantity{Value: 45, Unit: "g"},
+					PerPackage: float32Pointer(4),
+					Nutrition: &v1.Nutrition{
+						Per:  v1.Quantity{Value: 45, Unit: "g"},
+						Kcal: 180,
+						Nutrients: []v1.Nutrient{
+							{T: "PROTEIN", Quantity: v1.Quantity{Value: 9, Unit: "g"}},
+						},
+						Vitamins: []v1.Vitamin{},
+						Minerals: []v1.Mineral{
+							{T: "IRON", Quantity: v1.Quantity{Value: 4.5, Unit: "mg"}},
+						},
+					},
+				},
+			},
+		},
+		{
+			Name:    "computed fields sent by the client are replaced",
+			Product: bar,
+			Servings: []v1.Serving{
+				{Name: "half a bar", Quantity: v1.Quantity{Value: 0.0225, Unit: "kg"}, PerPackage: float32Pointer(1)},
+			},
+			ExpectedServings: []v1.Serving{
+				{
+					Name:       "half a bar",
+					Quantity:   v1.Quantity{Value: 0.0225, Unit: "kg"},
+					PerPackage: float32Pointer(8),
+					Nutrition: &v1.Nutrition{
+						Per:  v1.Quantity{Value: 0.0225, Unit: "kg"},
+						Kcal: 90,
+						Nutrients: []v1.Nutrient{
+							{T: "PROTEIN", Quantity: v1.Quantity{Value: 4.5, Unit: "g"}},
+						},
+						Vitamins: []v1.Vitamin{},
+						Minerals: []v1.Mineral{
+							{T: "IRON", Quantity: v1.Quantity{Value: 2.25, Unit: "mg"}},
+						},
+					},
+				},
+			},
+		},
+		{
+			Name:    "serving in another dimension",
+			Product: bar,
+			Servings: []v1.Serving{
+				{Name: "glass", Quantity: v1.Quantity{Value: 250, Unit: "ml"}},
+			},
+			ExpectedServings: []v1.Serving{
+				{Name: "glass", Quantity: v1.Quantity{Value: 250, Unit: "ml"}},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			product := test.Product
+			product.Servings = test.Servings
+
+			result := withServings(product)
+			assert.Equal(t, len(test.ExpectedServings), len(result.Servings))
+			for i, expected := range test.ExpectedServings {
+				actual := result.Servings[i]
+				assert.Equal(t, expected.Name, actual.Name)
+				assert.Equal(t, expected.Quantity, actual.Quantity)
+				assert.Equal(t, expected.PerPackage, actual.PerPackage)
+				if expected.Nutrition == nil {
+					assert.Nil(t, actual.Nutrition)
+					continue
+				}
+				assert.Equal(t, expected.Nutrition.Per, actual.Nutrition.Per)
+				assert.Equal(t, expected.Nutrition.Kcal, actual.Nutrition.Kcal)
+				assertQuantitiesInDelta(t, expected.Nutrition.Nutrients[0].Quantity, actual.Nutrition.Nutrients[0].Quantity)
+				assertQuantitiesInDelta(t, expected.Nutrition.Minerals[0].Quantity, actual.Nutrition.Minerals[0].Quantity)
+			}
+		})
+	}
+}
+
+func float32Pointer(value float32) *float32 {
+	return &value
+}
+
+func assertQuantitiesInDelta(t *testing.T, expected, actual v1.Quantity) {
+	assert.Equal(t, expected.Unit, actual.Unit)
+	assert.InDelta(t, expected.Value, actual.Value, 0.001)
+}
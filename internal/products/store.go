@@ -7,8 +7,15 @@ import (
 
 type Store interface {
 	GetProduct(ctx context.Context, ean string) (v1.Product, error)
+	// GetProducts reads the products of the EANs, EANs without a product are
+	// missing from the map.
+	GetProducts(ctx context.Context, eans []string) (map[string]v1.Product, error)
 	SearchProducts(ctx context.Context, query string, limit int8) ([]v1.Product, error)
 	CreateProduct(ctx context.Context, product v1.Product) error
 	UpdateProduct(ctx context.Context, product v1.Product) error
+	UpsertProduct(ctx context.Context, product v1.Product) (created bool, err error)
+	// UpsertProducts writes all the products in one unit of work or none of
+	// them, a product may use the products before it as components.
+	UpsertProducts(ctx context.Context, products []v1.Product) error
 	DeleteProduct(ctx context.Context, ean string) error
 }
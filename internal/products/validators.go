@@ -1,10 +1,13 @@
 package products
 
 import (
+	"context"
 	"errors"
 	"github.com/Kobietka/product-service/internal/ean"
+	"github.com/Kobietka/product-service/internal/types"
 	v1 "github.com/Kobietka/product-service/pkg/api/v1"
 	"github.com/Kobietka/product-service/pkg/text"
+	"strings"
 )
 
 var (
@@ -13,57 +16,249 @@ var (
 	ErrorProductNameMissing = errors.New("PRODUCT_NAME_MISSING")
 )
 
-func validateProduct(product v1.Product) error {
+const (
+	RuleEan               = "ean"
+	RuleName              = "name"
+	RulePackaging         = "packaging"
+	RuleComponents        = "components"
+	RuleServings          = "servings"
+	RuleNutrition         = "nutrition"
+	RuleRequiredNutrients = "required-nutrients"
+	RuleNutrientSubtypes  = "nutrient-subtypes"
+	RuleMassBalance       = "mass-balance"
+	RuleEnergy            = "energy"
+	RuleUnitDimensions    = "unit-dimensions"
+	RuleDictionaries      = "dictionaries"
+	RuleComposition       = "composition"
+)
+
+// DefaultRules collects every violation of the product instead of stopping at
+// the first one. Rules comparing nutrients with each other or with the basis
+// skip nutrition that is not valid itself, otherwise a single mistake would be
+// reported several times. The rules checking units and types against the
+// dictionaries and components against the catalogue need an Env.
+var DefaultRules = NewRules(
+	NewRule(RuleEan, checkEan),
+	NewRule(RuleName, checkName),
+	NewRule(RulePackaging, checkPackaging),
+	NewRule(RuleComponents, checkComponents),
+	NewRule(RuleServings, checkServings),
+	NewRule(RuleNutrition, checkNutrition),
+	NewRule(RuleRequiredNutrients, checkRequiredNutrients),
+	NewRule(RuleNutrientSubtypes, checkNutrientSubtypes),
+	NewRule(RuleMassBalance, checkMassBalance),
+	NewRule(RuleEnergy, checkEnergy),
+	NewEnvRule(RuleUnitDimensions, checkUnitDimensions),
+	NewEnvRule(RuleDictionaries, checkDictionaries),
+	NewEnvRule(RuleComposition, checkComposition),
+)
+
+func checkEan(product v1.Product, _ Params) Violations {
+	var violations Violations
 	if text.IsBlankString(product.Ean) {
-		return ErrorProductEanMissing
+		violations.add("/ean", ErrorProductEanMissing)
+	} else if !ean.IsValid(product.Ean) {
+		violations.add("/ean", ErrorProductEanInvalid, "ean", product.Ean)
 	}
+	return violations
+}
 
-	if !ean.IsValid(product.Ean) {
-		return ErrorProductEanInvalid
+func checkName(product v1.Product, _ Params) Violations {
+	var violations Violations
+	if text.IsBlankString(product.Name) {
+		violations.add("/name", ErrorProductNameMissing)
 	}
+	return violations
+}
 
-	if text.IsBlankString(product.Name) {
-		return ErrorProductNameMissing
+func checkPackaging(product v1.Product, _ Params) Violations {
+	return validateQuantity("/packaging", product.Packaging)
+}
+
+func checkComponents(product v1.Product, _ Params) Violations {
+	return validateComponents("/components", product)
+}
+
+func checkServings(product v1.Product, _ Params) Violations {
+	return validateServings("/servings", product)
+}
+
+func checkNutrition(product v1.Product, _ Params) Violations {
+	if !hasNutrition(product) {
+		return nil
 	}
+	return validateNutrition("/nutrition", product.Nutrition)
+}
 
-	err := validateQuantity(product.Packaging)
-	if err != nil {
-		return err
+func checkRequiredNutrients(product v1.Product, params Params) Violations {
+	if !hasNutrition(product) {
+		return nil
 	}
+	required := params.Strings("types", defaultRequiredNutrients)
+	return validateRequiredNutrients("/nutrition/nutrients", product.Nutrition.Nutrients, required)
+}
 
-	return validateNutrition(product.Nutrition)
+func checkNutrientSubtypes(product v1.Product, _ Params) Violations {
+	if !hasNutrition(product) || len(validateNutrients("/nutrition/nutrients", product.Nutrition.Nutrients)) > 0 {
+		return nil
+	}
+	return validateNutrientSubtypes("/nutrition/nutrients", product.Nutrition.Nutrients)
 }
 
-var (
-	ErrorNutritionKcalInvalid = errors.New("NUTRITION_KCAL_INVALID")
-)
+func checkMassBalance(product v1.Product, _ Params) Violations {
+	if !hasComparableNutrition(product) {
+		return nil
+	}
+	return validateMassBalance("/nutrition", product.Nutrition)
+}
 
-func validateNutrition(nutrition v1.Nutrition) error {
-	err := validateQuantity(nutrition.Per)
-	if err != nil {
-		return err
+func checkEnergy(product v1.Product, params Params) Violations {
+	if !hasComparableNutrition(product) || product.Nutrition.Kcal < 0 {
+		return nil
 	}
+	return validateEnergy("/nutrition", product.Nutrition, params.Float("tolerance", DefaultEnergyTolerance))
+}
 
-	if nutrition.Kcal < 0 {
-		return ErrorNutritionKcalInvalid
+func checkUnitDimensions(_ context.Context, env Env, product v1.Product, _ Params) (Violations, error) {
+	if env.Dictionaries == nil {
+		return nil, nil
 	}
+	return validateUnitDimensions(product, env.Dictionaries.Units), nil
+}
 
-	err = validateNutrients(nutrition.Nutrients)
-	if err != nil {
-		return err
+func checkDictionaries(_ context.Context, env Env, product v1.Product, _ Params) (Violations, error) {
+	if env.Dictionaries == nil {
+		return nil, nil
 	}
+	return validateDictionaries(product, *env.Dictionaries), nil
+}
 
-	err = validateVitamins(nutrition.Vitamins)
-	if err != nil {
-		return err
+// checkComposition looks the components up only when they are valid
+// themselves.
+func checkComposition(ctx context.Context, env Env, product v1.Product, _ Params) (Violations, error) {
+	if env.Store == nil || len(validateComponents("/components", product)) > 0 {
+		return nil, nil
 	}
 
-	err = validateMinerals(nutrition.Minerals)
-	if err != nil {
-		return err
+	var violations Violations
+	err := validateComposition(ctx, env.Store, product)
+	if errors.As(err, &violations) {
+		return violations, nil
+	}
+	return nil, err
+}
+
+// hasNutrition is false for multipacks that leave their nutrition to be
+// resolved from the components.
+func hasNutrition(product v1.Product) bool {
+	return len(product.Components) == 0 || !product.Nutrition.IsEmpty()
+}
+
+func hasComparableNutrition(product v1.Product) bool {
+	return hasNutrition(product) &&
+		len(validateQuantity("/nutrition/per", product.Nutrition.Per)) == 0 &&
+		len(validateNutrients("/nutrition/nutrients", product.Nutrition.Nutrients)) == 0
+}
+
+var (
+	ErrorComponentEanInvalid   = errors.New("COMPONENT_EAN_INVALID")
+	ErrorComponentCountInvalid = errors.New("COMPONENT_COUNT_INVALID")
+	ErrorComponentDuplicate    = errors.New("COMPONENT_DUPLICATE")
+	ErrorComponentCycle        = errors.New("COMPONENT_CYCLE")
+)
+
+func validateComponents(path string, product v1.Product) Violations {
+	var violations Violations
+	seen := make(map[string]bool)
+	for i, component := range product.Components {
+		if !ean.IsValid(component.Ean) {
+			violations.add(pointer(path, i, "ean"), ErrorComponentEanInvalid, "ean", component.Ean)
+		} else if component.Ean == product.Ean {
+			violations.add(pointer(path, i, "ean"), ErrorComponentCycle, "ean", component.Ean)
+		} else if seen[component.Ean] {
+			violations.add(pointer(path, i, "ean"), ErrorComponentDuplicate, "ean", component.Ean)
+		}
+		seen[component.Ean] = true
+
+		if component.Count <= 0 {
+			violations.add(pointer(path, i, "count"), ErrorComponentCountInvalid, "count", component.Count)
+		}
+	}
+	return violations
+}
+
+var (
+	ErrorServingNameMissing      = errors.New("SERVING_NAME_MISSING")
+	ErrorServingDuplicate        = errors.New("SERVING_DUPLICATE")
+	ErrorServingQuantityInvalid  = errors.New("SERVING_QUANTITY_INVALID")
+	ErrorServingUnitMismatch     = errors.New("SERVING_UNIT_MISMATCH")
+	ErrorServingExceedsPackaging = errors.New("SERVING_EXCEEDS_PACKAGING")
+)
+
+func validateServings(path string, product v1.Product) Violations {
+	var violations Violations
+	packaging, packagingDimension, packagingOk := toBase(product.Packaging)
+	seen := make(map[string]bool)
+	for i, serving := range product.Servings {
+		if text.IsBlankString(serving.Name) {
+			violations.add(pointer(path, i, "name"), ErrorServingNameMissing)
+		} else {
+			name := strings.ToLower(strings.TrimSpace(serving.Name))
+			if seen[name] {
+				violations.add(pointer(path, i, "name"), ErrorServingDuplicate, "name", serving.Name)
+			}
+			seen[name] = true
+		}
+
+		quantityPath := pointer(path, i, "quantity")
+		quantityViolations := validateQuantity(quantityPath, serving.Quantity)
+		if len(quantityViolations) > 0 {
+			violations.merge(quantityViolations)
+			continue
+		}
+
+		if serving.Quantity.Value == 0 {
+			violations.add(pointer(quantityPath, "value"), ErrorServingQuantityInvalid)
+			continue
+		}
+
+		value, dimension, ok := toBase(serving.Quantity)
+		if !ok || !packagingOk || dimension != packagingDimension {
+			violations.add(
+				pointer(quantityPath, "unit"), ErrorServingUnitMismatch,
+				"unit", serving.Quantity.Unit, "packagingUnit", product.Packaging.Unit,
+			)
+			continue
+		}
+
+		if value > packaging*(1+packagingToleranceFactor) {
+			violations.add(
+				pointer(quantityPath, "value"), ErrorServingExceedsPackaging,
+				"value", value, "packaging", packaging,
+			)
+		}
+	}
+	return violations
+}
+
+var (
+	ErrorNutritionKcalInvalid = errors.New("NUTRITION_KCAL_INVALID")
+)
+
+func validateNutrition(path string, nutrition v1.Nutrition) Violations {
+	var violations Violations
+
+	violations.merge(validateQuantity(pointer(path, "per"), nutrition.Per))
+
+	if nutrition.Kcal < 0 {
+		violations.add(pointer(path, "kcal"), ErrorNutritionKcalInvalid, "kcal", nutrition.Kcal)
 	}
 
-	return nil
+	violations.merge(validateNutrients(pointer(path, "nutrients"), nutrition.Nutrients))
+	violations.merge(validateVitamins(pointer(path, "vitamins"), nutrition.Vitamins))
+	violations.merge(validateMinerals(pointer(path, "minerals"), nutrition.Minerals))
+
+	return violations
 }
 
 var (
@@ -71,100 +266,278 @@ var (
 	ErrorQuantityValueInvalid = errors.New("QUANTITY_VALUE_INVALID")
 )
 
-func validateQuantity(quantity v1.Quantity) error {
+func validateQuantity(path string, quantity v1.Quantity) Violations {
+	var violations Violations
+
 	if text.IsBlankString(quantity.Unit) {
-		return ErrorQuantityUnitMissing
+		violations.add(pointer(path, "unit"), ErrorQuantityUnitMissing)
 	}
 
 	if quantity.Value < 0 {
-		return ErrorQuantityValueInvalid
+		violations.add(pointer(path, "value"), ErrorQuantityValueInvalid, "value", quantity.Value)
 	}
 
-	return nil
+	return violations
+}
+
+var (
+	ErrorPackagingUnitInvalid     = errors.New("PACKAGING_UNIT_INVALID")
+	ErrorNutritionPerUnitInvalid  = errors.New("NUTRITION_PER_UNIT_INVALID")
+	ErrorNutritionPerUnitMismatch = errors.New("NUTRITION_PER_UNIT_MISMATCH")
+	ErrorNutrientUnitInvalid      = errors.New("NUTRIENT_UNIT_INVALID")
+	ErrorVitaminUnitInvalid       = errors.New("VITAMIN_UNIT_INVALID")
+	ErrorMineralUnitInvalid       = errors.New("MINERAL_UNIT_INVALID")
+)
+
+// Vitamins and minerals are declared in milligrams or micrograms only.
+var micronutrientUnits = map[string]bool{"mg": true, "µg": true}
+
+// validateUnitDimensions checks every unit against the dimension its field
+// allows. Units missing from units are left to validateDictionaries.
+func validateUnitDimensions(product v1.Product, units map[string]types.Dimension) Violations {
+	var violations Violations
+
+	packaging, packagingOk := units[product.Packaging.Unit]
+	if packagingOk && !isAmount(packaging) {
+		violations.add("/packaging/unit", ErrorPackagingUnitInvalid, "unit", product.Packaging.Unit, "dimension", string(packaging))
+		packagingOk = false
+	}
+
+	if len(product.Components) > 0 && product.Nutrition.IsEmpty() {
+		return violations
+	}
+
+	nutrition := product.Nutrition
+	if per, ok := units[nutrition.Per.Unit]; ok {
+		if !isAmount(per) {
+			violations.add("/nutrition/per/unit", ErrorNutritionPerUnitInvalid, "unit", nutrition.Per.Unit, "dimension", string(per))
+		} else if packagingOk && per != packaging {
+			violations.add("/nutrition/per/unit", ErrorNutritionPerUnitMismatch, "unit", nutrition.Per.Unit, "packaging", product.Packaging.Unit)
+		}
+	}
+
+	for i, nutrient := range nutrition.Nutrients {
+		if dimension, ok := units[nutrient.Quantity.Unit]; ok && dimension != types.DimensionMass {
+			violations.add(pointer("/nutrition/nutrients", i, "quantity", "unit"), ErrorNutrientUnitInvalid, "unit", nutrient.Quantity.Unit, "dimension", string(dimension))
+		}
+	}
+	for i, vitamin := range nutrition.Vitamins {
+		if _, ok := units[vitamin.Quantity.Unit]; ok && !micronutrientUnits[vitamin.Quantity.Unit] {
+			violations.add(pointer("/nutrition/vitamins", i, "quantity", "unit"), ErrorVitaminUnitInvalid, "unit", vitamin.Quantity.Unit)
+		}
+	}
+	for i, mineral := range nutrition.Minerals {
+		if _, ok := units[mineral.Quantity.Unit]; ok && !micronutrientUnits[mineral.Quantity.Unit] {
+			violations.add(pointer("/nutrition/minerals", i, "quantity", "unit"), ErrorMineralUnitInvalid, "unit", mineral.Quantity.Unit)
+		}
+	}
+
+	return violations
+}
+
+// isAmount reports whether a dimension measures an amount of product.
+func isAmount(dimension types.Dimension) bool {
+	return dimension == types.DimensionMass || dimension == types.DimensionVolume
 }
 
 const (
-	FatType           = "FAT"
-	CarbohydratesType = "CARBOHYDRATES"
-	ProteinType       = "PROTEIN"
+	FatType                = "FAT"
+	SaturatedFatType       = "SATURATED_FAT"
+	MonoUnsaturatedFatType = "MONO_UNSATURATED_FAT"
+	PolyUnsaturatedFatType = "POLY_UNSATURATED_FAT"
+	TransFatType           = "TRANS_FAT"
+	CarbohydratesType      = "CARBOHYDRATES"
+	SugarType              = "SUGAR"
+	PolyolsType            = "POLYOLS"
+	FiberType              = "FIBER"
+	ProteinType            = "PROTEIN"
+	SaltType               = "SALT"
+	AlcoholType            = "ALCOHOL"
 )
 
+// nutrientTolerance absorbs rounding of label values when nutrients are
+// compared with each other or with the nutrition basis.
+const (
+	nutrientToleranceFactor = 0.01
+	nutrientToleranceGrams  = 0.1
+)
+
+// Nutrients that are part of another one, a subtype never exceeds its
+// parent and the listed subtypes together never exceed it either.
+var nutrientSubtypes = []struct {
+	Parent   string
+	Subtypes []string
+	Err      error
+}{
+	{Parent: FatType, Subtypes: []string{SaturatedFatType, MonoUnsaturatedFatType, PolyUnsaturatedFatType}, Err: ErrorFatSubtypesExceedFat},
+	{Parent: FatType, Subtypes: []string{TransFatType}, Err: ErrorFatSubtypesExceedFat},
+	{Parent: CarbohydratesType, Subtypes: []string{SugarType, PolyolsType}, Err: ErrorSugarsExceedCarbohydrates},
+}
+
+// Nutrients whose masses add up to at most the nutrition basis. Subtypes are
+// already included in their parents.
+var massBalanceTypes = []string{FatType, CarbohydratesType, FiberType, ProteinType, SaltType, AlcoholType}
+
 var (
-	ErrorNutrientTypeMissing  = errors.New("NUTRIENT_TYPE_MISSING")
-	ErrorFatMissing           = errors.New("NUTRIENT_FAT_MISSING")
-	ErrorCarbohydratesMissing = errors.New("NUTRIENT_CARBOHYDRATES_MISSING")
-	ErrorProteinMissing       = errors.New("NUTRIENT_PROTEIN_MISSING")
+	ErrorNutrientTypeMissing       = errors.New("NUTRIENT_TYPE_MISSING")
+	ErrorFatMissing                = errors.New("NUTRIENT_FAT_MISSING")
+	ErrorCarbohydratesMissing      = errors.New("NUTRIENT_CARBOHYDRATES_MISSING")
+	ErrorProteinMissing            = errors.New("NUTRIENT_PROTEIN_MISSING")
+	ErrorNutrientMissing           = errors.New("NUTRIENT_MISSING")
+	ErrorNutrientDuplicate         = errors.New("NUTRIENT_DUPLICATE")
+	ErrorFatSubtypesExceedFat      = errors.New("NUTRIENT_FAT_SUBTYPES_EXCEED_FAT")
+	ErrorSugarsExceedCarbohydrates = errors.New("NUTRIENT_SUGARS_EXCEED_CARBOHYDRATES")
+	ErrorNutritionMassExceeded     = errors.New("NUTRITION_MASS_EXCEEDS_BASIS")
 )
 
-func validateNutrients(nutrients []v1.Nutrient) error {
-	containsFat := false
-	containsCarbohydrates := false
-	containsProteins := false
-	for _, item := range nutrients {
+func validateNutrients(path string, nutrients []v1.Nutrient) Violations {
+	var violations Violations
+	seen := make(map[string]bool)
+	for i, item := range nutrients {
 		if text.IsBlankString(item.T) {
-			return ErrorNutrientTypeMissing
+			violations.add(pointer(path, i, "type"), ErrorNutrientTypeMissing)
+		} else if seen[item.T] {
+			violations.add(pointer(path, i, "type"), ErrorNutrientDuplicate, "type", item.T)
 		}
+		seen[item.T] = true
+
+		violations.merge(validateQuantity(pointer(path, i, "quantity"), item.Quantity))
+	}
+	return violations
+}
+
+var defaultRequiredNutrients = []string{FatType, CarbohydratesType, ProteinType}
+
+// Required nutrients without a code of their own are reported as
+// ErrorNutrientMissing.
+var requiredNutrientErrors = map[string]error{
+	FatType:           ErrorFatMissing,
+	CarbohydratesType: ErrorCarbohydratesMissing,
+	ProteinType:       ErrorProteinMissing,
+}
+
+func validateRequiredNutrients(path string, nutrients []v1.Nutrient, required []string) Violations {
+	var violations Violations
+	for _, t := range required {
+		if nutrientIndex(nutrients, t) >= 0 {
+			continue
+		}
+		err, ok := requiredNutrientErrors[t]
+		if !ok {
+			err = ErrorNutrientMissing
+		}
+		violations.add(path, err, "type", t)
+	}
+	return violations
+}
 
-		err := validateQuantity(item.Quantity)
-		if err != nil {
-			return err
+func validateNutrientSubtypes(path string, nutrients []v1.Nutrient) Violations {
+	var violations Violations
+	grams := nutrientGrams(nutrients)
+	for _, rule := range nutrientSubtypes {
+		parent, ok := grams[rule.Parent]
+		if !ok {
+			continue
 		}
 
-		switch item.T {
-		case FatType:
-			containsFat = true
-		case CarbohydratesType:
-			containsCarbohydrates = true
-		case ProteinType:
-			containsProteins = true
+		total := 0.0
+		for _, subtype := range rule.Subtypes {
+			total += grams[subtype]
+		}
+		if exceeds(total, parent) {
+			index := nutrientIndex(nutrients, rule.Parent)
+			violations.add(
+				pointer(path, index, "quantity", "value"), rule.Err,
+				"type", rule.Parent, "subtypes", rule.Subtypes, "total", total, "limit", parent,
+			)
 		}
 	}
+	return violations
+}
 
-	if !containsFat {
-		return ErrorFatMissing
+// validateMassBalance checks that the nutrients do not weigh more than the
+// basis they are declared for. Bases given by volume are skipped as the
+// density of the product is not known.
+func validateMassBalance(path string, nutrition v1.Nutrition) Violations {
+	basis, ok := toGrams(nutrition.Per)
+	if !ok {
+		return nil
 	}
-	if !containsCarbohydrates {
-		return ErrorCarbohydratesMissing
+
+	grams := nutrientGrams(nutrition.Nutrients)
+	total := 0.0
+	for _, t := range massBalanceTypes {
+		total += grams[t]
 	}
-	if !containsProteins {
-		return ErrorProteinMissing
+
+	var violations Violations
+	if exceeds(total, basis) {
+		violations.add(pointer(path, "per", "value"), ErrorNutritionMassExceeded, "total", total, "limit", basis)
 	}
+	return violations
+}
 
-	return nil
+func nutrientGrams(nutrients []v1.Nutrient) map[string]float64 {
+	grams := make(map[string]float64)
+	for _, nutrient := range nutrients {
+		if value, ok := toGrams(nutrient.Quantity); ok {
+			grams[nutrient.T] += value
+		}
+	}
+	return grams
+}
+
+func nutrientIndex(nutrients []v1.Nutrient, t string) int {
+	for i, nutrient := range nutrients {
+		if nutrient.T == t {
+			return i
+		}
+	}
+	return -1
+}
+
+func exceeds(value, limit float64) bool {
+	return value > limit*(1+nutrientToleranceFactor)+nutrientToleranceGrams
 }
 
 var (
 	ErrorVitaminTypeMissing = errors.New("VITAMIN_TYPE_MISSING")
+	ErrorVitaminDuplicate   = errors.New("VITAMIN_DUPLICATE")
 )
 
-func validateVitamins(vitamins []v1.Vitamin) error {
-	for _, vitamin := range vitamins {
+func validateVitamins(path string, vitamins []v1.Vitamin) Violations {
+	var violations Violations
+	seen := make(map[string]bool)
+	for i, vitamin := range vitamins {
 		if text.IsBlankString(vitamin.T) {
-			return ErrorVitaminTypeMissing
+			violations.add(pointer(path, i, "type"), ErrorVitaminTypeMissing)
+		} else if seen[vitamin.T] {
+			violations.add(pointer(path, i, "type"), ErrorVitaminDuplicate, "type", vitamin.T)
 		}
+		seen[vitamin.T] = true
 
-		err := validateQuantity(vitamin.Quantity)
-		if err != nil {
-			return err
-		}
+		violations.merge(validateQuantity(pointer(path, i, "quantity"), vitamin.Quantity))
 	}
-	return nil
+	return violations
 }
 
 var (
 	ErrorMineralTypeMissing = errors.New("MINERAL_TYPE_MISSING")
+	ErrorMineralDuplicate   = errors.New("MINERAL_DUPLICATE")
 )
 
-func validateMinerals(minerals []v1.Mineral) error {
-	for _, mineral := range minerals {
+func validateMinerals(path string, minerals []v1.Mineral) Violations {
+	var violations Violations
+	seen := make(map[string]bool)
+	for i, mineral := range minerals {
 		if text.IsBlankString(mineral.T) {
-			return ErrorMineralTypeMissing
+			violations.add(pointer(path, i, "type"), ErrorMineralTypeMissing)
+		} else if seen[mineral.T] {
+			violations.add(pointer(path, i, "type"), ErrorMineralDuplicate, "type", mineral.T)
 		}
+		seen[mineral.T] = true
 
-		err := validateQuantity(mineral.Quantity)
-		if err != nil {
-			return err
-		}
+		violations.merge(validateQuantity(pointer(path, i, "quantity"), mineral.Quantity))
 	}
-	return nil
+	return violations
 }
@@ -9,10 +9,10 @@ import (
 func TestValidateProduct(t *testing.T) {
 	correctNutrition := v1.Nutrition{
 		Per: v1.Quantity{
-			Value: 12,
+			Value: 100,
 			Unit:  "g",
 		},
-		Kcal: 123,
+		Kcal: 204,
 		Nutrients: []v1.Nutrient{
 			{
 				T: "PROTEIN",
@@ -135,7 +135,7 @@ func TestValidateProduct(t *testing.T) {
 				Name: "product name",
 				Nutrition: v1.Nutrition{
 					Per: v1.Quantity{
-						Value: 12,
+						Value: 100,
 						Unit:  "",
 					},
 					Kcal: -123,
@@ -155,14 +155,195 @@ func TestValidateProduct(t *testing.T) {
 					Unit:  "g",
 				},
 			},
-			ExpectedErr: validateQuantity(v1.Quantity{Value: -12, Unit: "g"}),
+			ExpectedErr: ErrorQuantityValueInvalid,
+		},
+		{
+			Name: "multipack without nutrition",
+			Product: v1.Product{
+				Ean:        "1234567890123",
+				Name:       "product name",
+				Packaging:  correctPackaging,
+				Components: []v1.Component{{Ean: "12345678", Count: 2}},
+			},
+			ExpectedErr: nil,
+		},
+		{
+			Name: "product without components needs nutrition",
+			Product: v1.Product{
+				Ean:       "1234567890123",
+				Name:      "product name",
+				Packaging: correctPackaging,
+			},
+			ExpectedErr: ErrorQuantityUnitMissing,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			err := DefaultRules.Validate(test.Product)
+			assertValidationError(t, test.ExpectedErr, err)
+		})
+	}
+}
+
+func TestValidateProductReportsAllViolations(t *testing.T) {
+	product := v1.Product{
+		Ean:       "1234",
+		Packaging: v1.Quantity{Value: -1, Unit: "g"},
+		Nutrition: v1.Nutrition{
+			Per:  v1.Quantity{Value: 100, Unit: "g"},
+			Kcal: 100,
+			Nutrients: []v1.Nutrient{
+				{T: "FAT", Quantity: v1.Quantity{Value: 10, Unit: "g"}},
+				{T: "PROTEIN", Quantity: v1.Quantity{Value: 10, Unit: "g"}},
+				{T: "PROTEIN", Quantity: v1.Quantity{Value: 10}},
+			},
+			Vitamins: []v1.Vitamin{
+				{T: "VITAMIN_C", Quantity: v1.Quantity{Value: -1, Unit: "mg"}},
+			},
+		},
+	}
+
+	err := DefaultRules.Validate(product)
+
+	var violations Violations
+	assert.ErrorAs(t, err, &violations)
+	assert.Equal(t, Violations{
+		{Pointer: "/ean", Err: ErrorProductEanInvalid, Params: map[string]any{"ean": "1234"}},
+		{Pointer: "/name", Err: ErrorProductNameMissing},
+		{Pointer: "/packaging/value", Err: ErrorQuantityValueInvalid, Params: map[string]any{"value": float32(-1)}},
+		{Pointer: "/nutrition/nutrients/2/type", Err: ErrorNutrientDuplicate, Params: map[string]any{"type": "PROTEIN"}},
+		{Pointer: "/nutrition/nutrients/2/quantity/unit", Err: ErrorQuantityUnitMissing},
+		{Pointer: "/nutrition/vitamins/0/quantity/value", Err: ErrorQuantityValueInvalid, Params: map[string]any{"value": float32(-1)}},
+		{Pointer: "/nutrition/nutrients", Err: ErrorCarbohydratesMissing, Params: map[string]any{"type": "CARBOHYDRATES"}},
+	}, violations)
+	assert.ErrorIs(t, err, ErrorNutrientDuplicate)
+	assert.Equal(t, "PRODUCT_EAN_INVALID, PRODUCT_NAME_MISSING, QUANTITY_VALUE_INVALID, NUTRIENT_DUPLICATE, "+
+		"QUANTITY_UNIT_MISSING, QUANTITY_VALUE_INVALID, NUTRIENT_CARBOHYDRATES_MISSING", err.Error())
+}
+
+func TestToErrorResponse(t *testing.T) {
+	var violations Violations
+	violations.add("/name", ErrorProductNameMissing)
+	violations.add("/nutrition/kcal", ErrorNutritionKcalInvalid, "kcal", int32(-1))
+
+	assert.Equal(t, v1.ErrorResponse{
+		Code: "PRODUCT_NAME_MISSING",
+		Violations: []v1.Violation{
+			{Pointer: "/name", Code: "PRODUCT_NAME_MISSING"},
+			{Pointer: "/nutrition/kcal", Code: "NUTRITION_KCAL_INVALID", Params: map[string]any{"kcal": int32(-1)}},
+		},
+	}, toErrorResponse(violations))
+	assert.Equal(t, v1.ErrorResponse{Code: "PRODUCT_IN_USE"}, toErrorResponse(v1.ErrorProductInUse))
+}
+
+func TestValidateComponents(t *testing.T) {
+	tests := []struct {
+		Name        string
+		Components  []v1.Component
+		ExpectedErr error
+	}{
+		{
+			Name:        "correct components",
+			Components:  []v1.Component{{Ean: "12345678", Count: 6}, {Ean: "123456789012", Count: 1}},
+			ExpectedErr: nil,
+		},
+		{
+			Name:        "invalid ean",
+			Components:  []v1.Component{{Ean: "1234", Count: 6}},
+			ExpectedErr: ErrorComponentEanInvalid,
+		},
+		{
+			Name:        "zero count",
+			Components:  []v1.Component{{Ean: "12345678", Count: 0}},
+			ExpectedErr: ErrorComponentCountInvalid,
+		},
+		{
+			Name:        "negative count",
+			Components:  []v1.Component{{Ean: "12345678", Count: -1}},
+			ExpectedErr: ErrorComponentCountInvalid,
+		},
+		{
+			Name:        "contains itself",
+			Components:  []v1.Component{{Ean: "1234567890123", Count: 1}},
+			ExpectedErr: ErrorComponentCycle,
+		},
+		{
+			Name:        "duplicate component",
+			Components:  []v1.Component{{Ean: "12345678", Count: 1}, {Ean: "12345678", Count: 2}},
+			ExpectedErr: ErrorComponentDuplicate,
 		},
 	}
 
 	for _, test := range tests {
 		t.Run(test.Name, func(t *testing.T) {
-			err := validateProduct(test.Product)
-			assert.Equal(t, test.ExpectedErr, err)
+			err := validateComponents("/components", v1.Product{Ean: "1234567890123", Components: test.Components}).err()
+			assertValidationError(t, test.ExpectedErr, err)
+		})
+	}
+}
+
+func TestValidateServings(t *testing.T) {
+	tests := []struct {
+		Name        string
+		Servings    []v1.Serving
+		ExpectedErr error
+	}{
+		{
+			Name: "correct servings",
+			Servings: []v1.Serving{
+				{Name: "1 bar", Quantity: v1.Quantity{Value: 45, Unit: "g"}},
+				{Name: "whole package", Quantity: v1.Quantity{Value: 0.18, Unit: "kg"}},
+			},
+			ExpectedErr: nil,
+		},
+		{
+			Name:        "blank name",
+			Servings:    []v1.Serving{{Name: " ", Quantity: v1.Quantity{Value: 45, Unit: "g"}}},
+			ExpectedErr: ErrorServingNameMissing,
+		},
+		{
+			Name: "duplicate name",
+			Servings: []v1.Serving{
+				{Name: "1 bar", Quantity: v1.Quantity{Value: 45, Unit: "g"}},
+				{Name: "1 Bar ", Quantity: v1.Quantity{Value: 40, Unit: "g"}},
+			},
+			ExpectedErr: ErrorServingDuplicate,
+		},
+		{
+			Name:        "missing unit",
+			Servings:    []v1.Serving{{Name: "1 bar", Quantity: v1.Quantity{Value: 45}}},
+			ExpectedErr: ErrorQuantityUnitMissing,
+		},
+		{
+			Name:        "zero quantity",
+			Servings:    []v1.Serving{{Name: "1 bar", Quantity: v1.Quantity{Value: 0, Unit: "g"}}},
+			ExpectedErr: ErrorServingQuantityInvalid,
+		},
+		{
+			Name:        "volume serving of a solid",
+			Servings:    []v1.Serving{{Name: "1 glass", Quantity: v1.Quantity{Value: 250, Unit: "ml"}}},
+			ExpectedErr: ErrorServingUnitMismatch,
+		},
+		{
+			Name:        "unknown unit",
+			Servings:    []v1.Serving{{Name: "1 bar", Quantity: v1.Quantity{Value: 1, Unit: "piece"}}},
+			ExpectedErr: ErrorServingUnitMismatch,
+		},
+		{
+			Name:        "larger than packaging",
+			Servings:    []v1.Serving{{Name: "2 packages", Quantity: v1.Quantity{Value: 360, Unit: "g"}}},
+			ExpectedErr: ErrorServingExceedsPackaging,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			err := validateServings("/servings", v1.Product{
+				Packaging: v1.Quantity{Value: 180, Unit: "g"},
+				Servings:  test.Servings,
+			}).err()
+			assertValidationError(t, test.ExpectedErr, err)
 		})
 	}
 }
@@ -216,13 +397,21 @@ func TestValidateQuantity(t *testing.T) {
 	}
 	for _, test := range tests {
 		t.Run(test.Name, func(t *testing.T) {
-			err := validateQuantity(test.Quantity)
-			assert.Equal(t, test.ExpectedErr, err)
+			err := validateQuantity("/packaging", test.Quantity).err()
+			assertValidationError(t, test.ExpectedErr, err)
 		})
 	}
 }
 
 func TestValidateNutrition(t *testing.T) {
+	disabled := false
+	nutritionRules, err := DefaultRules.Configure(map[string]RuleConfig{
+		RuleEan:       {Enabled: &disabled},
+		RuleName:      {Enabled: &disabled},
+		RulePackaging: {Enabled: &disabled},
+	})
+	assert.NoError(t, err)
+
 	correctNutrients := []v1.Nutrient{
 		{
 			T: "PROTEIN",
@@ -256,10 +445,10 @@ func TestValidateNutrition(t *testing.T) {
 			Name: "nutrition correct",
 			Nutrition: v1.Nutrition{
 				Per: v1.Quantity{
-					Value: 12,
+					Value: 100,
 					Unit:  "g",
 				},
-				Kcal:      123,
+				Kcal:      204,
 				Nutrients: correctNutrients,
 			},
 			ExpectedErr: nil,
@@ -271,7 +460,7 @@ func TestValidateNutrition(t *testing.T) {
 					Value: -12,
 					Unit:  "g",
 				},
-				Kcal:      123,
+				Kcal:      204,
 				Nutrients: correctNutrients,
 			},
 			ExpectedErr: ErrorQuantityValueInvalid,
@@ -280,7 +469,7 @@ func TestValidateNutrition(t *testing.T) {
 			Name: "kcal invalid",
 			Nutrition: v1.Nutrition{
 				Per: v1.Quantity{
-					Value: 12,
+					Value: 100,
 					Unit:  "g",
 				},
 				Kcal:      -123,
@@ -292,22 +481,38 @@ func TestValidateNutrition(t *testing.T) {
 			Name: "kcal zero",
 			Nutrition: v1.Nutrition{
 				Per: v1.Quantity{
-					Value: 12,
+					Value: 100,
 					Unit:  "g",
 				},
-				Kcal:      0,
-				Nutrients: correctNutrients,
+				Kcal: 0,
+				Nutrients: []v1.Nutrient{
+					{T: "PROTEIN", Quantity: v1.Quantity{Value: 0, Unit: "g"}},
+					{T: "CARBOHYDRATES", Quantity: v1.Quantity{Value: 0, Unit: "g"}},
+					{T: "FAT", Quantity: v1.Quantity{Value: 0.1, Unit: "g"}},
+				},
 			},
 			ExpectedErr: nil,
 		},
+		{
+			Name: "kcal does not match macronutrients",
+			Nutrition: v1.Nutrition{
+				Per: v1.Quantity{
+					Value: 100,
+					Unit:  "g",
+				},
+				Kcal:      123,
+				Nutrients: correctNutrients,
+			},
+			ExpectedErr: ErrorNutritionEnergyMismatch,
+		},
 		{
 			Name: "checks nutrients",
 			Nutrition: v1.Nutrition{
 				Per: v1.Quantity{
-					Value: 12,
+					Value: 100,
 					Unit:  "g",
 				},
-				Kcal: 123,
+				Kcal: 204,
 				Nutrients: []v1.Nutrient{
 					{
 						T: "CARBOHYDRATES",
@@ -331,10 +536,10 @@ func TestValidateNutrition(t *testing.T) {
 			Name: "checks minerals",
 			Nutrition: v1.Nutrition{
 				Per: v1.Quantity{
-					Value: 12,
+					Value: 100,
 					Unit:  "g",
 				},
-				Kcal:      123,
+				Kcal:      204,
 				Nutrients: correctNutrients,
 				Minerals: []v1.Mineral{
 					{
@@ -352,10 +557,10 @@ func TestValidateNutrition(t *testing.T) {
 			Name: "checks vitamins",
 			Nutrition: v1.Nutrition{
 				Per: v1.Quantity{
-					Value: 12,
+					Value: 100,
 					Unit:  "g",
 				},
-				Kcal:      123,
+				Kcal:      204,
 				Nutrients: correctNutrients,
 				Vitamins: []v1.Vitamin{
 					{
@@ -372,8 +577,8 @@ func TestValidateNutrition(t *testing.T) {
 	}
 	for _, test := range tests {
 		t.Run(test.Name, func(t *testing.T) {
-			err := validateNutrition(test.Nutrition)
-			assert.Equal(t, test.ExpectedErr, err)
+			err := nutritionRules.Validate(v1.Product{Nutrition: test.Nutrition})
+			assertValidationError(t, test.ExpectedErr, err)
 		})
 	}
 }
@@ -557,11 +762,232 @@ func TestValidateNutrients(t *testing.T) {
 			Nutrients:   []v1.Nutrient{},
 			ExpectedErr: ErrorFatMissing,
 		},
+		{
+			Name: "duplicate nutrient",
+			Nutrients: []v1.Nutrient{
+				{T: "PROTEIN", Quantity: v1.Quantity{Value: 12, Unit: "g"}},
+				{T: "CARBOHYDRATES", Quantity: v1.Quantity{Value: 12, Unit: "g"}},
+				{T: "FAT", Quantity: v1.Quantity{Value: 12, Unit: "g"}},
+				{T: "PROTEIN", Quantity: v1.Quantity{Value: 10, Unit: "g"}},
+			},
+			ExpectedErr: ErrorNutrientDuplicate,
+		},
+		{
+			Name: "fat subtypes within fat",
+			Nutrients: []v1.Nutrient{
+				{T: "PROTEIN", Quantity: v1.Quantity{Value: 12, Unit: "g"}},
+				{T: "CARBOHYDRATES", Quantity: v1.Quantity{Value: 12, Unit: "g"}},
+				{T: "FAT", Quantity: v1.Quantity{Value: 12, Unit: "g"}},
+				{T: "SATURATED_FAT", Quantity: v1.Quantity{Value: 7, Unit: "g"}},
+				{T: "MONO_UNSATURATED_FAT", Quantity: v1.Quantity{Value: 3000, Unit: "mg"}},
+				{T: "POLY_UNSATURATED_FAT", Quantity: v1.Quantity{Value: 2.05, Unit: "g"}},
+				{T: "TRANS_FAT", Quantity: v1.Quantity{Value: 0.2, Unit: "g"}},
+			},
+			ExpectedErr: nil,
+		},
+		{
+			Name: "saturated fat exceeds fat",
+			Nutrients: []v1.Nutrient{
+				{T: "PROTEIN", Quantity: v1.Quantity{Value: 12, Unit: "g"}},
+				{T: "CARBOHYDRATES", Quantity: v1.Quantity{Value: 12, Unit: "g"}},
+				{T: "FAT", Quantity: v1.Quantity{Value: 12, Unit: "g"}},
+				{T: "SATURATED_FAT", Quantity: v1.Quantity{Value: 13, Unit: "g"}},
+			},
+			ExpectedErr: ErrorFatSubtypesExceedFat,
+		},
+		{
+			Name: "fat subtypes together exceed fat",
+			Nutrients: []v1.Nutrient{
+				{T: "PROTEIN", Quantity: v1.Quantity{Value: 12, Unit: "g"}},
+				{T: "CARBOHYDRATES", Quantity: v1.Quantity{Value: 12, Unit: "g"}},
+				{T: "FAT", Quantity: v1.Quantity{Value: 12, Unit: "g"}},
+				{T: "SATURATED_FAT", Quantity: v1.Quantity{Value: 7, Unit: "g"}},
+				{T: "POLY_UNSATURATED_FAT", Quantity: v1.Quantity{Value: 7, Unit: "g"}},
+			},
+			ExpectedErr: ErrorFatSubtypesExceedFat,
+		},
+		{
+			Name: "sugar exceeds carbohydrates",
+			Nutrients: []v1.Nutrient{
+				{T: "PROTEIN", Quantity: v1.Quantity{Value: 12, Unit: "g"}},
+				{T: "CARBOHYDRATES", Quantity: v1.Quantity{Value: 12, Unit: "g"}},
+				{T: "FAT", Quantity: v1.Quantity{Value: 12, Unit: "g"}},
+				{T: "SUGAR", Quantity: v1.Quantity{Value: 20, Unit: "g"}},
+			},
+			ExpectedErr: ErrorSugarsExceedCarbohydrates,
+		},
+		{
+			Name: "sugar and polyols exceed carbohydrates",
+			Nutrients: []v1.Nutrient{
+				{T: "PROTEIN", Quantity: v1.Quantity{Value: 12, Unit: "g"}},
+				{T: "CARBOHYDRATES", Quantity: v1.Quantity{Value: 12, Unit: "g"}},
+				{T: "FAT", Quantity: v1.Quantity{Value: 12, Unit: "g"}},
+				{T: "SUGAR", Quantity: v1.Quantity{Value: 8, Unit: "g"}},
+				{T: "POLYOLS", Quantity: v1.Quantity{Value: 8, Unit: "g"}},
+			},
+			ExpectedErr: ErrorSugarsExceedCarbohydrates,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			violations := validateNutrients("/nutrition/nutrients", test.Nutrients)
+			violations.merge(validateRequiredNutrients("/nutrition/nutrients", test.Nutrients, defaultRequiredNutrients))
+			violations.merge(validateNutrientSubtypes("/nutrition/nutrients", test.Nutrients))
+			err := violations.err()
+			assertValidationError(t, test.ExpectedErr, err)
+		})
+	}
+}
+
+func TestValidateMassBalance(t *testing.T) {
+	tests := []struct {
+		Name        string
+		Per         v1.Quantity
+		Nutrients   []v1.Nutrient
+		ExpectedErr error
+	}{
+		{
+			Name: "nutrients within basis",
+			Per:  v1.Quantity{Value: 100, Unit: "g"},
+			Nutrients: []v1.Nutrient{
+				{T: "FAT", Quantity: v1.Quantity{Value: 30, Unit: "g"}},
+				{T: "SATURATED_FAT", Quantity: v1.Quantity{Value: 20, Unit: "g"}},
+				{T: "CARBOHYDRATES", Quantity: v1.Quantity{Value: 50, Unit: "g"}},
+				{T: "SUGAR", Quantity: v1.Quantity{Value: 40, Unit: "g"}},
+				{T: "PROTEIN", Quantity: v1.Quantity{Value: 18, Unit: "g"}},
+				{T: "SALT", Quantity: v1.Quantity{Value: 2000, Unit: "mg"}},
+			},
+			ExpectedErr: nil,
+		},
+		{
+			Name: "rounding is accepted",
+			Per:  v1.Quantity{Value: 100, Unit: "g"},
+			Nutrients: []v1.Nutrient{
+				{T: "FAT", Quantity: v1.Quantity{Value: 99.5, Unit: "g"}},
+				{T: "CARBOHYDRATES", Quantity: v1.Quantity{Value: 0.5, Unit: "g"}},
+				{T: "PROTEIN", Quantity: v1.Quantity{Value: 0.5, Unit: "g"}},
+			},
+			ExpectedErr: nil,
+		},
+		{
+			Name: "nutrients exceed basis",
+			Per:  v1.Quantity{Value: 12, Unit: "g"},
+			Nutrients: []v1.Nutrient{
+				{T: "FAT", Quantity: v1.Quantity{Value: 12, Unit: "g"}},
+				{T: "CARBOHYDRATES", Quantity: v1.Quantity{Value: 12, Unit: "g"}},
+				{T: "PROTEIN", Quantity: v1.Quantity{Value: 12, Unit: "g"}},
+			},
+			ExpectedErr: ErrorNutritionMassExceeded,
+		},
+		{
+			Name: "basis in kilograms",
+			Per:  v1.Quantity{Value: 0.1, Unit: "kg"},
+			Nutrients: []v1.Nutrient{
+				{T: "FAT", Quantity: v1.Quantity{Value: 60, Unit: "g"}},
+				{T: "CARBOHYDRATES", Quantity: v1.Quantity{Value: 60, Unit: "g"}},
+				{T: "PROTEIN", Quantity: v1.Quantity{Value: 0, Unit: "g"}},
+			},
+			ExpectedErr: ErrorNutritionMassExceeded,
+		},
+		{
+			Name: "volume basis is skipped",
+			Per:  v1.Quantity{Value: 100, Unit: "ml"},
+			Nutrients: []v1.Nutrient{
+				{T: "FAT", Quantity: v1.Quantity{Value: 0, Unit: "g"}},
+				{T: "CARBOHYDRATES", Quantity: v1.Quantity{Value: 80, Unit: "g"}},
+				{T: "PROTEIN", Quantity: v1.Quantity{Value: 30, Unit: "g"}},
+			},
+			ExpectedErr: nil,
+		},
 	}
+
 	for _, test := range tests {
 		t.Run(test.Name, func(t *testing.T) {
-			err := validateNutrients(test.Nutrients)
-			assert.Equal(t, test.ExpectedErr, err)
+			err := validateMassBalance("/nutrition", v1.Nutrition{Per: test.Per, Nutrients: test.Nutrients}).err()
+			assertValidationError(t, test.ExpectedErr, err)
+		})
+	}
+}
+
+func TestValidateUnitDimensions(t *testing.T) {
+	nutrition := func(per string, nutrient string, vitamin string, mineral string) v1.Nutrition {
+		return v1.Nutrition{
+			Per:       v1.Quantity{Value: 100, Unit: per},
+			Nutrients: []v1.Nutrient{{T: "FAT", Quantity: v1.Quantity{Value: 1, Unit: nutrient}}},
+			Vitamins:  []v1.Vitamin{{T: "VITAMIN_C", Quantity: v1.Quantity{Value: 1, Unit: vitamin}}},
+			Minerals:  []v1.Mineral{{T: "IRON", Quantity: v1.Quantity{Value: 1, Unit: mineral}}},
+		}
+	}
+
+	tests := []struct {
+		Name               string
+		Product            v1.Product
+		ExpectedViolations Violations
+	}{
+		{
+			Name: "mass product",
+			Product: v1.Product{
+				Packaging: v1.Quantity{Value: 1, Unit: "kg"},
+				Nutrition: nutrition("g", "mg", "µg", "mg"),
+			},
+		},
+		{
+			Name: "volume product",
+			Product: v1.Product{
+				Packaging: v1.Quantity{Value: 1, Unit: "l"},
+				Nutrition: nutrition("ml", "g", "mg", "µg"),
+			},
+		},
+		{
+			Name: "unknown and blank units are skipped",
+			Product: v1.Product{
+				Packaging: v1.Quantity{Value: 1, Unit: "kilo"},
+				Nutrition: nutrition("", "gram", "", "l"),
+			},
+			ExpectedViolations: Violations{
+				{Pointer: "/nutrition/minerals/0/quantity/unit", Err: ErrorMineralUnitInvalid, Params: map[string]any{"unit": "l"}},
+			},
+		},
+		{
+			Name: "multipack without nutrition",
+			Product: v1.Product{
+				Packaging:  v1.Quantity{Value: 6, Unit: "pcs"},
+				Components: []v1.Component{{Ean: "12345678", Count: 6}},
+			},
+			ExpectedViolations: Violations{
+				{Pointer: "/packaging/unit", Err: ErrorPackagingUnitInvalid, Params: map[string]any{"unit": "pcs", "dimension": "COUNT"}},
+			},
+		},
+		{
+			Name: "basis does not match packaging",
+			Product: v1.Product{
+				Packaging: v1.Quantity{Value: 1, Unit: "l"},
+				Nutrition: nutrition("g", "g", "mg", "mg"),
+			},
+			ExpectedViolations: Violations{
+				{Pointer: "/nutrition/per/unit", Err: ErrorNutritionPerUnitMismatch, Params: map[string]any{"unit": "g", "packaging": "l"}},
+			},
+		},
+		{
+			Name: "wrong dimensions",
+			Product: v1.Product{
+				Packaging: v1.Quantity{Value: 1, Unit: "kcal"},
+				Nutrition: nutrition("pcs", "ml", "l", "g"),
+			},
+			ExpectedViolations: Violations{
+				{Pointer: "/packaging/unit", Err: ErrorPackagingUnitInvalid, Params: map[string]any{"unit": "kcal", "dimension": "ENERGY"}},
+				{Pointer: "/nutrition/per/unit", Err: ErrorNutritionPerUnitInvalid, Params: map[string]any{"unit": "pcs", "dimension": "COUNT"}},
+				{Pointer: "/nutrition/nutrients/0/quantity/unit", Err: ErrorNutrientUnitInvalid, Params: map[string]any{"unit": "ml", "dimension": "VOLUME"}},
+				{Pointer: "/nutrition/vitamins/0/quantity/unit", Err: ErrorVitaminUnitInvalid, Params: map[string]any{"unit": "l"}},
+				{Pointer: "/nutrition/minerals/0/quantity/unit", Err: ErrorMineralUnitInvalid, Params: map[string]any{"unit": "g"}},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			violations := validateUnitDimensions(test.Product, testDictionaries.Units)
+			assert.Equal(t, test.ExpectedViolations, violations)
 		})
 	}
 }
@@ -629,11 +1055,19 @@ func TestValidateVitamins(t *testing.T) {
 			},
 			ExpectedErr: ErrorQuantityValueInvalid,
 		},
+		{
+			Name: "duplicate vitamin",
+			Vitamins: []v1.Vitamin{
+				{T: "VITAMIN_A", Quantity: v1.Quantity{Value: 12, Unit: "µg"}},
+				{T: "VITAMIN_A", Quantity: v1.Quantity{Value: 10, Unit: "µg"}},
+			},
+			ExpectedErr: ErrorVitaminDuplicate,
+		},
 	}
 	for _, test := range tests {
 		t.Run(test.Name, func(t *testing.T) {
-			err := validateVitamins(test.Vitamins)
-			assert.Equal(t, test.ExpectedErr, err)
+			err := validateVitamins("/nutrition/vitamins", test.Vitamins).err()
+			assertValidationError(t, test.ExpectedErr, err)
 		})
 	}
 }
@@ -701,11 +1135,27 @@ func TestValidateMinerals(t *testing.T) {
 			},
 			ExpectedErr: ErrorQuantityValueInvalid,
 		},
+		{
+			Name: "duplicate mineral",
+			Minerals: []v1.Mineral{
+				{T: "IRON", Quantity: v1.Quantity{Value: 12, Unit: "mg"}},
+				{T: "IRON", Quantity: v1.Quantity{Value: 10, Unit: "mg"}},
+			},
+			ExpectedErr: ErrorMineralDuplicate,
+		},
 	}
 	for _, test := range tests {
 		t.Run(test.Name, func(t *testing.T) {
-			err := validateMinerals(test.Minerals)
-			assert.Equal(t, test.ExpectedErr, err)
+			err := validateMinerals("/nutrition/minerals", test.Minerals).err()
+			assertValidationError(t, test.ExpectedErr, err)
 		})
 	}
 }
+
+func assertValidationError(t *testing.T, expected error, err error) {
+	if expected == nil {
+		assert.NoError(t, err)
+		return
+	}
+	assert.ErrorIs(t, err, expected)
+}
@@ -0,0 +1,106 @@
+package products
+
+import (
+	"errors"
+	"fmt"
+	v1 "github.com/Kobietka/product-service/pkg/api/v1"
+	"strings"
+)
+
+// Violation is a single validation failure. Pointer is a JSON pointer to the
+// offending field of the product, Params carries the values the rule was
+// checked against. Violations without a Severity are errors.
+type Violation struct {
+	Pointer  string
+	Err      error
+	Params   map[string]any
+	Severity Severity
+}
+
+type Violations []Violation
+
+func (v Violations) Error() string {
+	codes := make([]string, len(v))
+	for i, violation := range v {
+		codes[i] = violation.Err.Error()
+	}
+	return strings.Join(codes, ", ")
+}
+
+func (v Violations) Unwrap() []error {
+	errs := make([]error, len(v))
+	for i, violation := range v {
+		errs[i] = violation.Err
+	}
+	return errs
+}
+
+func (v *Violations) add(pointer string, err error, params ...any) {
+	violation := Violation{Pointer: pointer, Err: err}
+	if len(params) > 0 {
+		violation.Params = make(map[string]any, len(params)/2)
+		for i := 0; i+1 < len(params); i += 2 {
+			violation.Params[params[i].(string)] = params[i+1]
+		}
+	}
+	*v = append(*v, violation)
+}
+
+func (v *Violations) merge(violations Violations) {
+	*v = append(*v, violations...)
+}
+
+// under reports whether any violation points into the given field.
+func (v Violations) under(pointer string) bool {
+	for _, violation := range v {
+		if violation.Pointer == pointer || strings.HasPrefix(violation.Pointer, pointer+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+func (v Violations) err() error {
+	if len(v) == 0 {
+		return nil
+	}
+	return v
+}
+
+func pointer(parent string, tokens ...any) string {
+	var builder strings.Builder
+	builder.WriteString(parent)
+	for _, token := range tokens {
+		builder.WriteString("/")
+		builder.WriteString(fmt.Sprint(token))
+	}
+	return builder.String()
+}
+
+// toErrorResponse keeps the code of the first error in Code so clients
+// reading only the code keep working.
+func toErrorResponse(err error) v1.ErrorResponse {
+	var violations Violations
+	if !errors.As(err, &violations) || len(violations) == 0 {
+		return v1.ErrorResponse{Code: err.Error()}
+	}
+
+	response := v1.ErrorResponse{
+		Violations: make([]v1.Violation, len(violations)),
+	}
+	for i, violation := range violations {
+		if response.Code == "" && violation.Severity != SeverityWarning {
+			response.Code = violation.Err.Error()
+		}
+		response.Violations[i] = v1.Violation{
+			Pointer:  violation.Pointer,
+			Code:     violation.Err.Error(),
+			Params:   violation.Params,
+			Severity: string(violation.Severity),
+		}
+	}
+	if response.Code == "" {
+		response.Code = violations[0].Err.Error()
+	}
+	return response
+}
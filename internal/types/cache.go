@@ -0,0 +1,72 @@
+package types
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+const DefaultCacheTtl = 5 * time.Minute
+
+type cacheEntry struct {
+	value   any
+	expires time.Time
+}
+
+// CachedStore keeps the dictionaries in memory for ttl, they only change when
+// the database is seeded. Errors are not cached.
+type CachedStore struct {
+	store   Store
+	ttl     time.Duration
+	now     func() time.Time
+	mutex   sync.Mutex
+	entries map[string]cacheEntry
+}
+
+func NewCachedStore(store Store, ttl time.Duration) *CachedStore {
+	return &CachedStore{
+		store:   store,
+		ttl:     ttl,
+		now:     time.Now,
+		entries: make(map[string]cacheEntry),
+	}
+}
+
+func (s *CachedStore) GetUnits(ctx context.Context) ([]string, error) {
+	return get(ctx, s, "units", s.store.GetUnits)
+}
+
+func (s *CachedStore) GetUnitDimensions(ctx context.Context) (map[string]Dimension, error) {
+	return get(ctx, s, "dimensions", s.store.GetUnitDimensions)
+}
+
+func (s *CachedStore) GetNutrientTypes(ctx context.Context) ([]string, error) {
+	return get(ctx, s, "nutrients", s.store.GetNutrientTypes)
+}
+
+func (s *CachedStore) GetVitaminTypes(ctx context.Context) ([]string, error) {
+	return get(ctx, s, "vitamins", s.store.GetVitaminTypes)
+}
+
+func (s *CachedStore) GetMineralTypes(ctx context.Context) ([]string, error) {
+	return get(ctx, s, "minerals", s.store.GetMineralTypes)
+}
+
+func get[T any](ctx context.Context, s *CachedStore, key string, load func(context.Context) (T, error)) (T, error) {
+	s.mutex.Lock()
+	entry, ok := s.entries[key]
+	s.mutex.Unlock()
+	if ok && s.now().Before(entry.expires) {
+		return entry.value.(T), nil
+	}
+
+	value, err := load(ctx)
+	if err != nil {
+		return *new(T), err
+	}
+
+	s.mutex.Lock()
+	s.entries[key] = cacheEntry{value: value, expires: s.now().Add(s.ttl)}
+	s.mutex.Unlock()
+	return value, nil
+}
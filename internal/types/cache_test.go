@@ -0,0 +1,71 @@
+package types
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"testing"
+	"time"
+)
+
+func TestCachedStore(t *testing.T) {
+	store := new(MockStore)
+	store.On("GetUnits", mock.Anything).Return([]string{"g", "kg"}, nil)
+	store.On("GetUnitDimensions", mock.Anything).Return(map[string]Dimension{"g": DimensionMass}, nil)
+	store.On("GetVitaminTypes", mock.Anything).Return([]string(nil), errors.New("err")).Once()
+	store.On("GetVitaminTypes", mock.Anything).Return([]string{"VITAMIN_C"}, nil)
+
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	cached := NewCachedStore(store, time.Minute)
+	cached.now = func() time.Time { return now }
+
+	units, err := cached.GetUnits(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"g", "kg"}, units)
+
+	_, err = cached.GetUnits(context.Background())
+	assert.NoError(t, err)
+	store.AssertNumberOfCalls(t, "GetUnits", 1)
+
+	now = now.Add(time.Minute)
+	_, err = cached.GetUnits(context.Background())
+	assert.NoError(t, err)
+	store.AssertNumberOfCalls(t, "GetUnits", 2)
+
+	dimensions, err := cached.GetUnitDimensions(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]Dimension{"g": DimensionMass}, dimensions)
+	_, err = cached.GetUnitDimensions(context.Background())
+	assert.NoError(t, err)
+	store.AssertNumberOfCalls(t, "GetUnitDimensions", 1)
+
+	_, err = cached.GetVitaminTypes(context.Background())
+	assert.Error(t, err)
+	vitamins, err := cached.GetVitaminTypes(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"VITAMIN_C"}, vitamins)
+	store.AssertNumberOfCalls(t, "GetVitaminTypes", 2)
+}
+
+func TestLoadDictionaries(t *testing.T) {
+	store := new(MockStore)
+	store.On("GetUnitDimensions", mock.Anything).Return(map[string]Dimension{"g": DimensionMass, "ml": DimensionVolume}, nil)
+	store.On("GetNutrientTypes", mock.Anything).Return([]string{"FAT"}, nil)
+	store.On("GetVitaminTypes", mock.Anything).Return([]string{"VITAMIN_C"}, nil)
+	store.On("GetMineralTypes", mock.Anything).Return([]string{}, nil)
+
+	dictionaries, err := LoadDictionaries(context.Background(), store)
+	assert.NoError(t, err)
+	assert.Equal(t, Dictionaries{
+		Units:         map[string]Dimension{"g": DimensionMass, "ml": DimensionVolume},
+		NutrientTypes: map[string]bool{"FAT": true},
+		VitaminTypes:  map[string]bool{"VITAMIN_C": true},
+		MineralTypes:  map[string]bool{},
+	}, dictionaries)
+
+	failing := new(MockStore)
+	failing.On("GetUnitDimensions", mock.Anything).Return(map[string]Dimension(nil), errors.New("err"))
+	_, err = LoadDictionaries(context.Background(), failing)
+	assert.Error(t, err)
+}
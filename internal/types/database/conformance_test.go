@@ -0,0 +1,34 @@
+package database
+
+import (
+	"github.com/Kobietka/product-service/internal/database/setup"
+	"github.com/Kobietka/product-service/internal/database/storetest"
+	"github.com/Kobietka/product-service/internal/types"
+	"testing"
+)
+
+func TestMemoryStoreConformance(t *testing.T) {
+	storetest.RunTypeStore(t, func(t *testing.T) types.Store {
+		return NewMemoryStore(setup.DefaultFixture())
+	})
+}
+
+func TestSqliteStoreConformance(t *testing.T) {
+	storetest.RunTypeStore(t, func(t *testing.T) types.Store {
+		return NewSqliteStore(storetest.SqliteDb(t))
+	})
+}
+
+func TestCachedStoreConformance(t *testing.T) {
+	storetest.RunTypeStore(t, func(t *testing.T) types.Store {
+		return types.NewCachedStore(NewSqliteStore(storetest.SqliteDb(t)), types.DefaultCacheTtl)
+	})
+}
+
+// TestPostgresStoreConformance runs when DATABASE_URL points at a local
+// Postgres instance.
+func TestPostgresStoreConformance(t *testing.T) {
+	storetest.RunTypeStore(t, func(t *testing.T) types.Store {
+		return NewPostgresStore(storetest.PostgresPool(t))
+	})
+}
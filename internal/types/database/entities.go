@@ -5,6 +5,11 @@ type unitEntity struct {
 	Value string
 }
 
+type unitDimensionEntity struct {
+	Value     string
+	Dimension string
+}
+
 type nutrientTypeEntity struct {
 	Id   int32
 	Type string
@@ -0,0 +1,53 @@
+package database
+
+import (
+	"context"
+	"github.com/Kobietka/product-service/internal/database/setup"
+	"github.com/Kobietka/product-service/internal/types"
+	"github.com/Kobietka/product-service/pkg/array"
+	"slices"
+)
+
+// MemoryStore serves the dictionaries of a fixture. They never change, so it
+// is safe for concurrent use without locking.
+type MemoryStore struct {
+	units         []setup.FixtureUnit
+	nutrientTypes []string
+	vitaminTypes  []string
+	mineralTypes  []string
+}
+
+func NewMemoryStore(fixture setup.Fixture) MemoryStore {
+	return MemoryStore{
+		units:         slices.Clone(fixture.Units),
+		nutrientTypes: slices.Clone(fixture.NutrientTypes),
+		vitaminTypes:  slices.Clone(fixture.VitaminTypes),
+		mineralTypes:  slices.Clone(fixture.MineralTypes),
+	}
+}
+
+func (s MemoryStore) GetUnits(context.Context) ([]string, error) {
+	return array.MapArray(s.units, func(unit setup.FixtureUnit) string {
+		return unit.Value
+	}), nil
+}
+
+func (s MemoryStore) GetUnitDimensions(context.Context) (map[string]types.Dimension, error) {
+	dimensions := make(map[string]types.Dimension, len(s.units))
+	for _, unit := range s.units {
+		dimensions[unit.Value] = unit.Dimension
+	}
+	return dimensions, nil
+}
+
+func (s MemoryStore) GetNutrientTypes(context.Context) ([]string, error) {
+	return append([]string{}, s.nutrientTypes...), nil
+}
+
+func (s MemoryStore) GetVitaminTypes(context.Context) ([]string, error) {
+	return append([]string{}, s.vitaminTypes...), nil
+}
+
+func (s MemoryStore) GetMineralTypes(context.Context) ([]string, error) {
+	return append([]string{}, s.mineralTypes...), nil
+}
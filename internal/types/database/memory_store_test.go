@@ -0,0 +1,43 @@
+package database
+
+import (
+	"context"
+	"github.com/Kobietka/product-service/internal/database/setup"
+	"github.com/Kobietka/product-service/internal/types"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestMemoryStore(t *testing.T) {
+	store := NewMemoryStore(setup.Fixture{
+		Units:         []setup.FixtureUnit{{Value: "g", Dimension: types.DimensionMass}, {Value: "ml", Dimension: types.DimensionVolume}},
+		NutrientTypes: []string{"FAT", "PROTEIN"},
+		VitaminTypes:  []string{"VITAMIN_C"},
+		MineralTypes:  []string{"IRON"},
+	})
+	ctx := context.Background()
+
+	units, err := store.GetUnits(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"g", "ml"}, units)
+
+	dimensions, err := store.GetUnitDimensions(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]types.Dimension{"g": types.DimensionMass, "ml": types.DimensionVolume}, dimensions)
+
+	nutrientTypes, err := store.GetNutrientTypes(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"FAT", "PROTEIN"}, nutrientTypes)
+	nutrientTypes[0] = "SALT"
+	nutrientTypes, err = store.GetNutrientTypes(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"FAT", "PROTEIN"}, nutrientTypes)
+
+	vitaminTypes, err := store.GetVitaminTypes(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"VITAMIN_C"}, vitaminTypes)
+
+	mineralTypes, err := store.GetMineralTypes(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"IRON"}, mineralTypes)
+}
@@ -3,6 +3,7 @@ package database
 import (
 	"context"
 	"errors"
+	"github.com/Kobietka/product-service/internal/types"
 	"github.com/Kobietka/product-service/pkg/array"
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
@@ -38,6 +39,27 @@ func (s PostgresStore) GetUnits(ctx context.Context) ([]string, error) {
 	}), nil
 }
 
+func (s PostgresStore) GetUnitDimensions(ctx context.Context) (map[string]types.Dimension, error) {
+	query := `SELECT value, dimension FROM unit`
+
+	rows, err := s.pool.Query(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entities, err := pgx.CollectRows(rows, pgx.RowToStructByPos[unitDimensionEntity])
+	if err != nil {
+		return nil, err
+	}
+
+	dimensions := make(map[string]types.Dimension, len(entities))
+	for _, entity := range entities {
+		dimensions[entity.Value] = types.Dimension(entity.Dimension)
+	}
+	return dimensions, nil
+}
+
 func (s PostgresStore) GetNutrientTypes(ctx context.Context) ([]string, error) {
 	query := `SELECT id, type FROM nutrient_type`
 
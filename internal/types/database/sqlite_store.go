@@ -0,0 +1,63 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"github.com/Kobietka/product-service/internal/types"
+	"github.com/Kobietka/product-service/pkg/sqlite"
+)
+
+type SqliteStore struct {
+	db *sql.DB
+}
+
+func NewSqliteStore(db *sql.DB) SqliteStore {
+	return SqliteStore{db}
+}
+
+func (s SqliteStore) GetUnits(ctx context.Context) ([]string, error) {
+	return s.getValues(ctx, `SELECT value FROM unit ORDER BY id`)
+}
+
+func (s SqliteStore) GetUnitDimensions(ctx context.Context) (map[string]types.Dimension, error) {
+	entities, err := sqlite.CollectRows[unitDimensionEntity](s.db.QueryContext(ctx, `SELECT value, dimension FROM unit`))
+	if err != nil {
+		return nil, err
+	}
+
+	dimensions := make(map[string]types.Dimension, len(entities))
+	for _, entity := range entities {
+		dimensions[entity.Value] = types.Dimension(entity.Dimension)
+	}
+	return dimensions, nil
+}
+
+func (s SqliteStore) GetNutrientTypes(ctx context.Context) ([]string, error) {
+	return s.getValues(ctx, `SELECT type FROM nutrient_type ORDER BY id`)
+}
+
+func (s SqliteStore) GetVitaminTypes(ctx context.Context) ([]string, error) {
+	return s.getValues(ctx, `SELECT type FROM vitamin_type ORDER BY id`)
+}
+
+func (s SqliteStore) GetMineralTypes(ctx context.Context) ([]string, error) {
+	return s.getValues(ctx, `SELECT type FROM mineral_type ORDER BY id`)
+}
+
+func (s SqliteStore) getValues(ctx context.Context, query string) ([]string, error) {
+	rows, err := s.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	values := make([]string, 0)
+	for rows.Next() {
+		var value string
+		if err := rows.Scan(&value); err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+	}
+	return values, rows.Err()
+}
@@ -0,0 +1,44 @@
+package database
+
+import (
+	"context"
+	"github.com/Kobietka/product-service/internal/database/setup"
+	"github.com/Kobietka/product-service/internal/types"
+	"github.com/Kobietka/product-service/pkg/sqlite"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestSqliteStore(t *testing.T) {
+	db, err := sqlite.Open("sqlite://:memory:")
+	assert.NoError(t, err)
+	defer db.Close()
+	seeder := setup.NewSqliteSeeder(db)
+	assert.NoError(t, seeder.CreateSchema(context.Background()))
+	assert.NoError(t, seeder.Seed(context.Background()))
+	assert.NoError(t, seeder.Seed(context.Background()))
+
+	store := NewSqliteStore(db)
+	seeded := NewMemoryStore(setup.DefaultFixture())
+	ctx := context.Background()
+
+	for name, get := range map[string]func(types.Store) ([]string, error){
+		"units":          func(store types.Store) ([]string, error) { return store.GetUnits(ctx) },
+		"nutrient types": func(store types.Store) ([]string, error) { return store.GetNutrientTypes(ctx) },
+		"vitamin types":  func(store types.Store) ([]string, error) { return store.GetVitaminTypes(ctx) },
+		"mineral types":  func(store types.Store) ([]string, error) { return store.GetMineralTypes(ctx) },
+	} {
+		t.Run(name, func(t *testing.T) {
+			expected, err := get(seeded)
+			assert.NoError(t, err)
+			values, err := get(store)
+			assert.NoError(t, err)
+			assert.Equal(t, expected, values)
+		})
+	}
+
+	dimensions, err := store.GetUnitDimensions(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, types.DimensionEnergy, dimensions["kcal"])
+	assert.Equal(t, types.DimensionVolume, dimensions["ml"])
+}
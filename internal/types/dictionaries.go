@@ -0,0 +1,44 @@
+package types
+
+import "context"
+
+type Dictionaries struct {
+	Units         map[string]Dimension
+	NutrientTypes map[string]bool
+	VitaminTypes  map[string]bool
+	MineralTypes  map[string]bool
+}
+
+func LoadDictionaries(ctx context.Context, store Store) (Dictionaries, error) {
+	units, err := store.GetUnitDimensions(ctx)
+	if err != nil {
+		return Dictionaries{}, err
+	}
+	nutrientTypes, err := store.GetNutrientTypes(ctx)
+	if err != nil {
+		return Dictionaries{}, err
+	}
+	vitaminTypes, err := store.GetVitaminTypes(ctx)
+	if err != nil {
+		return Dictionaries{}, err
+	}
+	mineralTypes, err := store.GetMineralTypes(ctx)
+	if err != nil {
+		return Dictionaries{}, err
+	}
+
+	return Dictionaries{
+		Units:         units,
+		NutrientTypes: toSet(nutrientTypes),
+		VitaminTypes:  toSet(vitaminTypes),
+		MineralTypes:  toSet(mineralTypes),
+	}, nil
+}
+
+func toSet(values []string) map[string]bool {
+	set := make(map[string]bool, len(values))
+	for _, value := range values {
+		set[value] = true
+	}
+	return set
+}
@@ -269,6 +269,11 @@ func (m *MockStore) GetUnits(ctx context.Context) ([]string, error) {
 	return args.Get(0).([]string), args.Error(1)
 }
 
+func (m *MockStore) GetUnitDimensions(ctx context.Context) (map[string]Dimension, error) {
+	args := m.Called(ctx)
+	return args.Get(0).(map[string]Dimension), args.Error(1)
+}
+
 func (m *MockStore) GetNutrientTypes(ctx context.Context) ([]string, error) {
 	args := m.Called(ctx)
 	return args.Get(0).([]string), args.Error(1)
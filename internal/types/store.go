@@ -2,8 +2,18 @@ package types
 
 import "context"
 
+type Dimension string
+
+const (
+	DimensionMass   Dimension = "MASS"
+	DimensionVolume Dimension = "VOLUME"
+	DimensionEnergy Dimension = "ENERGY"
+	DimensionCount  Dimension = "COUNT"
+)
+
 type Store interface {
 	GetUnits(ctx context.Context) ([]string, error)
+	GetUnitDimensions(ctx context.Context) (map[string]Dimension, error)
 	GetNutrientTypes(ctx context.Context) ([]string, error)
 	GetVitaminTypes(ctx context.Context) ([]string, error)
 	GetMineralTypes(ctx context.Context) ([]string, error)
@@ -0,0 +1,41 @@
+package v1
+
+type Barcode struct {
+	Code    string `json:"code"`
+	Format  string `json:"format"`
+	Prefix  string `json:"prefix"`
+	Country string `json:"country,omitempty"`
+	Range   string `json:"range"`
+}
+
+type Measure struct {
+	Code      string     `json:"code"`
+	Article   string     `json:"article"`
+	Weight    *Quantity  `json:"weight,omitempty"`
+	Price     *float32   `json:"price,omitempty"`
+	Nutrition *Nutrition `json:"nutrition,omitempty"`
+}
+
+type ResolveRequest struct {
+	Data string `json:"data"`
+}
+
+type ApplicationIdentifier struct {
+	Ai     string   `json:"ai"`
+	Title  string   `json:"title"`
+	Value  string   `json:"value"`
+	Date   string   `json:"date,omitempty"`
+	Number *float64 `json:"number,omitempty"`
+}
+
+type Resolution struct {
+	Gtin     string                  `json:"gtin"`
+	Elements []ApplicationIdentifier `json:"elements"`
+	Product  *Product                `json:"product,omitempty"`
+}
+
+type DecodedBarcode struct {
+	Code    string   `json:"code"`
+	Barcode *Barcode `json:"barcode"`
+	Product *Product `json:"product,omitempty"`
+}
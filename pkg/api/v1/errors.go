@@ -3,11 +3,31 @@ package v1
 import "errors"
 
 var (
-	ErrorProductDoesNotExist = errors.New("PRODUCT_DOES_NOT_EXIST")
-	ErrorDataNotFound        = errors.New("DATA_NOT_FOUND")
-	ErrorInvalidData         = errors.New("PROVIDED_DATA_INVALID")
+	ErrorProductDoesNotExist  = errors.New("PRODUCT_DOES_NOT_EXIST")
+	ErrorDataNotFound         = errors.New("DATA_NOT_FOUND")
+	ErrorInvalidData          = errors.New("PROVIDED_DATA_INVALID")
+	ErrorProductInUse         = errors.New("PRODUCT_IN_USE")
+	ErrorProductAlreadyExists = errors.New("PRODUCT_ALREADY_EXISTS")
 )
 
 type ErrorResponse struct {
-	Code string `json:"code"`
+	Code       string      `json:"code"`
+	Violations []Violation `json:"violations,omitempty"`
+}
+
+// Violation is an error unless Severity is warning.
+type Violation struct {
+	Pointer  string         `json:"pointer"`
+	Code     string         `json:"code"`
+	Params   map[string]any `json:"params,omitempty"`
+	Severity string         `json:"severity,omitempty"`
+}
+
+// ValidationResult answers a dry run, Code and Violations are those an
+// ErrorResponse would carry. Valid products may still have warnings.
+type ValidationResult struct {
+	Ean        string      `json:"ean"`
+	Valid      bool        `json:"valid"`
+	Code       string      `json:"code,omitempty"`
+	Violations []Violation `json:"violations,omitempty"`
 }
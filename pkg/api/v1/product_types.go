@@ -1,10 +1,54 @@
 package v1
 
+import "slices"
+
 type Product struct {
-	Ean       string    `json:"ean"`
-	Name      string    `json:"name"`
-	Packaging Quantity  `json:"packaging"`
-	Nutrition Nutrition `json:"nutrition"`
+	Ean        string      `json:"ean"`
+	Name       string      `json:"name"`
+	Packaging  Quantity    `json:"packaging"`
+	Nutrition  Nutrition   `json:"nutrition"`
+	Components []Component `json:"components,omitempty"`
+	Servings   []Serving   `json:"servings,omitempty"`
+	Barcode    *Barcode    `json:"barcode,omitempty"`
+	Measure    *Measure    `json:"measure,omitempty"`
+}
+
+// Clone copies the lists of the product, the copy can be modified without
+// changing the original. The barcode, the measure and the computed fields of
+// servings are shared.
+func (p Product) Clone() Product {
+	p.Nutrition.Nutrients = slices.Clone(p.Nutrition.Nutrients)
+	p.Nutrition.Vitamins = slices.Clone(p.Nutrition.Vitamins)
+	p.Nutrition.Minerals = slices.Clone(p.Nutrition.Minerals)
+	p.Components = slices.Clone(p.Components)
+	p.Servings = slices.Clone(p.Servings)
+	return p
+}
+
+// LookupRequest lists the EANs of a batch lookup.
+type LookupRequest struct {
+	Eans []string `json:"eans"`
+}
+
+// LookupResult answers an EAN of a batch lookup with its product or with the
+// code of the error.
+type LookupResult struct {
+	Product *Product `json:"product,omitempty"`
+	Code    string   `json:"code,omitempty"`
+}
+
+type Component struct {
+	Ean   string `json:"ean"`
+	Count int32  `json:"count"`
+}
+
+// Serving is a named portion such as "1 bar". PerPackage and Nutrition are
+// computed when a product is read and are ignored when it is written.
+type Serving struct {
+	Name       string     `json:"name"`
+	Quantity   Quantity   `json:"quantity"`
+	PerPackage *float32   `json:"perPackage,omitempty"`
+	Nutrition  *Nutrition `json:"nutrition,omitempty"`
 }
 
 type Quantity struct {
@@ -34,3 +78,7 @@ type Vitamin struct {
 	T        string   `json:"type"`
 	Quantity Quantity `json:"quantity"`
 }
+
+func (n Nutrition) IsEmpty() bool {
+	return n.Per == Quantity{} && n.Kcal == 0 && len(n.Nutrients) == 0 && len(n.Vitamins) == 0 && len(n.Minerals) == 0
+}
@@ -0,0 +1,34 @@
+package postgres
+
+import (
+	"errors"
+	"github.com/jackc/pgx/v5/pgconn"
+	"strings"
+)
+
+const (
+	ForeignKeyViolation  = "23503"
+	UniqueViolation      = "23505"
+	SerializationFailure = "40001"
+	DeadlockDetected     = "40P01"
+
+	integrityConstraintViolationClass = "23"
+)
+
+func HasCode(err error, code string) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == code
+}
+
+// IsConstraintViolation reports whether err was raised by a not null, check,
+// foreign key or unique constraint.
+func IsConstraintViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && strings.HasPrefix(pgErr.Code, integrityConstraintViolationClass)
+}
+
+// IsRetryable reports whether the transaction failing with err can succeed
+// when it is run again.
+func IsRetryable(err error) bool {
+	return HasCode(err, SerializationFailure) || HasCode(err, DeadlockDetected)
+}
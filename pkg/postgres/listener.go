@@ -0,0 +1,74 @@
+package postgres
+
+import (
+	"context"
+	"github.com/charmbracelet/log"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"time"
+)
+
+const defaultReconnectDelay = time.Second
+
+// Listener receives the notifications of a channel on a connection of its
+// own, the connections of the pool are left to queries.
+type Listener struct {
+	config         *pgx.ConnConfig
+	channel        string
+	reconnectDelay time.Duration
+}
+
+func NewListener(pool *pgxpool.Pool, channel string) Listener {
+	return Listener{
+		config:         pool.Config().ConnConfig,
+		channel:        channel,
+		reconnectDelay: defaultReconnectDelay,
+	}
+}
+
+func (l Listener) WithReconnectDelay(delay time.Duration) Listener {
+	l.reconnectDelay = delay
+	return l
+}
+
+// Listen passes the payload of every notification to notify until ctx is
+// done, reconnecting when the connection is lost. Notifications sent while it
+// is disconnected are lost, connected is called whenever listening starts so
+// that callers can drop what they derived from them.
+func (l Listener) Listen(ctx context.Context, connected func(), notify func(payload string)) error {
+	for {
+		err := l.listen(ctx, connected, notify)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		log.Warn("listening failed", "channel", l.channel, "err", err)
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(l.reconnectDelay):
+		}
+	}
+}
+
+func (l Listener) listen(ctx context.Context, connected func(), notify func(payload string)) error {
+	conn, err := pgx.ConnectConfig(ctx, l.config.Copy())
+	if err != nil {
+		return err
+	}
+	defer conn.Close(context.Background())
+
+	_, err = conn.Exec(ctx, "LISTEN "+pgx.Identifier{l.channel}.Sanitize())
+	if err != nil {
+		return err
+	}
+	connected()
+
+	for {
+		notification, err := conn.WaitForNotification(ctx)
+		if err != nil {
+			return err
+		}
+		notify(notification.Payload)
+	}
+}
@@ -0,0 +1,85 @@
+package postgres
+
+import (
+	"context"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"time"
+)
+
+const (
+	defaultMaxAttempts = 3
+	defaultRetryDelay  = 20 * time.Millisecond
+)
+
+type Beginner interface {
+	BeginTx(ctx context.Context, options pgx.TxOptions) (pgx.Tx, error)
+}
+
+// UnitOfWork runs writes in a transaction and runs them again when the
+// database aborts the transaction because of a serialization failure or a
+// deadlock.
+type UnitOfWork struct {
+	db          Beginner
+	options     pgx.TxOptions
+	maxAttempts int
+	retryDelay  time.Duration
+}
+
+func NewUnitOfWork(db Beginner) UnitOfWork {
+	return UnitOfWork{db: db, maxAttempts: defaultMaxAttempts, retryDelay: defaultRetryDelay}
+}
+
+func (u UnitOfWork) WithOptions(options pgx.TxOptions) UnitOfWork {
+	u.options = options
+	return u
+}
+
+// WithRetries sets how many times work is run at most, the delay before a
+// retry grows linearly with the attempt.
+func (u UnitOfWork) WithRetries(maxAttempts int, retryDelay time.Duration) UnitOfWork {
+	u.maxAttempts = max(maxAttempts, 1)
+	u.retryDelay = retryDelay
+	return u
+}
+
+// Run commits when work returns nil and rolls back otherwise. As work can be
+// run several times, it must not have effects outside the transaction.
+func (u UnitOfWork) Run(ctx context.Context, work func(tx pgx.Tx) error) error {
+	for attempt := 1; ; attempt++ {
+		err := u.run(ctx, work)
+		if err == nil || !IsRetryable(err) || attempt >= u.maxAttempts {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(u.retryDelay * time.Duration(attempt)):
+		}
+	}
+}
+
+func (u UnitOfWork) run(ctx context.Context, work func(tx pgx.Tx) error) error {
+	tx, err := u.db.BeginTx(ctx, u.options)
+	if err != nil {
+		return err
+	}
+
+	if err := work(tx); err != nil {
+		_ = tx.Rollback(ctx)
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// ExpectExec translates the outcome of a queued statement with check, so that
+// a failing batch reports the error of the statement that failed. Results of
+// the statements after a failing one are not read.
+func ExpectExec(query *pgx.QueuedQuery, check func(tag pgconn.CommandTag, err error) error) {
+	query.Fn = func(results pgx.BatchResults) error {
+		tag, err := results.Exec()
+		return check(tag, err)
+	}
+}
@@ -0,0 +1,150 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+type fakeTx struct {
+	pgx.Tx
+	db *fakeDb
+}
+
+func (tx fakeTx) Commit(context.Context) error {
+	tx.db.commits++
+	if len(tx.db.commitErrs) > 0 {
+		err := tx.db.commitErrs[0]
+		tx.db.commitErrs = tx.db.commitErrs[1:]
+		return err
+	}
+	return nil
+}
+
+func (tx fakeTx) Rollback(context.Context) error {
+	tx.db.rollbacks++
+	return nil
+}
+
+type fakeDb struct {
+	beginErr   error
+	commitErrs []error
+	options    pgx.TxOptions
+	commits    int
+	rollbacks  int
+}
+
+func (db *fakeDb) BeginTx(_ context.Context, options pgx.TxOptions) (pgx.Tx, error) {
+	db.options = options
+	if db.beginErr != nil {
+		return nil, db.beginErr
+	}
+	return fakeTx{db: db}, nil
+}
+
+func TestUnitOfWorkRun(t *testing.T) {
+	serializationFailure := &pgconn.PgError{Code: SerializationFailure}
+	deadlock := &pgconn.PgError{Code: DeadlockDetected}
+	uniqueViolation := &pgconn.PgError{Code: UniqueViolation}
+
+	tests := []struct {
+		Name              string
+		WorkErrs          []error
+		CommitErrs        []error
+		BeginErr          error
+		ExpectedErr       error
+		ExpectedRuns      int
+		ExpectedCommits   int
+		ExpectedRollbacks int
+	}{
+		{
+			Name:            "commits",
+			ExpectedRuns:    1,
+			ExpectedCommits: 1,
+		},
+		{
+			Name:              "rolls back on error",
+			WorkErrs:          []error{uniqueViolation},
+			ExpectedErr:       uniqueViolation,
+			ExpectedRuns:      1,
+			ExpectedRollbacks: 1,
+		},
+		{
+			Name:              "retries serialization failures and deadlocks",
+			WorkErrs:          []error{serializationFailure, deadlock},
+			ExpectedRuns:      3,
+			ExpectedCommits:   1,
+			ExpectedRollbacks: 2,
+		},
+		{
+			Name:              "gives up after the last attempt",
+			WorkErrs:          []error{serializationFailure, serializationFailure, serializationFailure},
+			ExpectedErr:       serializationFailure,
+			ExpectedRuns:      3,
+			ExpectedRollbacks: 3,
+		},
+		{
+			Name:            "retries failed commits",
+			CommitErrs:      []error{serializationFailure},
+			ExpectedRuns:    2,
+			ExpectedCommits: 2,
+		},
+		{
+			Name:         "begin fails",
+			BeginErr:     errors.New("connection refused"),
+			ExpectedErr:  errors.New("connection refused"),
+			ExpectedRuns: 0,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			db := &fakeDb{beginErr: test.BeginErr, commitErrs: test.CommitErrs}
+			unitOfWork := NewUnitOfWork(db).
+				WithOptions(pgx.TxOptions{IsoLevel: pgx.Serializable}).
+				WithRetries(3, time.Millisecond)
+
+			runs := 0
+			err := unitOfWork.Run(context.Background(), func(tx pgx.Tx) error {
+				runs++
+				if runs <= len(test.WorkErrs) {
+					return test.WorkErrs[runs-1]
+				}
+				return nil
+			})
+
+			assert.Equal(t, test.ExpectedErr, err)
+			assert.Equal(t, test.ExpectedRuns, runs)
+			assert.Equal(t, test.ExpectedCommits, db.commits)
+			assert.Equal(t, test.ExpectedRollbacks, db.rollbacks)
+			assert.Equal(t, pgx.Serializable, db.options.IsoLevel)
+		})
+	}
+}
+
+func TestUnitOfWorkRunStopsWhenContextIsDone(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	serializationFailure := &pgconn.PgError{Code: SerializationFailure}
+
+	runs := 0
+	err := NewUnitOfWork(&fakeDb{}).Run(ctx, func(tx pgx.Tx) error {
+		runs++
+		cancel()
+		return serializationFailure
+	})
+
+	assert.Equal(t, serializationFailure, err)
+	assert.Equal(t, 1, runs)
+}
+
+func TestIsConstraintViolation(t *testing.T) {
+	assert.True(t, IsConstraintViolation(&pgconn.PgError{Code: UniqueViolation}))
+	assert.True(t, IsConstraintViolation(&pgconn.PgError{Code: "23502"}))
+	assert.False(t, IsConstraintViolation(&pgconn.PgError{Code: SerializationFailure}))
+	assert.False(t, IsConstraintViolation(errors.New("23505")))
+	assert.False(t, IsConstraintViolation(nil))
+}
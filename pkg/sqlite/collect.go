@@ -0,0 +1,47 @@
+package sqlite
+
+import (
+	"database/sql"
+	"reflect"
+)
+
+// CollectRows scans every row into a struct, columns are assigned to the
+// fields by position.
+func CollectRows[T any](rows *sql.Rows, queryErr error) ([]T, error) {
+	if queryErr != nil {
+		return make([]T, 0), queryErr
+	}
+	defer rows.Close()
+
+	data := make([]T, 0)
+	for rows.Next() {
+		var value T
+		if err := rows.Scan(fields(&value)...); err != nil {
+			return make([]T, 0), err
+		}
+		data = append(data, value)
+	}
+	return data, rows.Err()
+}
+
+// CollectOneRow scans the first row like CollectRows, sql.ErrNoRows is
+// returned when there is none.
+func CollectOneRow[T any](rows *sql.Rows, queryErr error) (T, error) {
+	data, err := CollectRows[T](rows, queryErr)
+	if err != nil {
+		return *new(T), err
+	}
+	if len(data) == 0 {
+		return *new(T), sql.ErrNoRows
+	}
+	return data[0], nil
+}
+
+func fields(value any) []any {
+	structValue := reflect.ValueOf(value).Elem()
+	pointers := make([]any, structValue.NumField())
+	for i := range pointers {
+		pointers[i] = structValue.Field(i).Addr().Interface()
+	}
+	return pointers
+}
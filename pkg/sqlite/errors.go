@@ -0,0 +1,33 @@
+package sqlite
+
+import (
+	"errors"
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+const (
+	Constraint           = sqlite3.SQLITE_CONSTRAINT
+	ConstraintUnique     = sqlite3.SQLITE_CONSTRAINT_UNIQUE
+	ConstraintPrimaryKey = sqlite3.SQLITE_CONSTRAINT_PRIMARYKEY
+	ConstraintForeignKey = sqlite3.SQLITE_CONSTRAINT_FOREIGNKEY
+)
+
+// HasCode reports whether err is a SQLite error with the extended result code.
+func HasCode(err error, code int) bool {
+	var sqliteErr *sqlite.Error
+	return errors.As(err, &sqliteErr) && sqliteErr.Code() == code
+}
+
+// IsConstraintViolation reports whether err is any SQLite constraint error,
+// the primary result code of all of them is SQLITE_CONSTRAINT.
+func IsConstraintViolation(err error) bool {
+	var sqliteErr *sqlite.Error
+	return errors.As(err, &sqliteErr) && sqliteErr.Code()&0xff == Constraint
+}
+
+// IsDuplicate reports whether err is a violated primary key or unique
+// constraint.
+func IsDuplicate(err error) bool {
+	return HasCode(err, ConstraintPrimaryKey) || HasCode(err, ConstraintUnique)
+}
@@ -0,0 +1,56 @@
+package sqlite
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"modernc.org/sqlite"
+	"strings"
+	"sync"
+)
+
+const Scheme = "sqlite://"
+
+var ErrorUrlInvalid = errors.New("SQLITE_URL_INVALID")
+
+var registerOnce sync.Once
+
+// Open opens the database of a sqlite:// URL. sqlite://products.db is relative
+// to the working directory, sqlite:///var/lib/products.db is absolute and
+// sqlite://:memory: is a database that lives as long as the returned handle.
+//
+// Foreign keys are enforced and a single connection is used, SQLite
+// serializes writes anyway and an in-memory database is per connection.
+func Open(databaseUrl string) (*sql.DB, error) {
+	path, ok := strings.CutPrefix(databaseUrl, Scheme)
+	if !ok || path == "" {
+		return nil, ErrorUrlInvalid
+	}
+	registerOnce.Do(registerFunctions)
+
+	separator := "?"
+	if strings.Contains(path, "?") {
+		separator = "&"
+	}
+	db, err := sql.Open("sqlite", path+separator+"_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)&_txlock=immediate")
+	if err != nil {
+		return nil, err
+	}
+	db.SetMaxOpenConns(1)
+	return db, nil
+}
+
+// registerFunctions replaces the built-in lower, which only folds ASCII, with
+// one that folds like Postgres does.
+func registerFunctions() {
+	sqlite.MustRegisterDeterministicScalarFunction("lower", 1, func(_ *sqlite.FunctionContext, args []driver.Value) (driver.Value, error) {
+		switch value := args[0].(type) {
+		case string:
+			return strings.ToLower(value), nil
+		case []byte:
+			return strings.ToLower(string(value)), nil
+		default:
+			return value, nil
+		}
+	})
+}
@@ -0,0 +1,35 @@
+package sqlite
+
+import (
+	"github.com/stretchr/testify/assert"
+	"path/filepath"
+	"testing"
+)
+
+func TestOpen(t *testing.T) {
+	_, err := Open("postgres://localhost/products")
+	assert.ErrorIs(t, err, ErrorUrlInvalid)
+	_, err = Open(Scheme)
+	assert.ErrorIs(t, err, ErrorUrlInvalid)
+
+	db, err := Open(Scheme + filepath.Join(t.TempDir(), "products.db"))
+	assert.NoError(t, err)
+	defer db.Close()
+
+	var lower string
+	assert.NoError(t, db.QueryRow(`SELECT LOWER('ŻÓŁW')`).Scan(&lower))
+	assert.Equal(t, "żółw", lower)
+
+	var foreignKeys bool
+	assert.NoError(t, db.QueryRow(`PRAGMA foreign_keys`).Scan(&foreignKeys))
+	assert.True(t, foreignKeys)
+
+	_, err = db.Exec(`CREATE TABLE unit (id INTEGER PRIMARY KEY, value TEXT NOT NULL UNIQUE); INSERT INTO unit VALUES (1, 'g')`)
+	assert.NoError(t, err)
+	_, err = db.Exec(`INSERT INTO unit VALUES (2, 'g')`)
+	assert.True(t, IsDuplicate(err))
+	assert.True(t, HasCode(err, ConstraintUnique))
+	_, err = db.Exec(`INSERT INTO unit VALUES (3, NULL)`)
+	assert.True(t, IsConstraintViolation(err))
+	assert.False(t, IsDuplicate(err))
+}
@@ -0,0 +1,26 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+)
+
+type Seeder struct {
+	db     *sql.DB
+	schema string
+	seed   string
+}
+
+func NewSeeder(db *sql.DB, schema, seed string) Seeder {
+	return Seeder{db: db, schema: schema, seed: seed}
+}
+
+func (s Seeder) CreateSchema(ctx context.Context) error {
+	_, err := s.db.ExecContext(ctx, s.schema)
+	return err
+}
+
+func (s Seeder) Seed(ctx context.Context) error {
+	_, err := s.db.ExecContext(ctx, s.seed)
+	return err
+}